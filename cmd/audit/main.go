@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/audit"
+)
+
+func main() {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: audit [flags]\n\nQuery or verify the audit log, including rotated files.\n\n")
+		fs.PrintDefaults()
+	}
+
+	file := fs.String("file", os.Getenv("MCP_AUDIT_LOG"), "path of the audit log")
+	var q audit.Query
+	fs.StringVar(&q.Principal, "principal", "", "only entries by this principal")
+	fs.StringVar(&q.Session, "session", "", "only entries from this session")
+	fs.StringVar(&q.Tool, "tool", "", "only calls to this tool, or entries of this method such as resources/read")
+	fs.StringVar(&q.Outcome, "outcome", "", "only entries with this outcome: success, error or denied")
+	fs.StringVar(&q.Path, "path", "", "only entries that read or returned a path matching this glob")
+	since := fs.String("since", "", "only entries at or after this time (RFC 3339) or this long ago (e.g. 24h)")
+	until := fs.String("until", "", "only entries before this time (RFC 3339) or this long ago")
+	asJSON := fs.Bool("json", false, "print matching entries as JSON lines")
+	verify := fs.Bool("verify", false, "check the hash chain instead of printing entries")
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return
+		}
+		os.Exit(2)
+	}
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "audit: -file or MCP_AUDIT_LOG is required")
+		os.Exit(2)
+	}
+
+	var err error
+	if q.Since, err = parseTime(*since); err != nil {
+		fmt.Fprintf(os.Stderr, "audit: -since: %v\n", err)
+		os.Exit(2)
+	}
+	if q.Until, err = parseTime(*until); err != nil {
+		fmt.Fprintf(os.Stderr, "audit: -until: %v\n", err)
+		os.Exit(2)
+	}
+
+	if *verify {
+		count, err := audit.Verify(*file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "audit: verification failed after %d entries: %v\n", count, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%d entries verified\n", count)
+		return
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	err = audit.Read(*file, func(_ audit.Location, e audit.Entry) error {
+		if !q.Match(e) {
+			return nil
+		}
+		if *asJSON {
+			return encoder.Encode(e)
+		}
+		fmt.Println(formatEntry(e))
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// parseTime accepts an RFC 3339 time or a duration before now
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// formatEntry renders an entry on one line followed by its paths
+func formatEntry(e audit.Entry) string {
+	principal := e.Principal
+	if principal == "" {
+		principal = "anonymous"
+	}
+	target := e.Tool
+	if target == "" {
+		target = e.Resource
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s %s %s %dB", e.Time.Format(time.RFC3339), principal, e.Method, target, e.Outcome, e.Bytes)
+	if e.Session != "" {
+		fmt.Fprintf(&b, " session=%s", e.Session)
+	}
+	if len(e.Arguments) > 0 {
+		if args, err := json.Marshal(e.Arguments); err == nil {
+			fmt.Fprintf(&b, " args=%s", args)
+		}
+	}
+	if e.Error != "" {
+		fmt.Fprintf(&b, " error=%q", e.Error)
+	}
+	for _, path := range e.Paths {
+		fmt.Fprintf(&b, "\n    %s", path)
+	}
+	return b.String()
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/conformance"
+)
+
+func main() {
+	fs := flag.NewFlagSet("conformance", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: conformance [flags] [request files...]\n\n")
+		fmt.Fprintf(fs.Output(), "Replay request files against in-process servers over each transport and\ncompare the responses with golden files, then run the protocol scenarios.\nWithout files, examples/*.json and examples/*.jsonl are replayed.\n\n")
+		fs.PrintDefaults()
+	}
+
+	update := fs.Bool("update", false, "rewrite the golden files from the responses")
+	transportList := fs.String("transport", strings.Join(conformance.Transports, ","), "comma-separated transports to check")
+	run := fs.String("run", "", "only run files and scenarios whose name matches this regular expression")
+	goldenDir := fs.String("golden", filepath.Join("internal", "conformance", "testdata", "golden"), "directory of the golden files")
+	fixture := fs.String("fixture", filepath.Join("internal", "conformance", "testdata", "root"), "directory served as the root \""+conformance.RootName+"\"")
+	scenarios := fs.Bool("scenarios", true, "run the protocol scenarios")
+	replay := fs.Bool("replay", true, "replay request files")
+	verbose := fs.Bool("v", false, "print the servers' logs and every passing check")
+	timeout := fs.Duration("timeout", conformance.DefaultTimeout, "how long to wait for each reply")
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return
+		}
+		os.Exit(2)
+	}
+
+	var filter *regexp.Regexp
+	if *run != "" {
+		var err error
+		if filter, err = regexp.Compile(*run); err != nil {
+			fmt.Fprintf(os.Stderr, "conformance: -run: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	transports, err := parseTransports(*transportList)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "conformance: -transport: %v\n", err)
+		os.Exit(2)
+	}
+	if *update && len(transports) < 2 {
+		fmt.Fprintln(os.Stderr, "conformance: warning: golden files updated from one transport are not cross-checked")
+	}
+
+	h := &conformance.Harness{
+		Root:      *fixture,
+		GoldenDir: *goldenDir,
+		Update:    *update,
+		Timeout:   *timeout,
+	}
+	if *verbose {
+		h.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+
+	var results []conformance.Result
+	if *replay {
+		files := fs.Args()
+		if len(files) == 0 {
+			for _, pattern := range []string{"examples/*.json", "examples/*.jsonl"} {
+				matches, _ := filepath.Glob(pattern)
+				files = append(files, matches...)
+			}
+		}
+		for _, file := range files {
+			if filter == nil || filter.MatchString(file) {
+				results = append(results, report(h.CheckFile(file, transports), *verbose)...)
+			}
+		}
+	}
+	if *scenarios {
+		for _, s := range conformance.Scenarios {
+			if filter != nil && !filter.MatchString(s.Name) {
+				continue
+			}
+			for _, transport := range transports {
+				if s.AppliesTo(transport) {
+					results = append(results, report([]conformance.Result{h.RunScenario(s, transport)}, *verbose)...)
+				}
+			}
+		}
+	}
+
+	failed := 0
+	for _, r := range results {
+		if !r.Passed() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("FAIL: %d of %d checks failed\n", failed, len(results))
+		os.Exit(1)
+	}
+	fmt.Printf("ok: %d checks passed\n", len(results))
+}
+
+// parseTransports splits and validates the -transport flag
+func parseTransports(list string) ([]string, error) {
+	var transports []string
+	for _, t := range strings.Split(list, ",") {
+		t = strings.TrimSpace(t)
+		switch t {
+		case "":
+		case conformance.TransportStdio, conformance.TransportHTTP:
+			transports = append(transports, t)
+		default:
+			return nil, fmt.Errorf("unknown transport %q", t)
+		}
+	}
+	if len(transports) == 0 {
+		return nil, fmt.Errorf("no transports")
+	}
+	return transports, nil
+}
+
+// report prints the failures of results, and their passes when verbose
+func report(results []conformance.Result, verbose bool) []conformance.Result {
+	for _, r := range results {
+		switch {
+		case !r.Passed():
+			fmt.Printf("FAIL  %-5s %s (%s)\n", r.Transport, r.Name, r.Duration.Round(1e6))
+			for _, failure := range r.Failures {
+				fmt.Printf("      %s\n", strings.ReplaceAll(strings.TrimSuffix(failure, "\n"), "\n", "\n      "))
+			}
+		case verbose:
+			fmt.Printf("ok    %-5s %s (%s)\n", r.Transport, r.Name, r.Duration.Round(1e6))
+		}
+	}
+	return results
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/app"
+	"github.com/aawadall/go-mcp-filesearch/internal/config"
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+)
+
+const usage = `Usage: filesearch <command> [flags] [arguments]
+
+Search files from the terminal with the same engine, configuration and
+results as the MCP servers' tools.
+
+Commands:
+  find [flags] [pattern]    find files by name (find_files)
+  grep [flags] query        search file contents (search_content)
+  index build [flags] [root]
+                            rebuild the index of one root or all (rebuild_index)
+  index stats [flags]       show what the index holds
+
+Roots come from -root flags, MCP_ROOTS or the configuration file, as for the
+servers; without any, the current directory is searched as the root ".".
+Run "filesearch <command> -h" for the flags of a command.
+
+Exit status is 0 when something was found, 1 when nothing was and 2 on
+error.
+`
+
+// multiFlag collects the values of a repeated flag
+type multiFlag []string
+
+func (m *multiFlag) String() string     { return strings.Join(*m, ",") }
+func (m *multiFlag) Set(v string) error { *m = append(*m, v); return nil }
+
+// options are the flags every command takes
+type options struct {
+	config string
+	roots  multiFlag
+	index  string
+	sets   multiFlag
+	json   bool
+	color  string
+}
+
+// register adds the shared flags to fs
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.config, "config", "", "path to a TOML configuration file (default $MCP_CONFIG)")
+	fs.Var(&o.roots, "root", "search root as name=path (repeatable)")
+	fs.StringVar(&o.index, "index", "", "path of the persisted file index")
+	fs.Var(&o.sets, "set", "override any setting as key=value, e.g. search.max_results=50 (repeatable)")
+	fs.BoolVar(&o.json, "json", false, "print the tool result as JSON, as the servers return it")
+	fs.StringVar(&o.color, "color", "auto", "color output: auto, always or never")
+}
+
+// load builds the file search handler the servers would build from the
+// same configuration file, environment and flags
+func (o *options) load() (*filesearch.Handler, *config.Config, error) {
+	var args []string
+	if o.config != "" {
+		args = append(args, "-config", o.config)
+	}
+	for _, root := range o.roots {
+		args = append(args, "-root", root)
+	}
+	if o.index != "" {
+		args = append(args, "-index", o.index)
+	}
+	for _, set := range o.sets {
+		args = append(args, "-set", set)
+	}
+
+	cfg, _, err := config.Load("filesearch", args, io.Discard)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(cfg.Roots) == 0 {
+		cfg.Roots = []config.RootConfig{{Name: ".", Path: "."}}
+	}
+	files, err := app.NewFileSearch(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return files, cfg, nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var run func([]string) error
+	switch command := os.Args[1]; command {
+	case "find":
+		run = runFind
+	case "grep":
+		run = runGrep
+	case "index":
+		run = runIndex
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "filesearch: unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+
+	err := run(os.Args[2:])
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errNotFound):
+		os.Exit(1)
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "filesearch: %v\n", err)
+		os.Exit(2)
+	}
+}
+
+// errNotFound is returned when a search ran but found nothing
+var errNotFound = errors.New("nothing found")
+
+// errUsage is returned when the command line was rejected; the flag
+// package has already explained why
+var errUsage = errors.New("usage")
+
+// newFlagSet returns a flag set for a command with the shared flags
+// registered and the given usage line
+func newFlagSet(name, line, description string, opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet("filesearch "+name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: filesearch %s\n\n%s\n\n", line, description)
+		fs.PrintDefaults()
+	}
+	opts.register(fs)
+	return fs
+}
+
+// parseArgs parses flags wherever they appear among the arguments and
+// returns the arguments that are not flags. Everything after "--" is taken
+// as an argument.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, errUsage
+		}
+		rest := fs.Args()
+		if i := len(args) - len(rest); i > 0 && args[i-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// commandContext returns a context canceled by Ctrl-C
+func commandContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+func runFind(args []string) error {
+	var opts options
+	fs := newFlagSet("find", "find [flags] [pattern]",
+		"Find files whose name matches the glob pattern, or whose path does if the\npattern contains '/'. '**' crosses directories. Without a pattern every\nfile is listed.", &opts)
+	in := fs.String("in", "", "search only this root")
+	ext := fs.String("ext", "", "comma-separated extensions to keep, e.g. .go,.md")
+	limit := fs.Int("limit", 0, "maximum number of results (default search.max_results)")
+	long := fs.Bool("l", false, "show size and modification time")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		fmt.Fprintln(fs.Output(), "find takes at most one pattern; quote patterns so the shell does not expand them")
+		return errUsage
+	}
+
+	toolArgs := map[string]interface{}{"root": *in, "extensions": listArg(*ext), "limit": *limit}
+	if len(positional) == 1 {
+		toolArgs["pattern"] = positional[0]
+	}
+	result, err := callTool(&opts, filesearch.ToolFindFiles, toolArgs)
+	if err != nil {
+		return err
+	}
+
+	found := result["structuredContent"].(*filesearch.FindResult)
+	if opts.json {
+		err = printJSON(result)
+	} else {
+		newPrinter(opts.color).files(found, *long)
+	}
+	if err == nil && len(found.Files) == 0 {
+		return errNotFound
+	}
+	return err
+}
+
+func runGrep(args []string) error {
+	var opts options
+	fs := newFlagSet("grep", "grep [flags] query",
+		"Search file contents for lines containing the query, ignoring case unless\n-s is given, or matching it as a regular expression with -E.", &opts)
+	in := fs.String("in", "", "search only this root")
+	regex := fs.Bool("E", false, "treat the query as a regular expression")
+	caseSensitive := fs.Bool("s", false, "match case exactly")
+	include := fs.String("include", "", "search only files matching this glob")
+	ext := fs.String("ext", "", "comma-separated extensions to search, e.g. .go,.md")
+	contextLines := fs.Int("C", 0, fmt.Sprintf("lines of context around each match (max %d)", filesearch.MaxContextLines))
+	limit := fs.Int("limit", 0, "maximum number of matches (default search.max_results)")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(fs.Output(), "grep takes exactly one query; quote queries containing spaces")
+		return errUsage
+	}
+
+	result, err := callTool(&opts, filesearch.ToolSearchContent, map[string]interface{}{
+		"root":          *in,
+		"query":         positional[0],
+		"regex":         *regex,
+		"caseSensitive": *caseSensitive,
+		"include":       *include,
+		"extensions":    listArg(*ext),
+		"contextLines":  *contextLines,
+		"limit":         *limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	found := result["structuredContent"].(*filesearch.GrepResult)
+	if opts.json {
+		err = printJSON(result)
+	} else {
+		p := newPrinter(opts.color)
+		p.highlight = highlighter(positional[0], *regex, *caseSensitive)
+		p.matches(found)
+	}
+	if err == nil && len(found.Matches) == 0 {
+		return errNotFound
+	}
+	return err
+}
+
+func runIndex(args []string) error {
+	if len(args) == 0 || (args[0] != "build" && args[0] != "stats") {
+		fmt.Fprint(os.Stderr, "Usage: filesearch index build [flags] [root]\n       filesearch index stats [flags]\n")
+		return errUsage
+	}
+
+	var opts options
+	var fs *flag.FlagSet
+	if args[0] == "build" {
+		fs = newFlagSet("index build", "index build [flags] [root]",
+			"Walk one root, or every root, and save its file list to the index.", &opts)
+	} else {
+		fs = newFlagSet("index stats", "index stats [flags]",
+			"Show the roots held by the index, with their file counts, sizes and\nbuild times.", &opts)
+	}
+	positional, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if args[0] == "stats" && len(positional) > 0 || len(positional) > 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	files, cfg, err := opts.load()
+	if err != nil {
+		return err
+	}
+	if !cfg.Index.Enabled || cfg.Index.Path == "" {
+		return fmt.Errorf("no index to use; set -index, MCP_INDEX_PATH or index.path")
+	}
+
+	var result map[string]interface{}
+	if args[0] == "build" {
+		ctx, cancel := commandContext()
+		defer cancel()
+		root := ""
+		if len(positional) == 1 {
+			root = positional[0]
+		}
+		value, err := files.Call(ctx, filesearch.ToolRebuildIndex, map[string]interface{}{"root": root}, nil)
+		if err != nil {
+			return err
+		}
+		result = value.(map[string]interface{})
+	} else {
+		result = filesearch.StatsResult(files.Engine().Index().Stats())
+	}
+
+	if opts.json {
+		return printJSON(result)
+	}
+	stats := result["structuredContent"].(map[string]interface{})["roots"].([]filesearch.IndexStats)
+	newPrinter(opts.color).stats(stats)
+	return nil
+}
+
+// callTool runs a file search tool with the handler built from opts
+func callTool(opts *options, name string, args map[string]interface{}) (map[string]interface{}, error) {
+	files, _, err := opts.load()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := commandContext()
+	defer cancel()
+	result, err := files.Call(ctx, name, args, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result.(map[string]interface{}), nil
+}
+
+// listArg splits a comma-separated flag value into a tool's array argument
+func listArg(value string) []interface{} {
+	items := []interface{}{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// printJSON writes a tool result as indented JSON
+func printJSON(result map[string]interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+)
+
+// ANSI colors, chosen to resemble grep's
+const (
+	colorPath   = "\x1b[35m"
+	colorLine   = "\x1b[32m"
+	colorSep    = "\x1b[36m"
+	colorMatch  = "\x1b[1;31m"
+	colorDim    = "\x1b[2m"
+	colorNotice = "\x1b[33m"
+	colorReset  = "\x1b[0m"
+)
+
+// printer writes results for people, colored when enabled
+type printer struct {
+	out   *bufio.Writer
+	color bool
+	// highlight returns the byte ranges of a line to highlight as matches
+	highlight func(line string) [][]int
+}
+
+// newPrinter returns a printer writing to stdout. mode is auto, always or
+// never; auto colors a terminal unless NO_COLOR is set.
+func newPrinter(mode string) *printer {
+	color := mode == "always"
+	if mode == "auto" {
+		info, err := os.Stdout.Stat()
+		color = err == nil && info.Mode()&os.ModeCharDevice != 0 && os.Getenv("NO_COLOR") == ""
+	}
+	return &printer{out: bufio.NewWriter(os.Stdout), color: color}
+}
+
+// paint colors text when color is enabled
+func (p *printer) paint(color, text string) string {
+	if !p.color || text == "" {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// truncated tells the reader on stderr that results were cut off
+func (p *printer) truncated() {
+	fmt.Fprintln(os.Stderr, p.paint(colorNotice, "(results truncated; raise -limit or search.max_results)"))
+}
+
+// files writes one found file per line as root/path, with its size and
+// modification time when long is set
+func (p *printer) files(result *filesearch.FindResult, long bool) {
+	for _, f := range result.Files {
+		if long {
+			fmt.Fprintf(p.out, "%10d  %s  ", f.Size, p.paint(colorDim, f.ModTime.Local().Format("2006-01-02 15:04")))
+		}
+		fmt.Fprintln(p.out, p.paint(colorPath, f.Root+"/"+f.Path))
+	}
+	p.out.Flush()
+	if result.Truncated {
+		p.truncated()
+	}
+}
+
+// matches writes matching lines as root/path:line:text and context lines
+// as root/path-line-text. Like grep, it prints each line once and separates
+// groups that are not adjacent with "--".
+func (p *printer) matches(result *filesearch.GrepResult) {
+	matched := make(map[string]bool, len(result.Matches))
+	for _, m := range result.Matches {
+		matched[fmt.Sprintf("%s/%s:%d", m.Root, m.Path, m.Line)] = true
+	}
+
+	context := false
+	lastFile, lastLine := "", 0
+	for _, m := range result.Matches {
+		file := m.Root + "/" + m.Path
+		if file != lastFile {
+			lastLine = 0
+		}
+		first := max(m.Line-len(m.Before), lastLine+1)
+		if len(m.Before) > 0 || len(m.After) > 0 {
+			context = true
+		}
+		if context && lastFile != "" && (file != lastFile || first > lastLine+1) {
+			fmt.Fprintln(p.out, p.paint(colorSep, "--"))
+		}
+
+		for i, line := range m.Before {
+			if n := m.Line - len(m.Before) + i; n >= first {
+				p.line(file, n, "-", line, false)
+			}
+		}
+		p.line(file, m.Line, ":", m.Text, true)
+		lastFile, lastLine = file, m.Line
+		for i, line := range m.After {
+			n := m.Line + i + 1
+			// A later match prints its own line and context
+			if matched[fmt.Sprintf("%s:%d", file, n)] {
+				break
+			}
+			p.line(file, n, "-", line, false)
+			lastLine = n
+		}
+	}
+	p.out.Flush()
+	if result.Truncated {
+		p.truncated()
+	}
+}
+
+// line writes one line of a file, highlighting matches in matching lines
+func (p *printer) line(file string, n int, sep, text string, match bool) {
+	if match && p.color && p.highlight != nil {
+		var b strings.Builder
+		last := 0
+		for _, r := range p.highlight(text) {
+			b.WriteString(text[last:r[0]])
+			b.WriteString(p.paint(colorMatch, text[r[0]:r[1]]))
+			last = r[1]
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+	}
+	fmt.Fprintf(p.out, "%s%s%s%s%s\n", p.paint(colorPath, file), p.paint(colorSep, sep), p.paint(colorLine, fmt.Sprint(n)), p.paint(colorSep, sep), text)
+}
+
+// stats writes one indexed root per line
+func (p *printer) stats(stats []filesearch.IndexStats) {
+	if len(stats) == 0 {
+		fmt.Fprintln(p.out, "The index is empty; run filesearch index build")
+	}
+	for _, s := range stats {
+		fmt.Fprintf(p.out, "%s: %d files, %s, built %s\n", p.paint(colorPath, s.Root), s.Files,
+			formatBytes(s.Bytes), p.paint(colorDim, s.BuiltAt.Local().Format("2006-01-02 15:04:05")))
+	}
+	p.out.Flush()
+}
+
+// highlighter returns a function finding the matches of a grep query in a
+// line, as the engine matches them
+func highlighter(query string, regex, caseSensitive bool) func(string) [][]int {
+	expr := query
+	if !regex {
+		expr = regexp.QuoteMeta(query)
+	}
+	if !caseSensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil
+	}
+	return func(line string) [][]int {
+		return re.FindAllStringIndex(line, -1)
+	}
+}
+
+// formatBytes renders a size with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
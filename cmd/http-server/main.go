@@ -1,27 +1,96 @@
 package main
 
 import (
-	"log"
-	"net/http"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/aawadall/go-mcp-filesearch/internal/server"
+	"github.com/aawadall/go-mcp-filesearch/internal/app"
 )
 
 func main() {
-	// Get port from environment variable or use default
-	port := os.Getenv("MCP_HTTP_PORT")
-	if port == "" {
-		port = "8080"
+	// Load settings from defaults, the config file, MCP_* variables and flags
+	cfg, done, err := app.LoadConfig("http-server", os.Args[1:], os.Stdout, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
 	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if done {
+		return
+	}
+
+	logFile, err := app.SetupLogging(cfg, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	defer logFile.Close()
 
 	// Create MCP server instance
-	mcpServer := server.NewMCPServer()
+	mcpServer, err := app.NewMCPServer(cfg)
+	if err != nil {
+		slog.Error("Failed to start server", "error", err)
+		os.Exit(1)
+	}
+	app.ForwardLogs(mcpServer)
 
 	// Create HTTP server
-	httpServer := server.NewHTTPMCPServer(mcpServer)
+	httpServer, srv, err := app.NewHTTPServer(cfg, mcpServer)
+	if err != nil {
+		slog.Error("Failed to start HTTP server", "error", err)
+		os.Exit(1)
+	}
+
+	// Apply configuration changes on SIGHUP or POST /admin/reload
+	reloader := app.NewReloader("http-server", os.Args[1:], cfg, mcpServer)
+	httpServer.SetReloader(reloader.Reload)
+	reloader.AddReadinessChecks(httpServer)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			// Certificates come from the reloading TLS config
+			slog.Info("Starting HTTPS MCP server", "addr", srv.Addr)
+			serveErr <- srv.ListenAndServeTLS("", "")
+			return
+		}
+		slog.Info("Starting HTTP MCP server", "addr", srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
 
-	// Start HTTP server
-	log.Printf("Starting HTTP MCP server on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, httpServer))
+	// Run until the listener fails or we are asked to stop
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go reloader.ReloadOnSignal(ctx)
+
+	// Keep the index current and tell subscribers about changed files
+	if cfg.Watch.Enabled {
+		go mcpServer.WatchFiles(ctx, cfg.Watch.Interval)
+	}
+
+	select {
+	case err := <-serveErr:
+		slog.Error("HTTP server failed", "error", err)
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+
+	shutdownTimeout := cfg.HTTP.ShutdownTimeout
+	slog.Info("Shutting down, draining in-flight requests", "timeout", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx, srv); err != nil {
+		slog.Warn("Shutdown deadline exceeded, in-flight requests were cancelled", "error", err)
+		return
+	}
+	slog.Info("Server stopped")
 }
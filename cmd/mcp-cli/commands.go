@@ -0,0 +1,462 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aawadall/go-mcp-filesearch/pkg/client"
+)
+
+// command is a REPL command
+type command struct {
+	name string
+	args string
+	help string
+	// run executes the command with the words after its name and the raw
+	// text they came from
+	run func(c *cli, ctx context.Context, args []string, rest string) error
+}
+
+// errQuit ends the REPL
+var errQuit = errors.New("quit")
+
+// logLevels are the levels accepted by logging/setLevel
+var logLevels = []string{"debug", "info", "notice", "warning", "error", "critical", "alert", "emergency"}
+
+// commands lists the REPL commands in the order help shows them. It is
+// filled in by init because help refers to it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"help", "", "show this help", (*cli).help},
+		{"tools", "", "list the server's tools", (*cli).listTools},
+		{"tool", "<name>", "describe a tool and its arguments", (*cli).describeTool},
+		{"call", "<tool> [name=value ...] | <tool> {json}", "call a tool", (*cli).callTool},
+		{"resources", "", "list the server's resources", (*cli).listResources},
+		{"read", "<uri>", "read a resource", (*cli).readResource},
+		{"subscribe", "<uri>", "show updates of a resource", (*cli).subscribe},
+		{"unsubscribe", "<uri>", "stop showing updates of a resource", (*cli).unsubscribe},
+		{"loglevel", "<level>", "show server log messages at level and above", (*cli).setLogLevel},
+		{"rpc", "<method> [json]", "send any request; the method name alone works too", (*cli).rpc},
+		{"record", "[file | off]", "record the session's messages to a file as JSON lines", (*cli).record},
+		{"json", "[on | off]", "print results as raw JSON", (*cli).toggleJSON},
+		{"quit", "", "leave; Ctrl-D works too", func(*cli, context.Context, []string, string) error { return errQuit }},
+	}
+}
+
+// findCommand returns the command called name
+func findCommand(name string) (command, bool) {
+	if name == "exit" {
+		name = "quit"
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// execute runs one line of input
+func (c *cli) execute(ctx context.Context, line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	name, rest, _ := strings.Cut(line, " ")
+	rest = strings.TrimSpace(rest)
+	args, err := splitWords(rest)
+	if err != nil {
+		return err
+	}
+
+	if cmd, ok := findCommand(name); ok {
+		return cmd.run(c, ctx, args, rest)
+	}
+	if strings.ContainsAny(name, "/.") || c.isMethod(name) {
+		return c.sendRequest(ctx, name, rest)
+	}
+	return fmt.Errorf("unknown command %q; try help", name)
+}
+
+func (c *cli) help(ctx context.Context, args []string, rest string) error {
+	var b strings.Builder
+	for _, cmd := range commands {
+		usage := cmd.name
+		if cmd.args != "" {
+			usage += " " + cmd.args
+		}
+		fmt.Fprintf(&b, "  %-48s %s\n", usage, cmd.help)
+	}
+	b.WriteString("\nTab completes commands, methods, tools, argument names and resource URIs.\n")
+	b.WriteString("Ctrl-C cancels a running request.")
+	c.print(b.String())
+	return nil
+}
+
+func (c *cli) listTools(ctx context.Context, args []string, rest string) error {
+	tools, err := c.refreshTools(ctx)
+	if err != nil {
+		return err
+	}
+	if c.raw {
+		return c.printJSON(tools)
+	}
+
+	width := 0
+	for _, tool := range tools {
+		width = max(width, len(tool.Name))
+	}
+	var b strings.Builder
+	for _, tool := range tools {
+		name := fmt.Sprintf("%-*s", width, tool.Name)
+		fmt.Fprintf(&b, "%s  %s", c.paint(colorBold, name), tool.Description)
+		if tool.Annotations != nil && tool.Annotations.ReadOnlyHint {
+			b.WriteString(c.paint(colorDim, " (read-only)"))
+		}
+		b.WriteString("\n")
+	}
+	c.print(b.String())
+	return nil
+}
+
+func (c *cli) describeTool(ctx context.Context, args []string, rest string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: tool <name>")
+	}
+	tool, err := c.findTool(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if c.raw {
+		return c.printJSON(tool)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n  %s\n", c.paint(colorBold, tool.Name), tool.Description)
+	required := map[string]bool{}
+	for _, name := range stringList(tool.InputSchema["required"]) {
+		required[name] = true
+	}
+	properties := schemaProperties(tool.InputSchema)
+	if len(properties) > 0 {
+		b.WriteString("\nArguments:\n")
+	}
+	for _, name := range sortedKeys(properties) {
+		prop, _ := properties[name].(map[string]interface{})
+		kind := schemaType(prop)
+		if values := stringList(prop["enum"]); len(values) > 0 {
+			kind = strings.Join(values, "|")
+		}
+		flag := ""
+		if required[name] {
+			flag = c.paint(colorYellow, " required")
+		}
+		description, _ := prop["description"].(string)
+		fmt.Fprintf(&b, "  %s %s%s\n      %s\n", c.paint(colorBold, name), c.paint(colorDim, kind), flag, description)
+	}
+	c.print(b.String())
+	return nil
+}
+
+func (c *cli) callTool(ctx context.Context, args []string, rest string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: call <tool> [name=value ...] | <tool> {json}")
+	}
+	name := args[0]
+
+	var arguments map[string]interface{}
+	if raw := strings.TrimSpace(strings.TrimPrefix(rest, name)); strings.HasPrefix(raw, "{") {
+		if err := json.Unmarshal([]byte(raw), &arguments); err != nil {
+			return fmt.Errorf("arguments: %w", err)
+		}
+	} else {
+		tool, err := c.findTool(ctx, name)
+		if err != nil {
+			return err
+		}
+		if arguments, err = parseArguments(tool.InputSchema, args[1:]); err != nil {
+			return err
+		}
+	}
+
+	ctx = client.WithProgress(ctx, func(p client.Progress) {
+		c.print(c.paint(colorDim, "… "+progressText(p)))
+	})
+	result, err := c.client.CallTool(ctx, name, arguments)
+	if err != nil {
+		return err
+	}
+	if c.raw {
+		return c.printJSON(result)
+	}
+	c.printToolResult(result)
+	return nil
+}
+
+func (c *cli) listResources(ctx context.Context, args []string, rest string) error {
+	resources, err := c.refreshResources(ctx)
+	if err != nil {
+		return err
+	}
+	if c.raw {
+		return c.printJSON(resources)
+	}
+
+	width := 0
+	for _, resource := range resources {
+		width = max(width, len(resource.URI))
+	}
+	var b strings.Builder
+	for _, resource := range resources {
+		uri := fmt.Sprintf("%-*s", width, resource.URI)
+		fmt.Fprintf(&b, "%s  %s %s\n", c.paint(colorBold, uri), resource.Name, c.paint(colorDim, resource.MimeType))
+	}
+	c.print(b.String())
+	return nil
+}
+
+func (c *cli) readResource(ctx context.Context, args []string, rest string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: read <uri>")
+	}
+	contents, err := c.client.ReadResource(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if c.raw {
+		return c.printJSON(contents)
+	}
+	for _, content := range contents {
+		c.printContents(content)
+	}
+	return nil
+}
+
+func (c *cli) subscribe(ctx context.Context, args []string, rest string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: subscribe <uri>")
+	}
+	if err := c.client.Subscribe(ctx, args[0]); err != nil {
+		return err
+	}
+	c.print("subscribed to " + args[0])
+	return nil
+}
+
+func (c *cli) unsubscribe(ctx context.Context, args []string, rest string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: unsubscribe <uri>")
+	}
+	return c.client.Unsubscribe(ctx, args[0])
+}
+
+func (c *cli) setLogLevel(ctx context.Context, args []string, rest string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: loglevel <%s>", strings.Join(logLevels, "|"))
+	}
+	return c.client.SetLogLevel(ctx, args[0])
+}
+
+func (c *cli) rpc(ctx context.Context, args []string, rest string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: rpc <method> [json]")
+	}
+	return c.sendRequest(ctx, args[0], strings.TrimSpace(strings.TrimPrefix(rest, args[0])))
+}
+
+// sendRequest sends a request with params given as JSON and prints the
+// result as JSON
+func (c *cli) sendRequest(ctx context.Context, method, params string) error {
+	var decoded interface{}
+	if params != "" {
+		if err := json.Unmarshal([]byte(params), &decoded); err != nil {
+			return fmt.Errorf("params: %w", err)
+		}
+	}
+	var result json.RawMessage
+	if err := c.client.Call(ctx, method, decoded, &result); err != nil {
+		return err
+	}
+	return c.printJSON(result)
+}
+
+func (c *cli) record(ctx context.Context, args []string, rest string) error {
+	switch {
+	case len(args) == 0:
+		if path := c.recorder.recording(); path != "" {
+			c.print("recording to " + path)
+		} else {
+			c.print("not recording")
+		}
+	case len(args) == 1 && args[0] == "off":
+		c.recorder.stop()
+	case len(args) == 1:
+		if err := c.recorder.start(args[0]); err != nil {
+			return err
+		}
+		c.print("recording to " + args[0])
+	default:
+		return fmt.Errorf("usage: record [file | off]")
+	}
+	return nil
+}
+
+func (c *cli) toggleJSON(ctx context.Context, args []string, rest string) error {
+	switch {
+	case len(args) == 0:
+		c.raw = !c.raw
+	case args[0] == "on":
+		c.raw = true
+	case args[0] == "off":
+		c.raw = false
+	default:
+		return fmt.Errorf("usage: json [on | off]")
+	}
+	return nil
+}
+
+// parseArguments builds tool arguments from name=value words, converting
+// each value to the type its schema declares. Values of undeclared
+// arguments are taken as JSON if they parse, and as strings otherwise.
+func parseArguments(schema map[string]interface{}, words []string) (map[string]interface{}, error) {
+	properties := schemaProperties(schema)
+	arguments := map[string]interface{}{}
+	for _, word := range words {
+		name, raw, ok := strings.Cut(word, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("expected name=value, got %q", word)
+		}
+		prop, _ := properties[name].(map[string]interface{})
+		value, err := parseValue(prop, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		arguments[name] = value
+	}
+	return arguments, nil
+}
+
+// parseValue converts raw to the type declared by schema
+func parseValue(schema map[string]interface{}, raw string) (interface{}, error) {
+	switch schemaType(schema) {
+	case "string":
+		return raw, nil
+	case "integer":
+		return strconv.ParseInt(raw, 10, 64)
+	case "number":
+		return strconv.ParseFloat(raw, 64)
+	case "boolean":
+		return strconv.ParseBool(raw)
+	case "array":
+		if strings.HasPrefix(raw, "[") {
+			break
+		}
+		// A comma-separated list of items
+		items, _ := schema["items"].(map[string]interface{})
+		values := []interface{}{}
+		for _, item := range strings.Split(raw, ",") {
+			if item == "" {
+				continue
+			}
+			value, err := parseValue(items, item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		if schemaType(schema) != "" {
+			return nil, err
+		}
+		return raw, nil
+	}
+	return value, nil
+}
+
+// splitWords splits s at spaces, keeping quoted text together and removing
+// the quotes and backslash escapes
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// schemaProperties returns the properties of an object schema
+func schemaProperties(schema map[string]interface{}) map[string]interface{} {
+	properties, _ := schema["properties"].(map[string]interface{})
+	return properties
+}
+
+// schemaType returns the type a schema declares, or "" if it declares none
+func schemaType(schema map[string]interface{}) string {
+	kind, _ := schema["type"].(string)
+	return kind
+}
+
+// stringList returns the strings in a decoded JSON array
+func stringList(v interface{}) []string {
+	items, _ := v.([]interface{})
+	var list []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// defaultMethods are offered for completion when the server does not
+// describe its methods with rpc.discover
+var defaultMethods = []string{
+	"initialize",
+	"tools/list", "tools/call",
+	"resources/list", "resources/read", "resources/subscribe", "resources/unsubscribe",
+	"logging/setLevel",
+}
+
+// complete returns the completions of the word ending at pos: commands and
+// methods first, then the tool, argument names and values, resource URIs
+// or options each command takes
+func (c *cli) complete(line string, pos int) (int, []string) {
+	line = line[:pos]
+	start := strings.LastIndexAny(line, " \t") + 1
+	word := line[start:]
+	previous := strings.Fields(line[:start])
+
+	if len(previous) == 0 {
+		return start, matching(word, c.firstWords())
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch command := previous[0]; {
+	case (command == "call" || command == "tool") && len(previous) == 1:
+		names := make([]string, len(c.tools))
+		for i, tool := range c.tools {
+			names[i] = tool.Name
+		}
+		return start, matching(word, names)
+	case command == "call":
+		return c.completeArgument(previous[1], previous[2:], start, word)
+	case (command == "read" || command == "subscribe" || command == "unsubscribe") && len(previous) == 1:
+		uris := make([]string, len(c.resources))
+		for i, resource := range c.resources {
+			uris[i] = resource.URI
+		}
+		return start, matching(word, uris)
+	case command == "loglevel" && len(previous) == 1:
+		return start, matching(word, logLevels)
+	case command == "json" && len(previous) == 1:
+		return start, matching(word, []string{"on", "off"})
+	case command == "record" && len(previous) == 1:
+		return start, matching(word, []string{"off"})
+	case command == "rpc" && len(previous) == 1:
+		return start, matching(word, c.methodNames())
+	}
+	return start, nil
+}
+
+// completeArgument completes a name=value argument of a tool call: the
+// names of the arguments not given yet, then the values an argument's
+// schema allows
+func (c *cli) completeArgument(toolName string, given []string, start int, word string) (int, []string) {
+	var properties map[string]interface{}
+	for _, tool := range c.tools {
+		if tool.Name == toolName {
+			properties = schemaProperties(tool.InputSchema)
+		}
+	}
+
+	if name, value, ok := strings.Cut(word, "="); ok {
+		prop, _ := properties[name].(map[string]interface{})
+		values := stringList(prop["enum"])
+		if schemaType(prop) == "boolean" {
+			values = []string{"true", "false"}
+		}
+		return start + len(name) + 1, matching(value, values)
+	}
+
+	used := map[string]bool{}
+	for _, arg := range given {
+		name, _, _ := strings.Cut(arg, "=")
+		used[name] = true
+	}
+	var names []string
+	for _, name := range sortedKeys(properties) {
+		if !used[name] {
+			names = append(names, name+"=")
+		}
+	}
+	return start, matching(word, names)
+}
+
+// firstWords returns the commands and methods that may start a line
+func (c *cli) firstWords() []string {
+	words := []string{"exit"}
+	for _, cmd := range commands {
+		words = append(words, cmd.name)
+	}
+	c.mu.Lock()
+	words = append(words, c.methodNames()...)
+	c.mu.Unlock()
+	return words
+}
+
+// methodNames returns the server's methods; c.mu must be held
+func (c *cli) methodNames() []string {
+	if len(c.methods) > 0 {
+		return c.methods
+	}
+	return defaultMethods
+}
+
+// isMethod reports whether name is one of the server's methods
+func (c *cli) isMethod(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, method := range c.methodNames() {
+		if method == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matching returns the words starting with prefix, sorted
+func matching(prefix string, words []string) []string {
+	var matches []string
+	for _, word := range words {
+		if strings.HasPrefix(word, prefix) {
+			matches = append(matches, word)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// maxHistory is how many entered lines the editor remembers
+const maxHistory = 500
+
+// completer returns the candidates for the word that ends at pos in line,
+// and where that word starts
+type completer func(line string, pos int) (start int, candidates []string)
+
+// lineEditor reads commands from the terminal with editing, history and
+// tab completion, and lets other goroutines print above the line being
+// edited. When stdin is not a terminal it reads plain lines.
+type lineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	fd       int
+	terminal bool
+	prompt   string
+	complete completer
+	history  []string
+
+	// mu guards the output and the line being edited
+	mu      sync.Mutex
+	editing bool
+	line    []rune
+	pos     int
+}
+
+// newLineEditor returns an editor reading from stdin and writing to stdout
+func newLineEditor(prompt string, complete completer) *lineEditor {
+	return &lineEditor{
+		in:       bufio.NewReader(os.Stdin),
+		out:      os.Stdout,
+		fd:       int(os.Stdin.Fd()),
+		terminal: isTerminal(int(os.Stdin.Fd())) && isTerminal(int(os.Stdout.Fd())),
+		prompt:   prompt,
+		complete: complete,
+	}
+}
+
+// Print writes text above the line being edited, if any, and redraws the
+// line below it
+func (e *lineEditor) Print(text string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	if !e.editing {
+		io.WriteString(e.out, text)
+		return
+	}
+	io.WriteString(e.out, "\r\x1b[K"+text)
+	e.redraw()
+}
+
+// ReadLine reads one line. It returns io.EOF when the input ends or, on a
+// terminal, when Ctrl-D is pressed on an empty line.
+func (e *lineEditor) ReadLine() (string, error) {
+	if !e.terminal {
+		line, err := e.in.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	state, err := makeRaw(e.fd)
+	if err != nil {
+		e.terminal = false
+		return e.ReadLine()
+	}
+	defer restore(e.fd, state)
+
+	e.mu.Lock()
+	e.editing, e.line, e.pos = true, nil, 0
+	e.redraw()
+	e.mu.Unlock()
+
+	historyPos := len(e.history)
+	draft := ""
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			e.finish()
+			return "", err
+		}
+
+		e.mu.Lock()
+		switch r {
+		case '\r', '\n':
+			line := string(e.line)
+			e.mu.Unlock()
+			e.finish()
+			e.remember(line)
+			return line, nil
+		case 4: // Ctrl-D
+			if len(e.line) == 0 {
+				e.mu.Unlock()
+				e.finish()
+				return "", io.EOF
+			}
+			e.deleteAt(e.pos)
+		case 3: // Ctrl-C abandons the line
+			io.WriteString(e.out, "^C\r\n")
+			e.line, e.pos = nil, 0
+			historyPos = len(e.history)
+		case '\t':
+			e.tab()
+		case 127, 8: // Backspace
+			if e.pos > 0 {
+				e.pos--
+				e.deleteAt(e.pos)
+			}
+		case 1: // Ctrl-A
+			e.pos = 0
+		case 5: // Ctrl-E
+			e.pos = len(e.line)
+		case 11: // Ctrl-K
+			e.line = e.line[:e.pos]
+		case 21: // Ctrl-U
+			e.line, e.pos = append([]rune{}, e.line[e.pos:]...), 0
+		case 23: // Ctrl-W
+			start := wordStart(e.line, e.pos)
+			e.line = append(e.line[:start], e.line[e.pos:]...)
+			e.pos = start
+		case 12: // Ctrl-L
+			io.WriteString(e.out, "\x1b[H\x1b[2J")
+		case 27:
+			switch e.escape() {
+			case 'A':
+				if historyPos > 0 {
+					if historyPos == len(e.history) {
+						draft = string(e.line)
+					}
+					historyPos--
+					e.line = []rune(e.history[historyPos])
+					e.pos = len(e.line)
+				}
+			case 'B':
+				if historyPos < len(e.history) {
+					historyPos++
+					text := draft
+					if historyPos < len(e.history) {
+						text = e.history[historyPos]
+					}
+					e.line = []rune(text)
+					e.pos = len(e.line)
+				}
+			case 'C':
+				if e.pos < len(e.line) {
+					e.pos++
+				}
+			case 'D':
+				if e.pos > 0 {
+					e.pos--
+				}
+			case 'H':
+				e.pos = 0
+			case 'F':
+				e.pos = len(e.line)
+			case '~':
+				e.deleteAt(e.pos)
+			}
+		default:
+			if unicode.IsPrint(r) {
+				e.line = append(e.line[:e.pos], append([]rune{r}, e.line[e.pos:]...)...)
+				e.pos++
+			}
+		}
+		e.redraw()
+		e.mu.Unlock()
+	}
+}
+
+// escape reads the rest of an escape sequence and returns its final
+// character, mapping the numbered Home, End and Delete keys to 'H', 'F'
+// and '~'
+func (e *lineEditor) escape() rune {
+	r, _, err := e.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return 0
+	}
+	var params strings.Builder
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return 0
+		}
+		if (r >= '0' && r <= '9') || r == ';' {
+			params.WriteRune(r)
+			continue
+		}
+		if r != '~' {
+			return r
+		}
+		switch params.String() {
+		case "1", "7":
+			return 'H'
+		case "4", "8":
+			return 'F'
+		case "3":
+			return '~'
+		default:
+			return 0
+		}
+	}
+}
+
+// deleteAt removes the rune at i, if there is one
+func (e *lineEditor) deleteAt(i int) {
+	if i < len(e.line) {
+		e.line = append(e.line[:i], e.line[i+1:]...)
+	}
+}
+
+// wordStart returns where the word before pos starts
+func wordStart(line []rune, pos int) int {
+	i := pos
+	for i > 0 && line[i-1] == ' ' {
+		i--
+	}
+	for i > 0 && line[i-1] != ' ' {
+		i--
+	}
+	return i
+}
+
+// tab completes the word before the cursor. A single candidate is
+// inserted; several are listed below the line after inserting their
+// common prefix.
+func (e *lineEditor) tab() {
+	if e.complete == nil {
+		return
+	}
+	before := string(e.line[:e.pos])
+	start, candidates := e.complete(before, len(before))
+	if len(candidates) == 0 {
+		return
+	}
+	start = len([]rune(before[:start]))
+
+	insert := commonPrefix(candidates)
+	if len(candidates) == 1 && !strings.HasSuffix(insert, "=") && !strings.HasSuffix(insert, "/") {
+		insert += " "
+	}
+	if typed := e.pos - start; len([]rune(insert)) > typed || len(candidates) == 1 {
+		rest := append([]rune(insert), e.line[e.pos:]...)
+		e.line = append(e.line[:start], rest...)
+		e.pos = start + len([]rune(insert))
+		return
+	}
+
+	// Nothing more to insert, so show the choices
+	io.WriteString(e.out, "\r\n"+columns(candidates, 80))
+}
+
+// commonPrefix returns the longest prefix shared by all words
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// columns lays words out in columns fitting width
+func columns(words []string, width int) string {
+	longest := 0
+	for _, word := range words {
+		longest = max(longest, len(word))
+	}
+	perLine := max(1, width/(longest+2))
+
+	var b strings.Builder
+	for i, word := range words {
+		b.WriteString(word)
+		if (i+1)%perLine == 0 || i == len(words)-1 {
+			b.WriteString("\r\n")
+		} else {
+			b.WriteString(strings.Repeat(" ", longest+2-len(word)))
+		}
+	}
+	return b.String()
+}
+
+// redraw writes the prompt and line and places the cursor
+func (e *lineEditor) redraw() {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", e.prompt, string(e.line))
+	if back := len(e.line) - e.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+// finish ends editing and moves to the next line
+func (e *lineEditor) finish() {
+	e.mu.Lock()
+	e.editing = false
+	io.WriteString(e.out, "\r\n")
+	e.mu.Unlock()
+}
+
+// remember adds a line to the history, skipping blanks and repeats
+func (e *lineEditor) remember(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if n := len(e.history); n > 0 && e.history[n-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[1:]
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+	"github.com/aawadall/go-mcp-filesearch/pkg/client"
+)
+
+// cli is an interactive session with one server
+type cli struct {
+	client   *client.Client
+	recorder *recorder
+	editor   *lineEditor
+	timeout  time.Duration
+	color    bool
+	// raw prints results as JSON
+	raw bool
+
+	// mu guards the lists used for completion, which notifications refresh
+	mu        sync.Mutex
+	methods   []string
+	tools     []client.Tool
+	resources []client.Resource
+}
+
+// multiFlag collects the values of a repeated flag
+type multiFlag []string
+
+func (m *multiFlag) String() string     { return strings.Join(*m, ", ") }
+func (m *multiFlag) Set(v string) error { *m = append(*m, v); return nil }
+
+func main() {
+	fs := flag.NewFlagSet("mcp-cli", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: mcp-cli [flags] -url URL\n       mcp-cli [flags] -- command [args...]\n\n")
+		fmt.Fprintf(fs.Output(), "Talk to an MCP server over HTTP or by running it and speaking over its\nstandard input and output. Type help at the prompt for the commands.\n\n")
+		fs.PrintDefaults()
+	}
+
+	url := fs.String("url", "", "MCP endpoint of an HTTP server, such as http://localhost:8080/mcp")
+	stateless := fs.Bool("stateless", false, "post each request on its own instead of holding a session; notifications are not received")
+	var headers multiFlag
+	fs.Var(&headers, "header", `header sent with every HTTP request, as "Name: value"; may be repeated`)
+	recordPath := fs.String("record", "", "record the session's messages to this file as JSON lines")
+	logLevel := fs.String("log-level", "", "show server log messages at this level and above")
+	raw := fs.Bool("json", false, "print results as raw JSON")
+	timeout := fs.Duration("timeout", 0, "give up on requests after this long; zero waits until Ctrl-C")
+	stderr := fs.Bool("stderr", false, "show the standard error of a server run as a command")
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return
+		}
+		os.Exit(2)
+	}
+
+	var transport client.Transport
+	switch {
+	case *url != "" && fs.NArg() > 0:
+		fmt.Fprintln(os.Stderr, "mcp-cli: give either -url or a command, not both")
+		os.Exit(2)
+	case *url != "":
+		header, err := parseHeaders(headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "mcp-cli: -header: %v\n", err)
+			os.Exit(2)
+		}
+		if *stateless {
+			t := client.NewHTTPTransport(*url)
+			t.Header = header
+			transport = t
+		} else {
+			t := client.NewStreamableHTTPTransport(*url)
+			t.Header = header
+			transport = t
+		}
+	case fs.NArg() > 0:
+		t := client.NewStdioTransport(fs.Arg(0), fs.Args()[1:]...)
+		if *stderr {
+			t.Stderr = os.Stderr
+		}
+		transport = t
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	c := &cli{
+		recorder: &recorder{Transport: transport},
+		timeout:  *timeout,
+		raw:      *raw,
+		color:    isTerminal(int(os.Stdout.Fd())) && os.Getenv("NO_COLOR") == "",
+	}
+	c.editor = newLineEditor("mcp> ", c.complete)
+	if *recordPath != "" {
+		if err := c.recorder.start(*recordPath); err != nil {
+			fmt.Fprintf(os.Stderr, "mcp-cli: -record: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	c.client = client.New(c.recorder)
+	c.client.SetClientInfo("mcp-cli", models.ServerVersion)
+	c.client.OnNotification("", c.showNotification)
+	defer c.client.Close()
+
+	ctx, cancel := c.requestContext()
+	info, err := c.client.Connect(ctx)
+	cancel()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mcp-cli: connecting: %v\n", err)
+		os.Exit(1)
+	}
+	c.print(fmt.Sprintf("Connected to %s %s (protocol %s). Type help for commands.",
+		info.ServerInfo.Name, info.ServerInfo.Version, info.ProtocolVersion))
+
+	if *logLevel != "" {
+		ctx, cancel := c.requestContext()
+		err := c.client.SetLogLevel(ctx, *logLevel)
+		cancel()
+		if err != nil {
+			c.printError(fmt.Errorf("-log-level: %w", err))
+		}
+	}
+	c.loadCompletions()
+
+	for {
+		line, err := c.editor.ReadLine()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "mcp-cli: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel := c.requestContext()
+		err = c.execute(ctx, line)
+		cancel()
+		switch {
+		case errors.Is(err, errQuit):
+			return
+		case errors.Is(err, context.Canceled):
+			c.printError(errors.New("canceled"))
+		case err != nil:
+			c.printError(err)
+		}
+	}
+}
+
+// requestContext returns the context of one command, canceled by Ctrl-C
+// and by the -timeout
+func (c *cli) requestContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if c.timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// parseHeaders parses "Name: value" header flags
+func parseHeaders(values []string) (http.Header, error) {
+	header := make(http.Header)
+	for _, value := range values {
+		name, v, ok := strings.Cut(value, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("expected \"Name: value\", got %q", value)
+		}
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(v))
+	}
+	return header, nil
+}
+
+// showNotification prints a notification as it arrives and refreshes the
+// lists a list_changed notification reports as stale. Progress is shown by
+// the call that asked for it.
+func (c *cli) showNotification(n client.Notification) {
+	switch n.Method {
+	case models.NotificationProgress:
+	case models.NotificationMessage:
+		var msg client.LogMessage
+		if json.Unmarshal(n.Params, &msg) != nil {
+			break
+		}
+		text := string(msg.Data)
+		var s string
+		if json.Unmarshal(msg.Data, &s) == nil {
+			text = s
+		}
+		if msg.Logger != "" {
+			text = msg.Logger + ": " + text
+		}
+		c.print(c.paint(levelColor(msg.Level), "["+msg.Level+"] ") + text)
+	case models.NotificationResourceUpdated:
+		var params struct {
+			URI string `json:"uri"`
+		}
+		json.Unmarshal(n.Params, &params)
+		c.print(c.paint(colorCyan, "updated: ") + params.URI)
+	case models.NotificationToolsListChanged:
+		c.print(c.paint(colorCyan, "tools changed"))
+		go c.refreshTools(context.Background())
+	case models.NotificationResourcesListChanged:
+		c.print(c.paint(colorCyan, "resources changed"))
+		go c.refreshResources(context.Background())
+	default:
+		text := n.Method
+		if len(n.Params) > 0 {
+			text += " " + string(n.Params)
+		}
+		c.print(c.paint(colorCyan, text))
+	}
+}
+
+// loadCompletions fetches the methods, tools and resources offered for
+// completion. Failures only leave a list empty.
+func (c *cli) loadCompletions() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if doc, err := c.client.Discover(ctx); err == nil {
+		methods, _ := doc["methods"].([]interface{})
+		var names []string
+		for _, method := range methods {
+			m, _ := method.(map[string]interface{})
+			if name, ok := m["name"].(string); ok {
+				names = append(names, name)
+			}
+		}
+		c.mu.Lock()
+		c.methods = names
+		c.mu.Unlock()
+	}
+	c.refreshTools(ctx)
+	c.refreshResources(ctx)
+}
+
+// refreshTools lists the server's tools and keeps them for completion
+func (c *cli) refreshTools(ctx context.Context) ([]client.Tool, error) {
+	tools, err := c.client.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.tools = tools
+	c.mu.Unlock()
+	return tools, nil
+}
+
+// refreshResources lists the server's resources and keeps them for
+// completion
+func (c *cli) refreshResources(ctx context.Context) ([]client.Resource, error) {
+	resources, err := c.client.ListResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.resources = resources
+	c.mu.Unlock()
+	return resources, nil
+}
+
+// findTool returns the tool called name, listing the tools again if it is
+// not among those last seen
+func (c *cli) findTool(ctx context.Context, name string) (client.Tool, error) {
+	if tool, ok := c.cachedTool(name); ok {
+		return tool, nil
+	}
+	if _, err := c.refreshTools(ctx); err != nil {
+		return client.Tool{}, err
+	}
+	if tool, ok := c.cachedTool(name); ok {
+		return tool, nil
+	}
+	return client.Tool{}, fmt.Errorf("unknown tool %q", name)
+}
+
+// cachedTool returns the tool called name from the last listing
+func (c *cli) cachedTool(name string) (client.Tool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tool := range c.tools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return client.Tool{}, false
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aawadall/go-mcp-filesearch/pkg/client"
+)
+
+// ANSI colors used when stdout is a terminal
+const (
+	colorBold   = "\x1b[1m"
+	colorDim    = "\x1b[2m"
+	colorRed    = "\x1b[31m"
+	colorYellow = "\x1b[33m"
+	colorCyan   = "\x1b[36m"
+	colorReset  = "\x1b[0m"
+)
+
+// paint colors text when color is enabled
+func (c *cli) paint(color, text string) string {
+	if !c.color || text == "" {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// levelColor returns the color of log messages at level
+func levelColor(level string) string {
+	switch level {
+	case "debug", "info", "notice":
+		return colorDim
+	case "warning":
+		return colorYellow
+	default:
+		return colorRed
+	}
+}
+
+// print writes text above the prompt
+func (c *cli) print(text string) {
+	c.editor.Print(strings.TrimRight(text, "\n"))
+}
+
+// printError writes an error in red
+func (c *cli) printError(err error) {
+	c.print(c.paint(colorRed, "error: "+err.Error()))
+}
+
+// printJSON writes v as indented JSON
+func (c *cli) printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	c.print(string(data))
+	return nil
+}
+
+// printToolResult writes a tool result's content. Text that holds JSON is
+// indented; a result without content shows its structured content.
+func (c *cli) printToolResult(result *client.ToolResult) {
+	var b strings.Builder
+	for _, content := range result.Content {
+		switch {
+		case content.Type == "text":
+			b.WriteString(indentJSON(content.Text))
+		case content.Resource != nil:
+			b.WriteString(c.paint(colorDim, content.Resource.URI) + "\n")
+			b.WriteString(contentsText(*content.Resource))
+		default:
+			b.WriteString(c.paint(colorDim, "["+content.Type+" content]"))
+		}
+		b.WriteString("\n")
+	}
+	if len(result.Content) == 0 && len(result.StructuredContent) > 0 {
+		b.WriteString(indentJSON(string(result.StructuredContent)))
+	}
+
+	if result.IsError {
+		c.print(c.paint(colorRed, "error: "+strings.TrimSpace(b.String())))
+		return
+	}
+	c.print(b.String())
+}
+
+// printContents writes the contents of a resource
+func (c *cli) printContents(contents client.ResourceContents) {
+	header := contents.URI
+	if contents.MimeType != "" {
+		header += " (" + contents.MimeType + ")"
+	}
+	c.print(c.paint(colorDim, header) + "\n" + contentsText(contents))
+}
+
+// contentsText returns resource contents as text, describing binary
+// contents instead of printing them
+func contentsText(contents client.ResourceContents) string {
+	if contents.Blob != nil {
+		return fmt.Sprintf("[%d bytes of binary data]", len(contents.Blob))
+	}
+	return indentJSON(contents.Text)
+}
+
+// indentJSON indents text that holds a JSON object or array and returns
+// other text unchanged
+func indentJSON(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return text
+	}
+	var v interface{}
+	if json.Unmarshal([]byte(trimmed), &v) != nil {
+		return text
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return text
+	}
+	return string(data)
+}
+
+// progressText describes a progress notification
+func progressText(p client.Progress) string {
+	text := fmt.Sprintf("%g", p.Progress)
+	if p.Total > 0 {
+		text = fmt.Sprintf("%g/%g", p.Progress, p.Total)
+	}
+	if p.Message != "" {
+		text += " " + p.Message
+	}
+	return text
+}
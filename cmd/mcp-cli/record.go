@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/pkg/client"
+)
+
+// recordEntry is one line of a session recording
+type recordEntry struct {
+	Time time.Time `json:"time"`
+	// Direction is "send" for messages to the server and "recv" for
+	// messages from it
+	Direction string          `json:"direction"`
+	Message   json.RawMessage `json:"message"`
+}
+
+// recorder wraps a transport and, while recording, appends every message
+// it carries to a file as JSON lines
+type recorder struct {
+	client.Transport
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Connect connects the transport, recording the messages it receives
+func (r *recorder) Connect(ctx context.Context, receive func([]byte), lost func(error)) error {
+	return r.Transport.Connect(ctx, func(message []byte) {
+		r.record("recv", message)
+		receive(message)
+	}, lost)
+}
+
+// Send records and sends a message
+func (r *recorder) Send(ctx context.Context, message []byte) error {
+	r.record("send", message)
+	return r.Transport.Send(ctx, message)
+}
+
+// Close stops recording and closes the transport
+func (r *recorder) Close() error {
+	r.stop()
+	return r.Transport.Close()
+}
+
+// start appends the following messages to the file at path
+func (r *recorder) start(path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	old := r.file
+	r.file = file
+	r.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// stop stops recording
+func (r *recorder) stop() {
+	r.mu.Lock()
+	file := r.file
+	r.file = nil
+	r.mu.Unlock()
+	if file != nil {
+		file.Close()
+	}
+}
+
+// recording returns the file being recorded to, or "" if none
+func (r *recorder) recording() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return ""
+	}
+	return r.file.Name()
+}
+
+// record appends a message to the recording, if one is running. Messages
+// that are not valid JSON are skipped.
+func (r *recorder) record(direction string, message []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil || !json.Valid(message) {
+		return
+	}
+	line, err := json.Marshal(recordEntry{Time: time.Now().UTC(), Direction: direction, Message: message})
+	if err != nil {
+		return
+	}
+	r.file.Write(append(line, '\n'))
+}
@@ -0,0 +1,24 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package main
+
+import "errors"
+
+// terminalState is the saved attributes of a terminal
+type terminalState struct{}
+
+// isTerminal reports whether fd is a terminal. Line editing is only
+// supported on Unix, so other systems read plain lines.
+func isTerminal(fd int) bool {
+	return false
+}
+
+// makeRaw is not supported on this system
+func makeRaw(fd int) (*terminalState, error) {
+	return nil, errors.New("line editing is not supported on this system")
+}
+
+// restore does nothing on this system
+func restore(fd int, state *terminalState) error {
+	return nil
+}
@@ -1,10 +1,53 @@
 package main
 
 import (
-	"github.com/aawadall/go-mcp-filesearch/internal/server"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/app"
 )
 
 func main() {
-	mcpServer := server.NewMCPServer()
+	// Load settings from defaults, the config file, MCP_* variables and flags
+	cfg, done, err := app.LoadConfig("server", os.Args[1:], os.Stdout, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if done {
+		return
+	}
+
+	// stdout carries the protocol, so logs must go elsewhere
+	logFile, err := app.SetupLogging(cfg, true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	defer logFile.Close()
+
+	mcpServer, err := app.NewMCPServer(cfg)
+	if err != nil {
+		slog.Error("Failed to start server", "error", err)
+		os.Exit(1)
+	}
+	app.ForwardLogs(mcpServer)
+
+	// Apply configuration changes on SIGHUP without restarting the session
+	reloader := app.NewReloader("server", os.Args[1:], cfg, mcpServer)
+	go reloader.ReloadOnSignal(context.Background())
+
+	// Keep the index current and tell subscribers about changed files
+	if cfg.Watch.Enabled {
+		go mcpServer.WatchFiles(context.Background(), cfg.Watch.Interval)
+	}
+
 	mcpServer.Run()
 }
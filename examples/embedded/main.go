@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/pkg/server"
+)
+
+func main() {
+	dir := "."
+	if len(os.Args) > 1 {
+		dir = os.Args[1]
+	}
+
+	// Search dir and offer a tool and a resource of our own
+	srv, err := server.NewServer(
+		server.WithRoot("project", dir),
+		server.WithTool(server.Tool{
+			Name:        "shout",
+			Description: "Upper-case the input text",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"text": map[string]interface{}{"type": "string"},
+				},
+				"required": []string{"text"},
+			},
+			ReadOnly: true,
+		}, func(ctx context.Context, args map[string]interface{}) (*server.ToolResult, error) {
+			text, _ := args["text"].(string)
+			return server.TextResult(strings.ToUpper(text)), nil
+		}),
+		server.WithResource(server.Resource{
+			URI:      "app://started",
+			Name:     "Start time",
+			MimeType: "text/plain",
+		}, func(ctx context.Context, uri string) ([]server.ResourceContents, error) {
+			return []server.ResourceContents{{URI: uri, Text: started.Format(time.RFC3339)}}, nil
+		}),
+		server.WithMiddleware(logRequests),
+		server.WithLogger(slog.New(slog.NewTextHandler(os.Stderr, nil))),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Mount the MCP server under /search next to the application's own
+	// endpoints
+	mux := http.NewServeMux()
+	mux.Handle("/search/", srv.Handler("/search"))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "MCP endpoint at /search/mcp")
+	})
+
+	fmt.Println("Listening on :8080, MCP endpoint at http://localhost:8080/search/mcp")
+	log.Fatal(http.ListenAndServe(":8080", mux))
+}
+
+// started is when the program started
+var started = time.Now()
+
+// logRequests logs each request to the MCP server
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s %s", r.Method, r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
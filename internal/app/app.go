@@ -0,0 +1,345 @@
+// Package app assembles the MCP servers from a loaded configuration so that
+// the stdio and HTTP binaries share the same wiring.
+package app
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/audit"
+	"github.com/aawadall/go-mcp-filesearch/internal/auth"
+	"github.com/aawadall/go-mcp-filesearch/internal/config"
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+	"github.com/aawadall/go-mcp-filesearch/internal/policy"
+	"github.com/aawadall/go-mcp-filesearch/internal/server"
+	"github.com/aawadall/go-mcp-filesearch/internal/trace"
+)
+
+// LoadConfig loads and validates the configuration of a server binary from
+// its command line. It handles -print-config and -check-config itself and
+// reports done when the binary should exit without serving.
+func LoadConfig(program string, args []string, stdout, stderr io.Writer) (cfg *config.Config, done bool, err error) {
+	cfg, opts, err := config.Load(program, args, stderr)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if opts.PrintConfig {
+		return cfg, true, cfg.Encode(stdout)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, false, err
+	}
+	if opts.CheckConfig {
+		fmt.Fprintln(stdout, "configuration OK")
+		return cfg, true, nil
+	}
+
+	return cfg, false, nil
+}
+
+// SetupLogging installs the default slog logger with the configured output,
+// level and format. The stdio transport owns stdout, so logging there is
+// rejected when stdio is set. The returned closer releases a log file, if
+// one was opened.
+func SetupLogging(cfg *config.Config, stdio bool) (io.Closer, error) {
+	var out io.Writer
+	closer := io.Closer(io.NopCloser(nil))
+
+	switch cfg.Logging.Output {
+	case "stderr":
+		out = os.Stderr
+	case "stdout":
+		if stdio {
+			return nil, fmt.Errorf("%s: logging.output: stdout is reserved for the stdio transport", cfg.Position("logging.output"))
+		}
+		out = os.Stdout
+	default:
+		file, err := os.OpenFile(cfg.Logging.Output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("%s: logging.output: %w", cfg.Position("logging.output"), err)
+		}
+		out, closer = file, file
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Logging.Level)); err != nil {
+		return nil, fmt.Errorf("%s: logging.level: %w", cfg.Position("logging.level"), err)
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler = slog.NewTextHandler(out, opts)
+	if cfg.Logging.Format == "json" {
+		handler = slog.NewJSONHandler(out, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+
+	return closer, nil
+}
+
+// ForwardLogs makes the default logger also send log records to MCP clients
+// that enabled logging with logging/setLevel
+func ForwardLogs(mcpServer *server.MCPServer) {
+	slog.SetDefault(slog.New(mcpServer.LogHandler(slog.Default().Handler())))
+}
+
+// NewFileSearch builds the file search handler for the configured roots,
+// loading the persisted index if one is enabled
+func NewFileSearch(cfg *config.Config) (*filesearch.Handler, error) {
+	return newFileSearch(cfg, nil)
+}
+
+// newFileSearch builds a file search handler, reusing index if it is not nil
+func newFileSearch(cfg *config.Config, index *filesearch.Index) (*filesearch.Handler, error) {
+	roots := make([]filesearch.Root, len(cfg.Roots))
+	for i, root := range cfg.Roots {
+		roots[i] = filesearch.Root{Name: root.Name, Path: root.Path}
+	}
+	registry, err := filesearch.NewRegistry(roots)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cfg.Index.Enabled {
+		index = nil
+	} else if index == nil {
+		index = filesearch.NewIndex(cfg.Index.Path)
+		if err := index.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	engine := filesearch.NewEngine(registry, index, filesearch.Options{
+		Ignore:      cfg.Search.Ignore,
+		MaxFileSize: cfg.Search.MaxFileSize,
+		MaxResults:  cfg.Search.MaxResults,
+	})
+	return filesearch.NewHandler(engine), nil
+}
+
+// NewMCPServer creates the protocol server with file search, the enabled
+// tools, the time limits and the authorization policy applied
+func NewMCPServer(cfg *config.Config) (*server.MCPServer, error) {
+	mcpServer := server.NewMCPServer()
+
+	files, err := NewFileSearch(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure file search: %w", err)
+	}
+	mcpServer.SetFileSearch(files)
+
+	if len(cfg.Tools.Enabled) > 0 {
+		mcpServer.SetEnabledTools(cfg.Tools.Enabled)
+	}
+
+	timeouts, err := serverTimeouts(cfg.Timeouts)
+	if err != nil {
+		return nil, fmt.Errorf("timeouts: %w", err)
+	}
+	mcpServer.SetTimeouts(timeouts)
+
+	if path := cfg.Auth.PolicyFile; path != "" {
+		engine, err := policy.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load policy: %w", err)
+		}
+		mcpServer.SetPolicy(engine)
+	}
+
+	if cfg.Audit.Path != "" {
+		auditLog, err := audit.Open(audit.Options{
+			Path:      cfg.Audit.Path,
+			MaxSize:   cfg.Audit.MaxSize,
+			MaxFiles:  cfg.Audit.MaxFiles,
+			HashChain: cfg.Audit.HashChain,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: audit.path: %w", cfg.Position("audit.path"), err)
+		}
+		mcpServer.SetAuditLog(auditLog)
+	}
+
+	if cfg.Tracing.Enabled {
+		tracer, traces, err := newTracer(cfg)
+		if err != nil {
+			return nil, err
+		}
+		mcpServer.SetTracer(tracer, traces)
+	}
+
+	return mcpServer, nil
+}
+
+// newTracer builds the tracer and its exporters: an in-memory buffer unless
+// its size is zero, and a file if one is configured
+func newTracer(cfg *config.Config) (*trace.Tracer, *trace.RingBuffer, error) {
+	var exporters []trace.Exporter
+
+	var traces *trace.RingBuffer
+	if cfg.Tracing.BufferSize > 0 {
+		traces = trace.NewRingBuffer(cfg.Tracing.BufferSize, models.ServerName)
+		exporters = append(exporters, traces)
+	}
+
+	if path := cfg.Tracing.File; path != "" {
+		file, err := trace.NewFileExporter(path, models.ServerName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: tracing.file: %w", cfg.Position("tracing.file"), err)
+		}
+		exporters = append(exporters, file)
+	}
+
+	return trace.NewTracer(exporters...), traces, nil
+}
+
+// NewHTTPServer wraps mcpServer in the HTTP transport and returns it along
+// with the configured listener, including TLS if a certificate is set
+func NewHTTPServer(cfg *config.Config, mcpServer *server.MCPServer) (*server.HTTPMCPServer, *http.Server, error) {
+	httpServer := server.NewHTTPMCPServer(mcpServer)
+
+	// Require API keys, bearer tokens or OAuth access tokens if configured
+	var authenticators auth.Chain
+	if path := cfg.Auth.CredentialsFile; path != "" {
+		authenticator, err := auth.LoadStaticAuthenticator(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load credentials: %w", err)
+		}
+		authenticators = append(authenticators, authenticator)
+	}
+	if cfg.Auth.OAuth.Issuer != "" {
+		authenticator, metadata, err := newOAuth(cfg.Auth.OAuth)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to configure OAuth: %w", err)
+		}
+		authenticators = append(authenticators, authenticator)
+		httpServer.SetResourceMetadata(metadata)
+	}
+	if len(authenticators) > 0 {
+		httpServer.SetAuthenticator(authenticators)
+	}
+
+	// Only allow configured browser origins
+	cors := server.DefaultCORSConfig()
+	cors.AllowedOrigins = cfg.HTTP.CORS.AllowedOrigins
+	cors.AllowedMethods = cfg.HTTP.CORS.AllowedMethods
+	cors.AllowedHeaders = cfg.HTTP.CORS.AllowedHeaders
+	httpServer.SetCORS(cors)
+
+	// Only answer to the local machine's names when bound to it, unless
+	// other hosts are configured
+	hosts := cfg.HTTP.AllowedHosts
+	if len(hosts) == 0 && server.IsLoopback(cfg.HTTP.Host) {
+		hosts = server.LoopbackHosts()
+	}
+	httpServer.SetAllowedHosts(hosts)
+
+	// Limit request rates and daily reads per client if configured
+	if rl := cfg.RateLimit; rl.Enabled() {
+		httpServer.SetRateLimits(server.RateLimitConfig{
+			Rate:           rl.Rate,
+			Burst:          rl.Burst,
+			ExpensiveRate:  rl.ExpensiveRate,
+			ExpensiveBurst: rl.ExpensiveBurst,
+			ExpensiveTools: rl.ExpensiveTools,
+			DailyReadBytes: rl.DailyReadBytes,
+		})
+	}
+
+	httpServer.SetUI(cfg.HTTP.UI)
+
+	// Bound request bodies and connection lifetimes
+	httpServer.SetMaxBodyBytes(cfg.HTTP.MaxBodyBytes)
+	httpServer.SetSessionLimits(cfg.HTTP.SessionTimeout, cfg.HTTP.MaxSessions)
+	srv := httpServer.NewServer(net.JoinHostPort(cfg.HTTP.Host, strconv.Itoa(cfg.HTTP.Port)), server.HTTPTimeouts{
+		ReadHeader: cfg.HTTP.ReadHeaderTimeout,
+		Read:       cfg.HTTP.ReadTimeout,
+		Write:      cfg.HTTP.WriteTimeout,
+		Idle:       cfg.HTTP.IdleTimeout,
+	})
+
+	// Serve plain HTTP unless a certificate is configured
+	if tls := cfg.HTTP.TLS; tls.Cert != "" || tls.Key != "" {
+		tlsConfig, err := server.NewTLSConfig(server.TLSConfig{
+			CertFile:     tls.Cert,
+			KeyFile:      tls.Key,
+			ClientCAFile: tls.ClientCA,
+			ClientAuth:   tls.ClientAuth,
+			Done:         httpServer.Done(),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to configure TLS: %w", err)
+		}
+		srv.TLSConfig = tlsConfig
+
+		// Map client certificate subjects to identities if configured
+		if tls.IdentityMap != "" {
+			identities, err := server.LoadIdentityMap(tls.IdentityMap)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to load identity map: %w", err)
+			}
+			httpServer.SetClientIdentities(identities)
+		}
+	}
+
+	return httpServer, srv, nil
+}
+
+// newOAuth builds a JWT authenticator and the matching protected resource
+// metadata
+func newOAuth(cfg config.OAuthConfig) (*auth.JWTAuthenticator, *auth.ProtectedResourceMetadata, error) {
+	keys, err := auth.LoadKeySet(cfg.JWKS)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Without a scope map, tokens are not restricted to particular tools
+	var scopeMap auth.ScopeMap
+	if cfg.ScopeMap != "" {
+		if scopeMap, err = auth.LoadScopeMap(cfg.ScopeMap); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	authenticator, err := auth.NewJWTAuthenticator(auth.JWTConfig{
+		Issuer:         cfg.Issuer,
+		Audience:       cfg.Resource,
+		RequiredScopes: cfg.RequiredScopes,
+		ScopeMap:       scopeMap,
+	}, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metadata := &auth.ProtectedResourceMetadata{
+		Resource:               cfg.Resource,
+		AuthorizationServers:   []string{cfg.Issuer},
+		ScopesSupported:        scopeMap.ScopesSupported(),
+		BearerMethodsSupported: []string{"header"},
+		ResourceName:           models.ServerName,
+	}
+
+	return authenticator, metadata, nil
+}
+
+// serverTimeouts converts the configured time limits to the server's form
+func serverTimeouts(cfg config.TimeoutsConfig) (server.Timeouts, error) {
+	methods, tools, err := cfg.Parse()
+	if err != nil {
+		return server.Timeouts{}, err
+	}
+	timeouts := server.Timeouts{Default: cfg.Default}
+	for _, t := range methods {
+		timeouts.Methods = append(timeouts.Methods, server.Timeout(t))
+	}
+	for _, t := range tools {
+		timeouts.Tools = append(timeouts.Tools, server.Timeout(t))
+	}
+	return timeouts, nil
+}
@@ -0,0 +1,345 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/config"
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/policy"
+	"github.com/aawadall/go-mcp-filesearch/internal/server"
+)
+
+// Reloader re-reads the configuration from the same file, environment and
+// flags the server started with, and applies the settings that can change
+// without a restart: roots, search and index settings, enabled tools and
+// the authorization policy.
+type Reloader struct {
+	program   string
+	args      []string
+	mcpServer *server.MCPServer
+
+	mu      sync.Mutex
+	current *config.Config
+	// lastReload and lastErr record the outcome of the latest reload
+	lastReload time.Time
+	lastErr    error
+}
+
+// NewReloader creates a reloader for a server started from cfg
+func NewReloader(program string, args []string, cfg *config.Config, mcpServer *server.MCPServer) *Reloader {
+	return &Reloader{program: program, args: args, mcpServer: mcpServer, current: cfg}
+}
+
+// Reload loads and validates the configuration and applies it atomically.
+// If the new configuration is invalid the running one is kept.
+func (rl *Reloader) Reload() (server.ReloadResult, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	result, err := rl.reload()
+	rl.lastReload, rl.lastErr = time.Now(), err
+	return result, err
+}
+
+// reload does the work of Reload with rl.mu held
+func (rl *Reloader) reload() (server.ReloadResult, error) {
+	next, _, err := config.Load(rl.program, rl.args, io.Discard)
+	if err != nil {
+		return server.ReloadResult{}, err
+	}
+	if err := next.Validate(); err != nil {
+		return server.ReloadResult{}, err
+	}
+
+	old := rl.current
+	changes := describeChanges(old, next)
+
+	// Roots added or removed through the admin API outlive the reload, so
+	// a configured root may not take the name of one added at runtime
+	files := rl.mcpServer.FileSearch()
+	added, removed := runtimeRoots(old, files)
+	for _, root := range added {
+		if hasRoot(next.Roots, root.Name) {
+			return server.ReloadResult{}, fmt.Errorf("root %q was added at runtime and is now also configured; remove it with DELETE /admin/roots/%s first", root.Name, root.Name)
+		}
+	}
+
+	// Keep the file search handler, and with it the index, unless its
+	// settings changed. The index may be shared with the running engine, so
+	// the entries of removed roots are dropped only once the reload can no
+	// longer fail.
+	var sharedIndex *filesearch.Index
+	var staleRoots []string
+	if files == nil || !reflect.DeepEqual(old.Roots, next.Roots) ||
+		!reflect.DeepEqual(old.Search, next.Search) || old.Index != next.Index {
+		var index *filesearch.Index
+		excludesChanged := !reflect.DeepEqual(old.Search.Ignore, next.Search.Ignore)
+		switch {
+		case !next.Index.Enabled:
+		case excludesChanged:
+			// The indexed file lists were built with the old excludes, so
+			// searches walk the roots until the index is rebuilt
+			index = filesearch.NewIndex(next.Index.Path)
+		case files != nil && old.Index == next.Index:
+			index = files.Engine().Index()
+			sharedIndex = index
+			for _, root := range old.Roots {
+				if !hasRoot(next.Roots, root.Name) {
+					staleRoots = append(staleRoots, root.Name)
+				}
+			}
+		}
+		if files, err = newFileSearch(next, index); err != nil {
+			return server.ReloadResult{}, fmt.Errorf("failed to configure file search: %w", err)
+		}
+		registry := files.Engine().Registry()
+		for _, name := range removed {
+			registry.Remove(name)
+		}
+		for _, root := range added {
+			if err := registry.Add(root); err != nil {
+				return server.ReloadResult{}, fmt.Errorf("failed to keep root %q added at runtime: %w", root.Name, err)
+			}
+		}
+	}
+
+	// Keep the policy engine if the policy is unchanged so that clients are
+	// not told their lists changed when they did not
+	engine := rl.mcpServer.Policy()
+	if path := next.Auth.PolicyFile; path == "" {
+		engine = nil
+	} else {
+		loaded, err := policy.Load(path)
+		if err != nil {
+			return server.ReloadResult{}, fmt.Errorf("failed to load policy: %w", err)
+		}
+		if !reflect.DeepEqual(loaded, engine) {
+			engine = loaded
+			changes = append(changes, "policy reloaded")
+		}
+	}
+
+	var enabledTools []string
+	if len(next.Tools.Enabled) > 0 {
+		enabledTools = next.Tools.Enabled
+	}
+
+	timeouts, err := serverTimeouts(next.Timeouts)
+	if err != nil {
+		return server.ReloadResult{}, err
+	}
+
+	if sharedIndex != nil {
+		for _, name := range staleRoots {
+			sharedIndex.Remove(name)
+		}
+	}
+
+	result := rl.mcpServer.Reconfigure(server.Runtime{
+		Files:        files,
+		EnabledTools: enabledTools,
+		Policy:       engine,
+		Timeouts:     timeouts,
+	})
+	result.Changes = changes
+	result.RestartRequired = restartRequired(old, next)
+	rl.current = next
+
+	if len(changes) == 0 {
+		slog.Info("Configuration reloaded, no changes")
+	} else {
+		slog.Info("Configuration reloaded", "changes", strings.Join(changes, "; "))
+	}
+	if len(result.RestartRequired) > 0 {
+		slog.Warn("Configuration changes take effect after a restart", "settings", strings.Join(result.RestartRequired, ", "))
+	}
+	return result, nil
+}
+
+// ReloadOnSignal reloads the configuration whenever the process receives
+// SIGHUP, until ctx is done. Rejected configurations are logged and the
+// running configuration is kept.
+func (rl *Reloader) ReloadOnSignal(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-hup:
+			slog.Info("Received SIGHUP, reloading configuration")
+			if _, err := rl.Reload(); err != nil {
+				slog.Error("Configuration rejected, keeping the running configuration", "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// describeChanges lists the reloadable differences between two configurations
+func describeChanges(old, next *config.Config) []string {
+	changes := []string{}
+
+	oldRoots := make(map[string]string, len(old.Roots))
+	for _, root := range old.Roots {
+		oldRoots[root.Name] = root.Path
+	}
+	nextRoots := make(map[string]string, len(next.Roots))
+	for _, root := range next.Roots {
+		nextRoots[root.Name] = root.Path
+	}
+
+	var added, removed, moved []string
+	for name, path := range nextRoots {
+		oldPath, ok := oldRoots[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case oldPath != path:
+			moved = append(moved, name)
+		}
+	}
+	for name := range oldRoots {
+		if _, ok := nextRoots[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	for _, c := range []struct {
+		verb  string
+		names []string
+	}{{"added", added}, {"removed", removed}, {"moved", moved}} {
+		if len(c.names) > 0 {
+			sort.Strings(c.names)
+			changes = append(changes, fmt.Sprintf("roots %s: %s", c.verb, strings.Join(c.names, ", ")))
+		}
+	}
+
+	if !reflect.DeepEqual(old.Search, next.Search) {
+		changes = append(changes, "search settings changed")
+	}
+	if old.Index != next.Index {
+		changes = append(changes, "index settings changed")
+	}
+	if !reflect.DeepEqual(old.Tools, next.Tools) {
+		changes = append(changes, fmt.Sprintf("enabled tools: %s", toolList(next.Tools.Enabled)))
+	}
+	if !reflect.DeepEqual(old.Timeouts, next.Timeouts) {
+		changes = append(changes, "timeouts changed")
+	}
+	return changes
+}
+
+// runtimeRoots returns the roots the running server has beyond those in
+// cfg, added through the admin API, and the names of the configured roots
+// it no longer has, removed through it
+func runtimeRoots(cfg *config.Config, files *filesearch.Handler) (added []filesearch.Root, removed []string) {
+	if files == nil {
+		return nil, nil
+	}
+	registry := files.Engine().Registry()
+	for _, root := range registry.List() {
+		if !hasRoot(cfg.Roots, root.Name) {
+			added = append(added, root)
+		}
+	}
+	for _, root := range cfg.Roots {
+		if _, ok := registry.Get(root.Name); !ok {
+			removed = append(removed, root.Name)
+		}
+	}
+	return added, removed
+}
+
+// hasRoot reports whether roots contains a root with the given name
+func hasRoot(roots []config.RootConfig, name string) bool {
+	for _, root := range roots {
+		if root.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// toolList formats enabled tool patterns for logging
+func toolList(patterns []string) string {
+	if len(patterns) == 0 {
+		return "all"
+	}
+	return strings.Join(patterns, ", ")
+}
+
+// restartRequired lists the changed settings that are only read at startup
+func restartRequired(old, next *config.Config) []string {
+	var sections []string
+	if !reflect.DeepEqual(old.HTTP, next.HTTP) {
+		sections = append(sections, "http")
+	}
+	if old.Auth.CredentialsFile != next.Auth.CredentialsFile || !reflect.DeepEqual(old.Auth.OAuth, next.Auth.OAuth) {
+		sections = append(sections, "auth")
+	}
+	if !reflect.DeepEqual(old.RateLimit, next.RateLimit) {
+		sections = append(sections, "rate_limit")
+	}
+	if old.Logging != next.Logging {
+		sections = append(sections, "logging")
+	}
+	if old.Audit != next.Audit {
+		sections = append(sections, "audit")
+	}
+	if old.Tracing != next.Tracing {
+		sections = append(sections, "tracing")
+	}
+	if old.Watch != next.Watch {
+		sections = append(sections, "watch")
+	}
+	return sections
+}
+
+// Current returns the configuration the server is running with
+func (rl *Reloader) Current() *config.Config {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.current
+}
+
+// CheckConfig is a readiness check that warns when the latest reload was
+// rejected. The server keeps serving with the previous configuration, so it
+// stays ready. Errors are not included as they may reveal file paths.
+func (rl *Reloader) CheckConfig(ctx context.Context) server.CheckResult {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.lastErr != nil {
+		return server.CheckResult{
+			Status:  server.CheckWarn,
+			Message: "latest reload was rejected, running the previous configuration",
+			Details: map[string]interface{}{"rejectedAt": rl.lastReload},
+		}
+	}
+	result := server.CheckResult{Status: server.CheckPass}
+	if !rl.lastReload.IsZero() {
+		result.Details = map[string]interface{}{"reloadedAt": rl.lastReload}
+	}
+	return result
+}
+
+// AddReadinessChecks registers the readiness checks that depend on the
+// configuration: the configuration itself and the age of the index
+func (rl *Reloader) AddReadinessChecks(httpServer *server.HTTPMCPServer) {
+	httpServer.AddReadinessCheck("config", rl.CheckConfig)
+	httpServer.AddReadinessCheck("index", func(ctx context.Context) server.CheckResult {
+		return rl.mcpServer.CheckIndex(rl.Current().Health.IndexMaxAge)
+	})
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/config"
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/server"
+)
+
+// writeConfig writes a configuration file with the named roots, each a
+// directory under dir holding one file, and an index in dir
+func writeConfig(t *testing.T, path, dir string, roots []string) {
+	t.Helper()
+	var b strings.Builder
+	fmt.Fprintf(&b, "[index]\nenabled = true\npath = %q\n", filepath.Join(dir, "index.json"))
+	for _, name := range roots {
+		root := filepath.Join(dir, name)
+		if err := os.MkdirAll(root, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, "a.md"), []byte("# "+name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&b, "[[roots]]\nname = %q\npath = %q\n", name, root)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// indexedRoots returns the names of the roots in the server's index
+func indexedRoots(t *testing.T, mcpServer *server.MCPServer) []string {
+	t.Helper()
+	stats, err := mcpServer.IndexStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range stats {
+		names = append(names, s.Root)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestFailedReloadKeepsIndex(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	writeConfig(t, path, dir, []string{"a", "b"})
+	args := []string{"-config", path}
+
+	cfg, _, err := config.Load("test", args, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mcpServer, err := NewMCPServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mcpServer.Reindex(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+	reloader := NewReloader("test", args, cfg, mcpServer)
+
+	// A root added at runtime whose directory has gone cannot be kept, so
+	// a reload dropping root b fails and changes nothing
+	extra := filepath.Join(dir, "extra")
+	if err := os.Mkdir(extra, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mcpServer.AddRoot(filesearch.Root{Name: "extra", Path: extra}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(extra); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, path, dir, []string{"a"})
+	if _, err := reloader.Reload(); err == nil {
+		t.Fatal("reload keeping a missing runtime root succeeded")
+	}
+	if roots := indexedRoots(t, mcpServer); strings.Join(roots, ",") != "a,b" {
+		t.Errorf("after a failed reload the index holds %v, want [a b]", roots)
+	}
+	if err := mcpServer.RemoveRoot("extra"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once the reload succeeds, root b leaves the index
+	if _, err := reloader.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if roots := indexedRoots(t, mcpServer); strings.Join(roots, ",") != "a" {
+		t.Errorf("after the reload the index holds %v, want [a]", roots)
+	}
+}
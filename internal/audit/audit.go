@@ -0,0 +1,250 @@
+// Package audit records file accesses and tool invocations in an
+// append-only log of JSON lines. The log is rotated when it reaches a
+// configured size, and entries can be hash-chained so that removing or
+// editing an entry is detectable.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcomes of an audited operation
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+	OutcomeDenied  = "denied"
+)
+
+// DefaultMaxSize is the size in bytes at which the log is rotated
+const DefaultMaxSize = 100 << 20
+
+// DefaultMaxFiles is the number of rotated files kept
+const DefaultMaxFiles = 10
+
+// rotatedTimeFormat suffixes rotated files so that they sort by age
+const rotatedTimeFormat = "20060102T150405.000000000Z"
+
+// Entry is a single audited operation
+type Entry struct {
+	Time      time.Time              `json:"time"`
+	Principal string                 `json:"principal,omitempty"`
+	Session   string                 `json:"session,omitempty"`
+	RequestID string                 `json:"requestId,omitempty"`
+	Method    string                 `json:"method"`
+	Tool      string                 `json:"tool,omitempty"`
+	Resource  string                 `json:"resource,omitempty"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Paths     []string               `json:"paths,omitempty"`
+	Bytes     int64                  `json:"bytes"`
+	Outcome   string                 `json:"outcome"`
+	Error     string                 `json:"error,omitempty"`
+
+	// PrevHash and Hash chain entries together when hash chaining is on
+	PrevHash string `json:"prevHash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// hash returns the chain hash of the entry: the SHA-256 of its JSON
+// encoding without the Hash field
+func (e Entry) hash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Options configure a Logger
+type Options struct {
+	// Path is the file entries are appended to
+	Path string
+	// MaxSize is the size in bytes at which the file is rotated; zero
+	// never rotates
+	MaxSize int64
+	// MaxFiles is the number of rotated files kept; zero keeps them all
+	MaxFiles int
+	// HashChain links each entry to the previous one
+	HashChain bool
+}
+
+// Logger appends entries to an audit log. It is safe for concurrent use.
+type Logger struct {
+	opts Options
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	lastHash string
+}
+
+// Open opens the audit log at opts.Path for appending, creating it if
+// necessary. With hash chaining the chain continues from the last entry
+// already in the log.
+func Open(opts Options) (*Logger, error) {
+	l := &Logger{opts: opts}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	if opts.HashChain {
+		last, err := lastEntry(opts.Path)
+		if err != nil {
+			l.file.Close()
+			return nil, err
+		}
+		l.lastHash = last.Hash
+	}
+
+	return l, nil
+}
+
+// open opens the current log file and records its size
+func (l *Logger) open() error {
+	file, err := os.OpenFile(l.opts.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	l.file, l.size = file, info.Size()
+	return nil
+}
+
+// Write appends an entry to the log, rotating the file first if the entry
+// would take it past the maximum size
+func (l *Logger) Write(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return fmt.Errorf("audit log is closed")
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+
+	if l.opts.HashChain {
+		e.PrevHash = l.lastHash
+		hash, err := e.hash()
+		if err != nil {
+			return fmt.Errorf("writing audit log: %w", err)
+		}
+		e.Hash = hash
+	} else {
+		e.PrevHash, e.Hash = "", ""
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	data = append(data, '\n')
+
+	if l.opts.MaxSize > 0 && l.size > 0 && l.size+int64(len(data)) > l.opts.MaxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	// A single write keeps each line intact even if another process
+	// appends to the same file
+	n, err := l.file.Write(data)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+
+	l.lastHash = e.Hash
+	return nil
+}
+
+// rotate renames the current file with a timestamp suffix, starts a new one
+// and removes the oldest rotated files beyond the configured count
+func (l *Logger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("rotating audit log: %w", err)
+	}
+	l.file = nil
+
+	rotated := l.opts.Path + "." + time.Now().UTC().Format(rotatedTimeFormat)
+	if err := os.Rename(l.opts.Path, rotated); err != nil {
+		return fmt.Errorf("rotating audit log: %w", err)
+	}
+	if err := l.open(); err != nil {
+		return err
+	}
+
+	if l.opts.MaxFiles > 0 {
+		old, err := rotatedFiles(l.opts.Path)
+		if err != nil {
+			return fmt.Errorf("rotating audit log: %w", err)
+		}
+		for len(old) > l.opts.MaxFiles {
+			if err := os.Remove(old[0]); err != nil {
+				return fmt.Errorf("rotating audit log: %w", err)
+			}
+			old = old[1:]
+		}
+	}
+
+	return nil
+}
+
+// Close closes the log file
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// rotatedFiles returns the rotated files of the log at path, oldest first
+func rotatedFiles(path string) ([]string, error) {
+	matches, err := filepath.Glob(globEscape(path) + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, match := range matches {
+		suffix := strings.TrimPrefix(match, path+".")
+		if _, err := time.Parse(rotatedTimeFormat, suffix); err == nil {
+			files = append(files, match)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// globEscape quotes the glob metacharacters in a path
+func globEscape(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,180 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/glob"
+)
+
+// Files returns the files of the log at path in the order they were
+// written: rotated files oldest first, then the current file if it exists
+func Files(path string) ([]string, error) {
+	files, err := rotatedFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Location identifies an entry within the log files
+type Location struct {
+	File string
+	Line int
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d", l.File, l.Line)
+}
+
+// Read calls fn for every entry of the log at path, oldest first. It stops
+// at the first error returned by fn.
+func Read(path string, fn func(Location, Entry) error) error {
+	files, err := Files(path)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := readFile(file, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readFile calls fn for every entry of a single log file
+func readFile(file string, fn func(Location, Entry) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		data, err := r.ReadBytes('\n')
+		if len(data) > 0 && strings.TrimSpace(string(data)) != "" {
+			var e Entry
+			if jsonErr := json.Unmarshal(data, &e); jsonErr != nil {
+				return fmt.Errorf("%s:%d: %w", file, line, jsonErr)
+			}
+			if fnErr := fn(Location{File: file, Line: line}, e); fnErr != nil {
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// lastEntry returns the most recent entry of the log at path, or a zero
+// entry if the log is empty
+func lastEntry(path string) (Entry, error) {
+	var last Entry
+	err := Read(path, func(_ Location, e Entry) error {
+		last = e
+		return nil
+	})
+	return last, err
+}
+
+// Verify checks the hash chain of the log at path and returns the number of
+// entries checked. The first entry is trusted as the start of the chain, since
+// older entries may have been rotated away.
+func Verify(path string) (int, error) {
+	count := 0
+	prev := ""
+	err := Read(path, func(loc Location, e Entry) error {
+		if e.Hash == "" {
+			return fmt.Errorf("%s: entry is not hash-chained", loc)
+		}
+		want, err := e.hash()
+		if err != nil {
+			return fmt.Errorf("%s: %w", loc, err)
+		}
+		if e.Hash != want {
+			return fmt.Errorf("%s: entry hash does not match its contents", loc)
+		}
+		if count > 0 && e.PrevHash != prev {
+			return fmt.Errorf("%s: chain broken, previous entry is missing or was altered", loc)
+		}
+		prev = e.Hash
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// Query selects entries. Zero fields match everything.
+type Query struct {
+	Principal string
+	Session   string
+	// Tool matches the tool name, or the method of entries without one
+	Tool    string
+	Outcome string
+	// Path is a glob matched against each resource URI read or returned.
+	// A pattern without a "/" is matched against the base name.
+	Path  string
+	Since time.Time
+	Until time.Time
+}
+
+// Match reports whether e satisfies the query
+func (q Query) Match(e Entry) bool {
+	if q.Principal != "" && e.Principal != q.Principal {
+		return false
+	}
+	if q.Session != "" && e.Session != q.Session {
+		return false
+	}
+	if q.Tool != "" && e.Tool != q.Tool && !(e.Tool == "" && e.Method == q.Tool) {
+		return false
+	}
+	if q.Outcome != "" && e.Outcome != q.Outcome {
+		return false
+	}
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.Time.Before(q.Until) {
+		return false
+	}
+	if q.Path != "" && !q.matchesPath(e) {
+		return false
+	}
+	return true
+}
+
+// matchesPath reports whether any path of e matches the query's path glob
+func (q Query) matchesPath(e Entry) bool {
+	paths := e.Paths
+	if e.Resource != "" {
+		paths = append([]string{e.Resource}, paths...)
+	}
+	baseOnly := !strings.Contains(q.Path, "/")
+	for _, p := range paths {
+		if baseOnly {
+			p = path.Base(p)
+		}
+		if glob.Match(q.Path, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Authentication methods recorded on a Principal
+const (
+	MethodAPIKey = "api-key"
+	MethodBearer = "bearer"
+)
+
+// APIKeyHeader is the header carrying a static API key
+const APIKeyHeader = "X-API-Key"
+
+// hashPrefix identifies the hash algorithm used for stored credentials
+const hashPrefix = "sha256:"
+
+// Authentication errors
+var (
+	// ErrNoCredentials is returned when the request carries no credentials
+	ErrNoCredentials = errors.New("no credentials provided")
+	// ErrInvalidCredentials is returned when presented credentials are not accepted
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrInsufficientScope is returned when a valid token lacks a required scope
+	ErrInsufficientScope = errors.New("insufficient scope")
+)
+
+// Authenticator identifies the caller of an HTTP request
+type Authenticator interface {
+	// Authenticate returns the principal for r, ErrNoCredentials if r carries
+	// no credentials this authenticator understands, or another error if the
+	// credentials are rejected.
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// HashKey returns the stored form of a secret key or token
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+// Credential is a configured static API key or bearer token, stored hashed
+type Credential struct {
+	Name   string   `json:"name"`
+	Hash   string   `json:"hash"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// StaticAuthenticator accepts a fixed set of hashed API keys and bearer tokens
+type StaticAuthenticator struct {
+	apiKeys []Credential
+	tokens  []Credential
+}
+
+// NewStaticAuthenticator creates an authenticator for the given hashed API
+// keys and bearer tokens
+func NewStaticAuthenticator(apiKeys, tokens []Credential) (*StaticAuthenticator, error) {
+	for _, c := range append(append([]Credential{}, apiKeys...), tokens...) {
+		if c.Name == "" {
+			return nil, fmt.Errorf("credential name required")
+		}
+		if !strings.HasPrefix(c.Hash, hashPrefix) {
+			return nil, fmt.Errorf("credential %s: hash must start with %q", c.Name, hashPrefix)
+		}
+	}
+
+	return &StaticAuthenticator{apiKeys: apiKeys, tokens: tokens}, nil
+}
+
+// StaticCredentials is the on-disk format of a static credentials file
+type StaticCredentials struct {
+	APIKeys []Credential `json:"apiKeys"`
+	Tokens  []Credential `json:"tokens"`
+}
+
+// LoadStaticAuthenticator reads hashed API keys and bearer tokens from a JSON file
+func LoadStaticAuthenticator(path string) (*StaticAuthenticator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	var creds StaticCredentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials %s: %w", path, err)
+	}
+
+	return NewStaticAuthenticator(creds.APIKeys, creds.Tokens)
+}
+
+// Authenticate implements Authenticator
+func (a *StaticAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return match(a.apiKeys, key, MethodAPIKey)
+	}
+
+	if token, ok := BearerToken(r); ok {
+		return match(a.tokens, token, MethodBearer)
+	}
+
+	return nil, ErrNoCredentials
+}
+
+// match finds the credential whose hash matches secret
+func match(creds []Credential, secret, method string) (*Principal, error) {
+	hash := []byte(HashKey(secret))
+
+	var found *Credential
+	for i := range creds {
+		// Compare every entry so timing does not reveal which one matched
+		if subtle.ConstantTimeCompare(hash, []byte(creds[i].Hash)) == 1 {
+			found = &creds[i]
+		}
+	}
+
+	if found == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Principal{
+		Name:   found.Name,
+		Method: method,
+		Scopes: found.Scopes,
+	}, nil
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer" header
+func BearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// Chain tries each authenticator in order until one accepts the request. If
+// none does, the first rejection is reported, or ErrNoCredentials when no
+// authenticator found credentials it understands.
+type Chain []Authenticator
+
+// Authenticate implements Authenticator
+func (c Chain) Authenticate(r *http.Request) (*Principal, error) {
+	rejection := ErrNoCredentials
+	for _, a := range c {
+		p, err := a.Authenticate(r)
+		if err == nil {
+			return p, nil
+		}
+		if errors.Is(rejection, ErrNoCredentials) && !errors.Is(err, ErrNoCredentials) {
+			rejection = err
+		}
+	}
+	return nil, rejection
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestKeySetRefreshesUnknownKeysOncePerInterval(t *testing.T) {
+	signers := newTestSigners(t)
+	var fetches atomic.Int32
+	var failing atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if failing.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(jwkSet{Keys: []jwk{signers[0].jwk()}})
+	}))
+	defer srv.Close()
+
+	ks, err := LoadKeySet(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Key(signers[0].kid); err != nil {
+		t.Fatalf("Key(%q): %v", signers[0].kid, err)
+	}
+
+	// Just loaded, so an unknown key does not trigger a fetch
+	if _, err := ks.Key("unknown"); err == nil {
+		t.Fatal("Key(unknown) succeeded")
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("fetches = %d, want 1", n)
+	}
+
+	// Once the interval has passed, a failed fetch still counts as an attempt
+	failing.Store(true)
+	ks.mu.Lock()
+	ks.lastAttempt = ks.lastAttempt.Add(-2 * jwksRefreshInterval)
+	ks.mu.Unlock()
+	for i := 0; i < 3; i++ {
+		if _, err := ks.Key("unknown"); err == nil {
+			t.Fatal("Key(unknown) succeeded")
+		}
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("fetches = %d, want 2", n)
+	}
+
+	// The keys from the last successful fetch are still used
+	if _, err := ks.Key(signers[0].kid); err != nil {
+		t.Errorf("Key(%q) after a failed refresh: %v", signers[0].kid, err)
+	}
+}
+
+func TestKeySetRejectsEncryptionKeys(t *testing.T) {
+	signers := newTestSigners(t)
+	key := signers[1].jwk()
+	key.Use = "enc"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jwkSet{Keys: []jwk{key}})
+	}))
+	defer srv.Close()
+
+	ks, err := LoadKeySet(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Key(key.Kid); err == nil {
+		t.Errorf("Key(%q) returned an encryption key", key.Kid)
+	}
+}
+
+func TestKeySetPicksUpRotatedKeys(t *testing.T) {
+	signers := newTestSigners(t)
+	var fetches atomic.Int32
+	var rotated atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		keys := []jwk{signers[0].jwk()}
+		if rotated.Load() {
+			keys = []jwk{signers[1].jwk()}
+		}
+		json.NewEncoder(w).Encode(jwkSet{Keys: keys})
+	}))
+	defer srv.Close()
+
+	ks, err := LoadKeySet(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Within the interval a new key ID is not fetched, however often asked
+	rotated.Store(true)
+	for i := 0; i < 3; i++ {
+		if _, err := ks.Key(signers[1].kid); err == nil {
+			t.Fatalf("Key(%q) found before the set was refetched", signers[1].kid)
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("fetches within the interval = %d, want 1", n)
+	}
+
+	// After it, the first unknown key ID refetches the set
+	ks.mu.Lock()
+	ks.lastAttempt = ks.lastAttempt.Add(-2 * jwksRefreshInterval)
+	ks.mu.Unlock()
+	if _, err := ks.Key(signers[1].kid); err != nil {
+		t.Fatalf("Key(%q) after rotation: %v", signers[1].kid, err)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("fetches = %d, want 2", n)
+	}
+	if _, err := ks.Key(signers[0].kid); err == nil {
+		t.Errorf("Key(%q) still found after it was rotated out", signers[0].kid)
+	}
+}
@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MethodOAuth is recorded on principals identified by a JWT access token
+const MethodOAuth = "oauth"
+
+// Supported JWS signature algorithms
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// defaultClockSkew is the tolerance applied to exp and nbf checks
+const defaultClockSkew = 30 * time.Second
+
+// JWTConfig describes which access tokens a JWTAuthenticator accepts
+type JWTConfig struct {
+	Issuer         string
+	Audience       string
+	RequiredScopes []string
+	ScopeMap       ScopeMap
+	ClockSkew      time.Duration
+}
+
+// JWTAuthenticator validates JWT bearer access tokens locally against a JWKS
+type JWTAuthenticator struct {
+	config JWTConfig
+	keys   *KeySet
+	now    func() time.Time
+}
+
+// NewJWTAuthenticator creates an authenticator that verifies tokens signed by
+// keys in the given key set
+func NewJWTAuthenticator(config JWTConfig, keys *KeySet) (*JWTAuthenticator, error) {
+	if config.Issuer == "" {
+		return nil, fmt.Errorf("issuer is required for JWT validation")
+	}
+	if config.Audience == "" {
+		return nil, fmt.Errorf("audience is required for JWT validation")
+	}
+	if config.ClockSkew == 0 {
+		config.ClockSkew = defaultClockSkew
+	}
+
+	return &JWTAuthenticator{
+		config: config,
+		keys:   keys,
+		now:    time.Now,
+	}, nil
+}
+
+// jwtHeader is the JOSE header of a token
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// jwtClaims holds the registered and scope claims checked by the server
+type jwtClaims struct {
+	Issuer    string          `json:"iss"`
+	Subject   string          `json:"sub"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf,omitempty"`
+	Scope     string          `json:"scope,omitempty"`
+	Scp       []string        `json:"scp,omitempty"`
+}
+
+// audiences returns the aud claim, which may be a string or an array
+func (c jwtClaims) audiences() []string {
+	var single string
+	if err := json.Unmarshal(c.Audience, &single); err == nil {
+		return []string{single}
+	}
+	var many []string
+	json.Unmarshal(c.Audience, &many)
+	return many
+}
+
+// scopes returns the granted scopes from either the scope or scp claim
+func (c jwtClaims) scopes() []string {
+	if c.Scope != "" {
+		return strings.Fields(c.Scope)
+	}
+	return c.Scp
+}
+
+// Authenticate implements Authenticator
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token, ok := BearerToken(r)
+	if !ok || strings.Count(token, ".") != 2 {
+		// Not a JWT; leave it for other authenticators
+		return nil, ErrNoCredentials
+	}
+
+	claims, err := a.verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	scopes := claims.scopes()
+	for _, required := range a.config.RequiredScopes {
+		if !contains(scopes, required) {
+			return nil, fmt.Errorf("%w: missing scope %s", ErrInsufficientScope, required)
+		}
+	}
+
+	return &Principal{
+		Name:        claims.Subject,
+		Method:      MethodOAuth,
+		Scopes:      scopes,
+		Permissions: a.config.ScopeMap.Permissions(scopes),
+	}, nil
+}
+
+// verify checks the token signature and registered claims
+func (a *JWTAuthenticator) verify(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed header")
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, fmt.Errorf("malformed header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature")
+	}
+
+	key, err := a.keys.Key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signingInput := []byte(parts[0] + "." + parts[1])
+	if err := verifySignature(header.Alg, key, signingInput, signature); err != nil {
+		return nil, err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed payload")
+	}
+	var claims jwtClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("malformed payload")
+	}
+
+	if claims.Issuer != a.config.Issuer {
+		return nil, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if !contains(claims.audiences(), a.config.Audience) {
+		return nil, fmt.Errorf("token not issued for this resource")
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("missing subject")
+	}
+
+	now := a.now()
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("missing expiry")
+	}
+	if now.After(time.Unix(*claims.ExpiresAt, 0).Add(a.config.ClockSkew)) {
+		return nil, fmt.Errorf("token expired")
+	}
+	if claims.NotBefore != nil && now.Add(a.config.ClockSkew).Before(time.Unix(*claims.NotBefore, 0)) {
+		return nil, fmt.Errorf("token not yet valid")
+	}
+
+	return &claims, nil
+}
+
+// verifySignature checks a JWS signature for the given algorithm. The key
+// type must match the algorithm so that a token cannot pick a weaker check.
+func verifySignature(alg string, key crypto.PublicKey, input, signature []byte) error {
+	switch alg {
+	case AlgRS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key type does not match algorithm %s", alg)
+		}
+		digest := sha256.Sum256(input)
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("invalid signature")
+		}
+	case AlgES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key type does not match algorithm %s", alg)
+		}
+		if len(signature) != 64 {
+			return fmt.Errorf("invalid signature")
+		}
+		digest := sha256.Sum256(input)
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+	case AlgEdDSA:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("key type does not match algorithm %s", alg)
+		}
+		if !ed25519.Verify(pub, input, signature) {
+			return fmt.Errorf("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	return nil
+}
+
+// contains reports whether values includes s
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
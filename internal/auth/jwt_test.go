@@ -0,0 +1,314 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://sso.example.com"
+	testAudience = "https://mcp.example.com/mcp"
+)
+
+// testNow is the time at which the test authenticators check tokens
+var testNow = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// testSigner signs tokens with a locally generated key
+type testSigner struct {
+	alg string
+	kid string
+	key crypto.Signer
+}
+
+// newTestSigners generates one key pair for each supported algorithm
+func newTestSigners(t *testing.T) []testSigner {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []testSigner{
+		{alg: AlgRS256, kid: "rsa", key: rsaKey},
+		{alg: AlgES256, kid: "ec", key: ecKey},
+		{alg: AlgEdDSA, kid: "ed", key: edKey},
+	}
+}
+
+// jwk returns the public half of the signer's key as a JWK
+func (s testSigner) jwk() jwk {
+	enc := base64.RawURLEncoding.EncodeToString
+	switch pub := s.key.Public().(type) {
+	case *rsa.PublicKey:
+		return jwk{Kty: "RSA", Kid: s.kid, Use: "sig", N: enc(pub.N.Bytes()), E: enc(big.NewInt(int64(pub.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		return jwk{Kty: "EC", Kid: s.kid, Crv: "P-256", X: enc(pub.X.FillBytes(make([]byte, 32))), Y: enc(pub.Y.FillBytes(make([]byte, 32)))}
+	case ed25519.PublicKey:
+		return jwk{Kty: "OKP", Kid: s.kid, Crv: "Ed25519", X: enc(pub)}
+	}
+	panic("unsupported key type")
+}
+
+// sign returns a compact JWS of the given claims
+func (s testSigner) sign(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	header, err := json.Marshal(jwtHeader{Alg: s.alg, Kid: s.kid, Typ: "JWT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	switch key := s.key.(type) {
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(input))
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256([]byte(input))
+		var r, sv *big.Int
+		r, sv, err = ecdsa.Sign(rand.Reader, key, digest[:])
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, 32)), sv.FillBytes(make([]byte, 32))...)
+		}
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, []byte(input))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// writeKeySet writes the public keys of the signers to a JWKS file
+func writeKeySet(t *testing.T, signers []testSigner) string {
+	t.Helper()
+	var set jwkSet
+	for _, s := range signers {
+		set.Keys = append(set.Keys, s.jwk())
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newTestAuthenticator returns an authenticator trusting the signers' keys
+func newTestAuthenticator(t *testing.T, signers []testSigner, config JWTConfig) *JWTAuthenticator {
+	t.Helper()
+	keys, err := LoadKeySet(writeKeySet(t, signers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Issuer, config.Audience = testIssuer, testAudience
+	a, err := NewJWTAuthenticator(config, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.now = func() time.Time { return testNow }
+	return a
+}
+
+// validClaims returns claims that the test authenticators accept
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":   testIssuer,
+		"sub":   "alice",
+		"aud":   testAudience,
+		"exp":   testNow.Add(time.Hour).Unix(),
+		"scope": "files:read tools:echo",
+	}
+}
+
+// bearerRequest returns a request carrying the token
+func bearerRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestJWTAuthenticatorAlgorithms(t *testing.T) {
+	signers := newTestSigners(t)
+	a := newTestAuthenticator(t, signers, JWTConfig{})
+
+	for _, s := range signers {
+		t.Run(s.alg, func(t *testing.T) {
+			p, err := a.Authenticate(bearerRequest(s.sign(t, validClaims())))
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if p.Name != "alice" || p.Method != MethodOAuth {
+				t.Errorf("principal = %s (%s), want alice (%s)", p.Name, p.Method, MethodOAuth)
+			}
+			if len(p.Scopes) != 2 {
+				t.Errorf("scopes = %v, want files:read and tools:echo", p.Scopes)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticatorRejects(t *testing.T) {
+	signers := newTestSigners(t)
+	a := newTestAuthenticator(t, signers, JWTConfig{})
+	// Signs with a key the set does not hold, under a key ID it does
+	impostor := testSigner{alg: AlgRS256, kid: signers[0].kid, key: newTestSigners(t)[0].key}
+
+	tests := []struct {
+		name   string
+		signer testSigner
+		change func(claims map[string]interface{})
+	}{
+		{"expired", signers[0], func(c map[string]interface{}) { c["exp"] = testNow.Add(-time.Minute).Unix() }},
+		{"expired past skew", signers[0], func(c map[string]interface{}) {
+			c["exp"] = testNow.Add(-defaultClockSkew - time.Second).Unix()
+		}},
+		{"no expiry", signers[1], func(c map[string]interface{}) { delete(c, "exp") }},
+		{"not yet valid", signers[1], func(c map[string]interface{}) { c["nbf"] = testNow.Add(time.Minute).Unix() }},
+		{"wrong audience", signers[2], func(c map[string]interface{}) { c["aud"] = "https://other.example.com" }},
+		{"wrong audience list", signers[2], func(c map[string]interface{}) { c["aud"] = []string{"a", "b"} }},
+		{"wrong issuer", signers[0], func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" }},
+		{"no subject", signers[0], func(c map[string]interface{}) { delete(c, "sub") }},
+		{"empty subject", signers[0], func(c map[string]interface{}) { c["sub"] = "" }},
+		{"no issuer", signers[1], func(c map[string]interface{}) { delete(c, "iss") }},
+		{"no audience", signers[2], func(c map[string]interface{}) { delete(c, "aud") }},
+		{"empty audience list", signers[2], func(c map[string]interface{}) { c["aud"] = []string{} }},
+		{"wrong key", impostor, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			if tt.change != nil {
+				tt.change(claims)
+			}
+			_, err := a.Authenticate(bearerRequest(tt.signer.sign(t, claims)))
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("Authenticate error = %v, want %v", err, ErrInvalidCredentials)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticatorClaimWindows(t *testing.T) {
+	signers := newTestSigners(t)
+	a := newTestAuthenticator(t, signers, JWTConfig{})
+
+	claims := validClaims()
+	claims["exp"] = testNow.Add(-defaultClockSkew + time.Second).Unix()
+	claims["nbf"] = testNow.Add(defaultClockSkew - time.Second).Unix()
+	claims["aud"] = []string{"https://other.example.com", testAudience}
+	if _, err := a.Authenticate(bearerRequest(signers[0].sign(t, claims))); err != nil {
+		t.Errorf("token within clock skew and listing the audience rejected: %v", err)
+	}
+}
+
+func TestJWTAuthenticatorAlgorithmMustMatchKey(t *testing.T) {
+	signers := newTestSigners(t)
+	a := newTestAuthenticator(t, signers, JWTConfig{})
+	rsaKey, ecKey, edKey := signers[0], signers[1], signers[2]
+	header := func(alg, kid string) string {
+		data, err := json.Marshal(jwtHeader{Alg: alg, Kid: kid, Typ: "JWT"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	payload, err := json.Marshal(validClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := base64.RawURLEncoding.EncodeToString(payload)
+
+	// An HMAC over the token keyed with the RSA public key, as a verifier
+	// that trusts the header's algorithm would check it
+	hsInput := header("HS256", rsaKey.kid) + "." + claims
+	mac := hmac.New(sha256.New, x509.MarshalPKCS1PublicKey(rsaKey.key.Public().(*rsa.PublicKey)))
+	mac.Write([]byte(hsInput))
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"Ed25519 signature as RS256", testSigner{alg: AlgRS256, kid: rsaKey.kid, key: edKey.key}.sign(t, validClaims())},
+		{"ES256 under an RSA key", testSigner{alg: AlgES256, kid: rsaKey.kid, key: ecKey.key}.sign(t, validClaims())},
+		{"RS256 under an EC key", testSigner{alg: AlgRS256, kid: ecKey.kid, key: rsaKey.key}.sign(t, validClaims())},
+		{"EdDSA under an EC key", testSigner{alg: AlgEdDSA, kid: ecKey.kid, key: edKey.key}.sign(t, validClaims())},
+		{"ES256 under an Ed25519 key", testSigner{alg: AlgES256, kid: edKey.kid, key: ecKey.key}.sign(t, validClaims())},
+		{"HS256 keyed with the public key", hsInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))},
+		{"unsigned", header("none", rsaKey.kid) + "." + claims + "."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := a.Authenticate(bearerRequest(tt.token)); !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("Authenticate error = %v, want %v", err, ErrInvalidCredentials)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticatorScopes(t *testing.T) {
+	signers := newTestSigners(t)
+
+	required := newTestAuthenticator(t, signers, JWTConfig{RequiredScopes: []string{"mcp"}})
+	if _, err := required.Authenticate(bearerRequest(signers[0].sign(t, validClaims()))); !errors.Is(err, ErrInsufficientScope) {
+		t.Errorf("missing required scope: error = %v, want %v", err, ErrInsufficientScope)
+	}
+
+	unmapped := newTestAuthenticator(t, signers, JWTConfig{})
+	p, err := unmapped.Authenticate(bearerRequest(signers[0].sign(t, validClaims())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Permissions != nil {
+		t.Errorf("without a scope map, permissions = %+v, want nil", p.Permissions)
+	}
+
+	mapped := newTestAuthenticator(t, signers, JWTConfig{ScopeMap: ScopeMap{
+		"tools:echo": {Tools: []string{"echo"}},
+	}})
+	p, err = mapped.Authenticate(bearerRequest(signers[0].sign(t, validClaims())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Permissions == nil || !p.Permissions.AllowsTool("echo") || p.Permissions.AllowsTool("grep_files") {
+		t.Errorf("with a scope map, permissions = %+v, want only echo", p.Permissions)
+	}
+}
+
+func TestJWTAuthenticatorIgnoresOtherTokens(t *testing.T) {
+	a := newTestAuthenticator(t, newTestSigners(t), JWTConfig{})
+	if _, err := a.Authenticate(bearerRequest("opaque-api-key")); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Authenticate error = %v, want %v", err, ErrNoCredentials)
+	}
+}
@@ -0,0 +1,27 @@
+package auth
+
+import "sort"
+
+// ProtectedResourceMetadataPath is the well-known path of the OAuth 2.0
+// protected resource metadata document (RFC 9728)
+const ProtectedResourceMetadataPath = "/.well-known/oauth-protected-resource"
+
+// ProtectedResourceMetadata describes this server as an OAuth protected
+// resource so that clients can discover its authorization servers
+type ProtectedResourceMetadata struct {
+	Resource               string   `json:"resource"`
+	AuthorizationServers   []string `json:"authorization_servers"`
+	ScopesSupported        []string `json:"scopes_supported,omitempty"`
+	BearerMethodsSupported []string `json:"bearer_methods_supported"`
+	ResourceName           string   `json:"resource_name,omitempty"`
+}
+
+// ScopesSupported returns the scopes known to the scope map
+func (m ScopeMap) ScopesSupported() []string {
+	scopes := make([]string, 0, len(m))
+	for scope := range m {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/glob"
+)
+
+// Permissions restricts which tools a principal may call and which resources
+// it may read. Entries are glob patterns over tool names and resource URIs.
+type Permissions struct {
+	Tools     []string `json:"tools,omitempty"`
+	Resources []string `json:"resources,omitempty"`
+}
+
+// AllowsTool reports whether the permissions grant calling the named tool
+func (p *Permissions) AllowsTool(name string) bool {
+	return glob.MatchAny(p.Tools, name)
+}
+
+// AllowsResource reports whether the permissions grant reading the resource URI
+func (p *Permissions) AllowsResource(uri string) bool {
+	return glob.MatchAny(p.Resources, uri)
+}
+
+// ScopeMap maps OAuth scopes to the permissions they grant
+type ScopeMap map[string]Permissions
+
+// LoadScopeMap reads a JSON object mapping scopes to permissions from a file
+func LoadScopeMap(path string) (ScopeMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scope map: %w", err)
+	}
+
+	var scopes ScopeMap
+	if err := json.Unmarshal(data, &scopes); err != nil {
+		return nil, fmt.Errorf("failed to parse scope map %s: %w", path, err)
+	}
+
+	return scopes, nil
+}
+
+// Permissions returns the union of the permissions granted by the given
+// scopes. Scopes without a mapping grant nothing. A nil map restricts
+// nothing, so Permissions returns nil.
+func (m ScopeMap) Permissions(scopes []string) *Permissions {
+	if m == nil {
+		return nil
+	}
+	perms := &Permissions{}
+	for _, scope := range scopes {
+		granted, ok := m[scope]
+		if !ok {
+			continue
+		}
+		perms.Tools = append(perms.Tools, granted.Tools...)
+		perms.Resources = append(perms.Resources, granted.Resources...)
+	}
+	return perms
+}
@@ -0,0 +1,32 @@
+package auth
+
+import "testing"
+
+func TestScopeMapPermissions(t *testing.T) {
+	var unset ScopeMap
+	if p := unset.Permissions([]string{"files:read"}); p != nil {
+		t.Errorf("nil map: permissions = %+v, want nil", p)
+	}
+
+	m := ScopeMap{
+		"files:read": {Resources: []string{"file:///srv/docs/**"}},
+		"tools:echo": {Tools: []string{"echo"}},
+	}
+	p := m.Permissions([]string{"files:read", "unmapped"})
+	if p == nil {
+		t.Fatal("permissions = nil, want restricted")
+	}
+	if !p.AllowsResource("file:///srv/docs/a/b.md") {
+		t.Error("files:read does not grant file:///srv/docs/a/b.md")
+	}
+	if p.AllowsResource("file:///etc/passwd") {
+		t.Error("files:read grants file:///etc/passwd")
+	}
+	if p.AllowsTool("echo") {
+		t.Error("echo allowed without tools:echo")
+	}
+
+	if p := m.Permissions(nil); p == nil || p.AllowsTool("echo") {
+		t.Errorf("no scopes: permissions = %+v, want nothing granted", p)
+	}
+}
@@ -0,0 +1,35 @@
+// Package auth provides caller identification for the MCP server.
+// It defines the Principal type that transports attach to a request context
+// once a caller has been identified, so that downstream authorization can
+// make decisions without knowing how the caller authenticated.
+package auth
+
+import "context"
+
+// Authentication methods recorded on a Principal
+const (
+	MethodClientCert = "client-cert"
+)
+
+// Principal represents an authenticated caller. A nil Permissions means the
+// principal is not restricted by token scopes.
+type Principal struct {
+	Name        string       `json:"name"`
+	Method      string       `json:"method"`
+	Scopes      []string     `json:"scopes,omitempty"`
+	Permissions *Permissions `json:"permissions,omitempty"`
+}
+
+// principalKey is the context key under which the Principal is stored
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the given principal
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal attached to ctx, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
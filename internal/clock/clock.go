@@ -0,0 +1,19 @@
+// Package clock abstracts reading the current time, so that tests can
+// control the times the server records and compares.
+package clock
+
+import "time"
+
+// Clock tells the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the operating system's clock
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
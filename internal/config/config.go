@@ -0,0 +1,301 @@
+// Package config loads the settings shared by the stdio and HTTP server
+// binaries. Settings come from built-in defaults, a TOML configuration file,
+// MCP_* environment variables and command-line flags, in increasing order of
+// precedence. Every setting remembers where it was defined so that
+// validation errors can point at the offending file and line.
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/audit"
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/trace"
+)
+
+// Config is the complete server configuration
+type Config struct {
+	HTTP      HTTPConfig      `toml:"http"`
+	Roots     []RootConfig    `toml:"roots"`
+	Search    SearchConfig    `toml:"search"`
+	Index     IndexConfig     `toml:"index"`
+	Watch     WatchConfig     `toml:"watch"`
+	Auth      AuthConfig      `toml:"auth"`
+	RateLimit RateLimitConfig `toml:"rate_limit"`
+	Logging   LoggingConfig   `toml:"logging"`
+	Audit     AuditConfig     `toml:"audit"`
+	Tracing   TracingConfig   `toml:"tracing"`
+	Health    HealthConfig    `toml:"health"`
+	Tools     ToolsConfig     `toml:"tools"`
+	Timeouts  TimeoutsConfig  `toml:"timeouts"`
+
+	// positions records where each setting was defined, keyed by its
+	// dotted name such as "http.port" or "roots[1].path"
+	positions map[string]Position
+}
+
+// HTTPConfig configures the HTTP transport
+type HTTPConfig struct {
+	Host              string        `toml:"host"`
+	Port              int           `toml:"port"`
+	ReadHeaderTimeout time.Duration `toml:"read_header_timeout"`
+	ReadTimeout       time.Duration `toml:"read_timeout"`
+	WriteTimeout      time.Duration `toml:"write_timeout"`
+	IdleTimeout       time.Duration `toml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `toml:"shutdown_timeout"`
+	MaxBodyBytes      int64         `toml:"max_body_bytes"`
+	SessionTimeout    time.Duration `toml:"session_idle_timeout"`
+	MaxSessions       int           `toml:"max_sessions"`
+	UI                bool          `toml:"ui"`
+	AllowedHosts      []string      `toml:"allowed_hosts"`
+	CORS              CORSConfig    `toml:"cors"`
+	TLS               TLSConfig     `toml:"tls"`
+}
+
+// CORSConfig lists the browser origins, methods and headers accepted
+type CORSConfig struct {
+	AllowedOrigins []string `toml:"allowed_origins"`
+	AllowedMethods []string `toml:"allowed_methods"`
+	AllowedHeaders []string `toml:"allowed_headers"`
+}
+
+// TLSConfig configures HTTPS and client certificate verification
+type TLSConfig struct {
+	Cert        string `toml:"cert"`
+	Key         string `toml:"key"`
+	ClientCA    string `toml:"client_ca"`
+	ClientAuth  string `toml:"client_auth"`
+	IdentityMap string `toml:"identity_map"`
+}
+
+// RootConfig names a directory to search
+type RootConfig struct {
+	Name string `toml:"name"`
+	Path string `toml:"path"`
+}
+
+// SearchConfig controls what is searched and how much is returned
+type SearchConfig struct {
+	Ignore      []string `toml:"ignore"`
+	MaxFileSize int64    `toml:"max_file_size"`
+	MaxResults  int      `toml:"max_results"`
+}
+
+// IndexConfig controls the file index. An empty path keeps the index in
+// memory only.
+type IndexConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Path    string `toml:"path"`
+}
+
+// WatchConfig controls the file watcher, which polls the roots for changed
+// files every Interval
+type WatchConfig struct {
+	Enabled  bool          `toml:"enabled"`
+	Interval time.Duration `toml:"interval"`
+}
+
+// AuthConfig configures authentication and authorization
+type AuthConfig struct {
+	CredentialsFile string      `toml:"credentials_file"`
+	PolicyFile      string      `toml:"policy_file"`
+	OAuth           OAuthConfig `toml:"oauth"`
+}
+
+// OAuthConfig configures validation of OAuth JWT access tokens
+type OAuthConfig struct {
+	Issuer         string   `toml:"issuer"`
+	Resource       string   `toml:"resource"`
+	JWKS           string   `toml:"jwks"`
+	ScopeMap       string   `toml:"scope_map"`
+	RequiredScopes []string `toml:"required_scopes"`
+}
+
+// RateLimitConfig sets per-client request budgets on the HTTP transport
+type RateLimitConfig struct {
+	Rate           float64  `toml:"rate"`
+	Burst          int      `toml:"burst"`
+	ExpensiveRate  float64  `toml:"expensive_rate"`
+	ExpensiveBurst int      `toml:"expensive_burst"`
+	ExpensiveTools []string `toml:"expensive_tools"`
+	DailyReadBytes int64    `toml:"daily_read_bytes"`
+}
+
+// Enabled reports whether any limit is configured
+func (c RateLimitConfig) Enabled() bool {
+	return c.Rate > 0 || c.ExpensiveRate > 0 || c.DailyReadBytes > 0
+}
+
+// LoggingConfig controls where and how logs are written
+type LoggingConfig struct {
+	Output string `toml:"output"`
+	Level  string `toml:"level"`
+	Format string `toml:"format"`
+}
+
+// AuditConfig controls the audit log of tool calls and resource reads. An
+// empty path disables it.
+type AuditConfig struct {
+	Path      string `toml:"path"`
+	MaxSize   int64  `toml:"max_size"`
+	MaxFiles  int    `toml:"max_files"`
+	HashChain bool   `toml:"hash_chain"`
+}
+
+// TracingConfig controls request tracing. Traces are kept in memory for
+// /debug/traces and, if a file is set, appended to it as OTLP/JSON.
+type TracingConfig struct {
+	Enabled    bool   `toml:"enabled"`
+	File       string `toml:"file"`
+	BufferSize int    `toml:"buffer_size"`
+}
+
+// HealthConfig tunes the readiness checks. An index older than
+// IndexMaxAge is reported as stale; zero does not check its age.
+type HealthConfig struct {
+	IndexMaxAge time.Duration `toml:"index_max_age"`
+}
+
+// ToolsConfig selects which tools are exposed. Entries are glob patterns;
+// an empty list enables every tool.
+type ToolsConfig struct {
+	Enabled []string `toml:"enabled"`
+}
+
+// TimeoutsConfig bounds how long requests may run. Methods and Tools hold
+// pattern=duration entries such as "tools/call=2m" or "search_*=30s"; the
+// first entry matching a method or tool name applies, and methods matching
+// none get Default. Zero means no limit.
+type TimeoutsConfig struct {
+	Default time.Duration `toml:"default"`
+	Methods []string      `toml:"methods"`
+	Tools   []string      `toml:"tools"`
+}
+
+// Timeout is a parsed Methods or Tools entry: the limit for names matching
+// Pattern
+type Timeout struct {
+	Pattern string
+	Limit   time.Duration
+}
+
+// Parse returns the method and tool limits, in the order they are listed
+func (c TimeoutsConfig) Parse() (methods, tools []Timeout, err error) {
+	if methods, err = parseTimeouts(c.Methods); err != nil {
+		return nil, nil, err
+	}
+	if tools, err = parseTimeouts(c.Tools); err != nil {
+		return nil, nil, err
+	}
+	return methods, tools, nil
+}
+
+// parseTimeouts parses pattern=duration entries
+func parseTimeouts(specs []string) ([]Timeout, error) {
+	var timeouts []Timeout
+	for _, spec := range specs {
+		pattern, raw, ok := strings.Cut(spec, "=")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("expected pattern=duration, got %q", spec)
+		}
+		limit, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pattern, err)
+		}
+		if limit < 0 {
+			return nil, fmt.Errorf("%s: duration must not be negative", pattern)
+		}
+		timeouts = append(timeouts, Timeout{Pattern: pattern, Limit: limit})
+	}
+	return timeouts, nil
+}
+
+// Defaults of the HTTP transport, matching those of the server package
+const (
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultReadTimeout       = 30 * time.Second
+	defaultWriteTimeout      = 60 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+	defaultShutdownTimeout   = 30 * time.Second
+	defaultMaxBodyBytes      = 4 << 20
+	defaultSessionTimeout    = 30 * time.Minute
+	defaultMaxSessions       = 1000
+)
+
+// defaultAllowedOrigins only admits pages served from the local machine
+var defaultAllowedOrigins = []string{
+	"http://localhost", "http://localhost:*",
+	"https://localhost", "https://localhost:*",
+	"http://127.0.0.1", "http://127.0.0.1:*",
+	"https://127.0.0.1", "https://127.0.0.1:*",
+	"http://[::1]", "http://[::1]:*",
+}
+
+// Default returns the configuration used when nothing is overridden
+func Default() *Config {
+	return &Config{
+		HTTP: HTTPConfig{
+			Host:              "127.0.0.1",
+			Port:              8080,
+			ReadHeaderTimeout: defaultReadHeaderTimeout,
+			ReadTimeout:       defaultReadTimeout,
+			WriteTimeout:      defaultWriteTimeout,
+			IdleTimeout:       defaultIdleTimeout,
+			ShutdownTimeout:   defaultShutdownTimeout,
+			MaxBodyBytes:      defaultMaxBodyBytes,
+			SessionTimeout:    defaultSessionTimeout,
+			MaxSessions:       defaultMaxSessions,
+			UI:                true,
+			CORS: CORSConfig{
+				AllowedOrigins: append([]string{}, defaultAllowedOrigins...),
+				AllowedMethods: []string{"GET", "POST", "DELETE", "OPTIONS"},
+				AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key", "Mcp-Session-Id"},
+			},
+		},
+		Search: SearchConfig{
+			Ignore:      append([]string{}, filesearch.DefaultIgnore...),
+			MaxFileSize: filesearch.DefaultMaxFileSize,
+			MaxResults:  filesearch.DefaultMaxResults,
+		},
+		Index: IndexConfig{
+			Enabled: true,
+		},
+		Watch: WatchConfig{
+			Interval: filesearch.DefaultWatchInterval,
+		},
+		RateLimit: RateLimitConfig{
+			ExpensiveTools: []string{"*search*", "*grep*", "*index*"},
+		},
+		Logging: LoggingConfig{
+			Output: "stderr",
+			Level:  "info",
+			Format: "text",
+		},
+		Audit: AuditConfig{
+			MaxSize:  audit.DefaultMaxSize,
+			MaxFiles: audit.DefaultMaxFiles,
+		},
+		Tracing: TracingConfig{
+			BufferSize: trace.DefaultBufferSize,
+		},
+		positions: map[string]Position{},
+	}
+}
+
+// Position returns where the named setting was defined. Settings inside an
+// array entry such as "roots[0].path" fall back to the entry's position.
+func (c *Config) Position(key string) Position {
+	for {
+		if pos, ok := c.positions[key]; ok {
+			return pos
+		}
+		i := strings.LastIndexByte(key, '.')
+		if i < 0 || !strings.HasSuffix(key[:i], "]") {
+			return Position{Source: "default"}
+		}
+		key = key[:i]
+	}
+}
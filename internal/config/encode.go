@@ -0,0 +1,88 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Encode writes the configuration as a TOML document that LoadFile accepts.
+// Each setting is annotated with where its value came from.
+func (c *Config) Encode(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	c.encodeTable(bw, reflect.ValueOf(c).Elem(), "")
+	return bw.Flush()
+}
+
+// encodeTable writes the leaf settings of rv followed by its sub-tables
+func (c *Config) encodeTable(w *bufio.Writer, rv reflect.Value, prefix string) {
+	t := rv.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("toml")
+		field := rv.Field(i)
+		if tag == "" || isTable(field) || isTableArray(field) {
+			continue
+		}
+		name := joinKey(prefix, tag)
+		fmt.Fprintf(w, "%s = %s  # %s\n", tag, encodeValue(field), c.Position(name))
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("toml")
+		field := rv.Field(i)
+		name := joinKey(prefix, tag)
+
+		switch {
+		case tag == "":
+		case isTable(field):
+			fmt.Fprintf(w, "\n[%s]\n", name)
+			c.encodeTable(w, field, name)
+		case isTableArray(field):
+			for j := 0; j < field.Len(); j++ {
+				itemName := fmt.Sprintf("%s[%d]", name, j)
+				fmt.Fprintf(w, "\n[[%s]]  # %s\n", name, c.Position(itemName))
+				c.encodeTable(w, field.Index(j), itemName)
+			}
+		}
+	}
+}
+
+// isTable reports whether a field is encoded as a [table]
+func isTable(field reflect.Value) bool {
+	return field.Kind() == reflect.Struct && field.Type() != durationType
+}
+
+// isTableArray reports whether a field is encoded as [[tables]]
+func isTableArray(field reflect.Value) bool {
+	return field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Struct
+}
+
+// encodeValue formats a leaf setting as a TOML value
+func encodeValue(field reflect.Value) string {
+	if field.Type() == durationType {
+		return strconv.Quote(time.Duration(field.Int()).String())
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		return strconv.Quote(field.String())
+	case reflect.Bool:
+		return strconv.FormatBool(field.Bool())
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(field.Int(), 10)
+	case reflect.Float64:
+		return strconv.FormatFloat(field.Float(), 'f', -1, 64)
+	case reflect.Slice:
+		items := make([]string, field.Len())
+		for i := range items {
+			items[i] = encodeValue(field.Index(i))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return `""`
+}
@@ -0,0 +1,501 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Position identifies where a setting was defined: a file and line, an
+// environment variable, a flag or the built-in default
+type Position struct {
+	File   string
+	Line   int
+	Source string
+}
+
+// String formats the position as file:line or as its source
+func (p Position) String() string {
+	if p.File != "" {
+		return fmt.Sprintf("%s:%d", p.File, p.Line)
+	}
+	return p.Source
+}
+
+// Error is a configuration problem tied to the position of a setting
+type Error struct {
+	Pos Position
+	Key string
+	Msg string
+}
+
+func (e *Error) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Pos, e.Key, e.Msg)
+}
+
+// Errors is a list of configuration problems
+type Errors []*Error
+
+func (e Errors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// envVars maps environment variables onto settings
+var envVars = []struct {
+	name string
+	key  string
+}{
+	{"MCP_HTTP_HOST", "http.host"},
+	{"MCP_HTTP_PORT", "http.port"},
+	{"MCP_HTTP_READ_HEADER_TIMEOUT", "http.read_header_timeout"},
+	{"MCP_HTTP_READ_TIMEOUT", "http.read_timeout"},
+	{"MCP_HTTP_WRITE_TIMEOUT", "http.write_timeout"},
+	{"MCP_HTTP_IDLE_TIMEOUT", "http.idle_timeout"},
+	{"MCP_SHUTDOWN_TIMEOUT", "http.shutdown_timeout"},
+	{"MCP_MAX_BODY_BYTES", "http.max_body_bytes"},
+	{"MCP_SESSION_IDLE_TIMEOUT", "http.session_idle_timeout"},
+	{"MCP_MAX_SESSIONS", "http.max_sessions"},
+	{"MCP_HTTP_UI", "http.ui"},
+	{"MCP_ALLOWED_HOSTS", "http.allowed_hosts"},
+	{"MCP_CORS_ORIGINS", "http.cors.allowed_origins"},
+	{"MCP_CORS_METHODS", "http.cors.allowed_methods"},
+	{"MCP_CORS_HEADERS", "http.cors.allowed_headers"},
+	{"MCP_TLS_CERT", "http.tls.cert"},
+	{"MCP_TLS_KEY", "http.tls.key"},
+	{"MCP_TLS_CLIENT_CA", "http.tls.client_ca"},
+	{"MCP_TLS_CLIENT_AUTH", "http.tls.client_auth"},
+	{"MCP_TLS_IDENTITY_MAP", "http.tls.identity_map"},
+	{"MCP_SEARCH_IGNORE", "search.ignore"},
+	{"MCP_MAX_FILE_SIZE", "search.max_file_size"},
+	{"MCP_MAX_RESULTS", "search.max_results"},
+	{"MCP_INDEX_ENABLED", "index.enabled"},
+	{"MCP_INDEX_PATH", "index.path"},
+	{"MCP_WATCH_ENABLED", "watch.enabled"},
+	{"MCP_WATCH_INTERVAL", "watch.interval"},
+	{"MCP_AUTH_FILE", "auth.credentials_file"},
+	{"MCP_POLICY_FILE", "auth.policy_file"},
+	{"MCP_OAUTH_ISSUER", "auth.oauth.issuer"},
+	{"MCP_OAUTH_RESOURCE", "auth.oauth.resource"},
+	{"MCP_OAUTH_JWKS", "auth.oauth.jwks"},
+	{"MCP_OAUTH_SCOPE_MAP", "auth.oauth.scope_map"},
+	{"MCP_OAUTH_REQUIRED_SCOPES", "auth.oauth.required_scopes"},
+	{"MCP_RATE_LIMIT", "rate_limit.rate"},
+	{"MCP_RATE_BURST", "rate_limit.burst"},
+	{"MCP_RATE_LIMIT_EXPENSIVE", "rate_limit.expensive_rate"},
+	{"MCP_RATE_BURST_EXPENSIVE", "rate_limit.expensive_burst"},
+	{"MCP_EXPENSIVE_TOOLS", "rate_limit.expensive_tools"},
+	{"MCP_DAILY_READ_BYTES", "rate_limit.daily_read_bytes"},
+	{"MCP_LOG_OUTPUT", "logging.output"},
+	{"MCP_LOG_LEVEL", "logging.level"},
+	{"MCP_LOG_FORMAT", "logging.format"},
+	{"MCP_AUDIT_LOG", "audit.path"},
+	{"MCP_AUDIT_MAX_SIZE", "audit.max_size"},
+	{"MCP_AUDIT_MAX_FILES", "audit.max_files"},
+	{"MCP_AUDIT_HASH_CHAIN", "audit.hash_chain"},
+	{"MCP_TRACING", "tracing.enabled"},
+	{"MCP_TRACE_FILE", "tracing.file"},
+	{"MCP_TRACE_BUFFER_SIZE", "tracing.buffer_size"},
+	{"MCP_INDEX_MAX_AGE", "health.index_max_age"},
+	{"MCP_ENABLED_TOOLS", "tools.enabled"},
+	{"MCP_REQUEST_TIMEOUT", "timeouts.default"},
+	{"MCP_METHOD_TIMEOUTS", "timeouts.methods"},
+	{"MCP_TOOL_TIMEOUTS", "timeouts.tools"},
+}
+
+// durationType is used to recognise time.Duration fields
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// LoadFile applies the settings in a configuration file on top of c
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	doc, err := parseTOML(string(data))
+	if err != nil {
+		var syntax *syntaxError
+		if errors.As(err, &syntax) {
+			return Errors{{Pos: Position{File: path, Line: syntax.line}, Msg: syntax.msg}}
+		}
+		return err
+	}
+
+	var errs Errors
+	c.decodeTable(doc.root, reflect.ValueOf(c).Elem(), "", path, doc.lines, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// decodeTable copies a parsed table into the struct rv
+func (c *Config) decodeTable(t table, rv reflect.Value, prefix, file string, lines map[string]int, errs *Errors) {
+	fields := tomlFields(rv.Type())
+
+	for key, entry := range t {
+		name := joinKey(prefix, key)
+		index, ok := fields[key]
+		if !ok {
+			*errs = append(*errs, &Error{Pos: Position{File: file, Line: entryLine(entry, name, lines)}, Key: name, Msg: "unknown setting"})
+			continue
+		}
+		field := rv.Field(index)
+
+		switch entry := entry.(type) {
+		case *value:
+			pos := Position{File: file, Line: entry.line}
+			if err := assign(field, entry.v); err != nil {
+				*errs = append(*errs, &Error{Pos: pos, Key: name, Msg: err.Error()})
+				continue
+			}
+			c.positions[name] = pos
+
+		case table:
+			if field.Kind() != reflect.Struct || field.Type() == durationType {
+				*errs = append(*errs, &Error{Pos: Position{File: file, Line: lines[name]}, Key: name, Msg: "expected a value, not a table"})
+				continue
+			}
+			c.decodeTable(entry, field, name, file, lines, errs)
+
+		case []table:
+			if field.Kind() != reflect.Slice || field.Type().Elem().Kind() != reflect.Struct {
+				*errs = append(*errs, &Error{Pos: Position{File: file, Line: lines[name+"[0]"]}, Key: name, Msg: "expected a value, not an array of tables"})
+				continue
+			}
+			slice := reflect.MakeSlice(field.Type(), len(entry), len(entry))
+			for i, item := range entry {
+				itemName := fmt.Sprintf("%s[%d]", name, i)
+				c.positions[itemName] = Position{File: file, Line: lines[itemName]}
+				c.decodeTable(item, slice.Index(i), itemName, file, lines, errs)
+			}
+			field.Set(slice)
+		}
+	}
+}
+
+// entryLine returns the line on which a parsed entry was defined
+func entryLine(entry interface{}, name string, lines map[string]int) int {
+	switch entry := entry.(type) {
+	case *value:
+		return entry.line
+	case table:
+		return lines[name]
+	default:
+		return lines[name+"[0]"]
+	}
+}
+
+// tomlFields maps toml tag names to struct field indexes
+func tomlFields(t reflect.Type) map[string]int {
+	fields := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		if tag := t.Field(i).Tag.Get("toml"); tag != "" {
+			fields[tag] = i
+		}
+	}
+	return fields
+}
+
+// assign stores a parsed TOML value into field, converting as needed
+func assign(field reflect.Value, v interface{}) error {
+	if field.Type() == durationType {
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("expected a duration string such as \"30s\"")
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("expected a string")
+		}
+		field.SetString(s)
+	case reflect.Bool:
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("expected true or false")
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, ok := v.(int64)
+		if !ok {
+			return fmt.Errorf("expected an integer")
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		switch n := v.(type) {
+		case int64:
+			field.SetFloat(float64(n))
+		case float64:
+			field.SetFloat(n)
+		default:
+			return fmt.Errorf("expected a number")
+		}
+	case reflect.Slice:
+		items, ok := v.([]interface{})
+		if !ok || field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("expected an array of strings")
+		}
+		values := make([]string, len(items))
+		for i, item := range items {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("expected an array of strings")
+			}
+			values[i] = s
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported setting type")
+	}
+	return nil
+}
+
+// Set assigns a setting from its string form, as given in an environment
+// variable or flag. Lists are comma-separated.
+func (c *Config) Set(key, raw string, pos Position) error {
+	field, err := c.lookup(key)
+	if err != nil {
+		return &Error{Pos: pos, Key: key, Msg: err.Error()}
+	}
+
+	if err := assignString(field, raw); err != nil {
+		return &Error{Pos: pos, Key: key, Msg: err.Error()}
+	}
+	c.positions[key] = pos
+	return nil
+}
+
+// lookup finds the field for a dotted setting name
+func (c *Config) lookup(key string) (reflect.Value, error) {
+	rv := reflect.ValueOf(c).Elem()
+	for _, part := range strings.Split(key, ".") {
+		if rv.Kind() != reflect.Struct || rv.Type() == durationType {
+			return reflect.Value{}, fmt.Errorf("unknown setting")
+		}
+		index, ok := tomlFields(rv.Type())[part]
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("unknown setting")
+		}
+		rv = rv.Field(index)
+	}
+	if rv.Kind() == reflect.Struct || (rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Struct) {
+		return reflect.Value{}, fmt.Errorf("not a single setting")
+	}
+	return rv, nil
+}
+
+// assignString stores the string form of a value into field
+func assignString(field reflect.Value, raw string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", raw)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", raw)
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", raw)
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		var values []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported setting type")
+	}
+	return nil
+}
+
+// AddRoot appends a search root given as name=path
+func (c *Config) AddRoot(spec string, pos Position) error {
+	name, path, ok := strings.Cut(spec, "=")
+	if !ok || name == "" || path == "" {
+		return &Error{Pos: pos, Key: "roots", Msg: fmt.Sprintf("expected name=path, got %q", spec)}
+	}
+	c.positions[fmt.Sprintf("roots[%d]", len(c.Roots))] = pos
+	c.Roots = append(c.Roots, RootConfig{Name: name, Path: path})
+	return nil
+}
+
+// resetRoots removes every root along with the positions recorded for them
+func (c *Config) resetRoots() {
+	c.Roots = nil
+	for key := range c.positions {
+		if strings.HasPrefix(key, "roots[") {
+			delete(c.positions, key)
+		}
+	}
+}
+
+// applyEnv applies MCP_* environment variable overrides
+func (c *Config) applyEnv(getenv func(string) string) error {
+	var errs Errors
+	for _, env := range envVars {
+		raw := getenv(env.name)
+		if raw == "" {
+			continue
+		}
+		if err := c.Set(env.key, raw, Position{Source: "env " + env.name}); err != nil {
+			errs = append(errs, err.(*Error))
+		}
+	}
+
+	// MCP_ROOTS lists roots as name=path pairs separated by commas and
+	// replaces the roots from the configuration file
+	if roots := getenv("MCP_ROOTS"); roots != "" {
+		c.resetRoots()
+		for _, spec := range strings.Split(roots, ",") {
+			if err := c.AddRoot(strings.TrimSpace(spec), Position{Source: "env MCP_ROOTS"}); err != nil {
+				errs = append(errs, err.(*Error))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Options are the command-line options that control loading rather than
+// individual settings
+type Options struct {
+	ConfigFile  string
+	PrintConfig bool
+	CheckConfig bool
+}
+
+// multiFlag collects repeated string flags
+type multiFlag []string
+
+func (m *multiFlag) String() string     { return strings.Join(*m, ",") }
+func (m *multiFlag) Set(s string) error { *m = append(*m, s); return nil }
+
+// Load builds the configuration for a server binary from defaults, the
+// configuration file, the environment and the command line, in that order
+// of precedence. The configuration file is taken from -config or MCP_CONFIG.
+func Load(program string, args []string, stderr io.Writer) (*Config, Options, error) {
+	fs := flag.NewFlagSet(program, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var opts Options
+	var roots, sets multiFlag
+	fs.StringVar(&opts.ConfigFile, "config", os.Getenv("MCP_CONFIG"), "path to a TOML configuration file")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective configuration and exit")
+	fs.BoolVar(&opts.CheckConfig, "check-config", false, "validate the configuration and exit")
+	host := fs.String("host", "", "HTTP listen host")
+	port := fs.String("port", "", "HTTP listen port")
+	index := fs.String("index", "", "path of the persisted file index")
+	logOutput := fs.String("log-output", "", "where to write logs: stderr or a file path")
+	logLevel := fs.String("log-level", "", "minimum log level: debug, info, warn or error")
+	auditLog := fs.String("audit-log", "", "path of the audit log of tool calls and resource reads")
+	tools := fs.String("tools", "", "comma-separated glob patterns of tools to enable")
+	fs.Var(&roots, "root", "search root as name=path (repeatable)")
+	fs.Var(&sets, "set", "override any setting as key=value, e.g. search.max_results=50 (repeatable)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, opts, err
+	}
+
+	cfg := Default()
+	if opts.ConfigFile != "" {
+		if err := cfg.LoadFile(opts.ConfigFile); err != nil {
+			return nil, opts, err
+		}
+	}
+
+	if err := cfg.applyEnv(os.Getenv); err != nil {
+		return nil, opts, err
+	}
+
+	// Roots given on the command line replace those from other sources
+	if len(roots) > 0 {
+		cfg.resetRoots()
+		for _, spec := range roots {
+			if err := cfg.AddRoot(spec, Position{Source: "flag -root"}); err != nil {
+				return nil, opts, err
+			}
+		}
+	}
+
+	named := []struct {
+		flag  string
+		key   string
+		value string
+	}{
+		{"host", "http.host", *host},
+		{"port", "http.port", *port},
+		{"index", "index.path", *index},
+		{"log-output", "logging.output", *logOutput},
+		{"log-level", "logging.level", *logLevel},
+		{"audit-log", "audit.path", *auditLog},
+		{"tools", "tools.enabled", *tools},
+	}
+	for _, f := range named {
+		if f.value == "" {
+			continue
+		}
+		if err := cfg.Set(f.key, f.value, Position{Source: "flag -" + f.flag}); err != nil {
+			return nil, opts, err
+		}
+	}
+
+	for _, set := range sets {
+		key, raw, ok := strings.Cut(set, "=")
+		if !ok {
+			return nil, opts, &Error{Pos: Position{Source: "flag -set"}, Msg: fmt.Sprintf("expected key=value, got %q", set)}
+		}
+		if err := cfg.Set(strings.TrimSpace(key), raw, Position{Source: "flag -set"}); err != nil {
+			return nil, opts, err
+		}
+	}
+
+	return cfg, opts, nil
+}
@@ -0,0 +1,373 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The configuration file format is the subset of TOML needed by the server:
+// comments, [tables], [[arrays of tables]], and key = value pairs whose
+// values are strings, integers, floats, booleans or arrays of those. Arrays
+// may span several lines.
+
+// value is a parsed leaf value together with the line it was defined on
+type value struct {
+	v    interface{}
+	line int
+}
+
+// table is a parsed TOML table. Entries are *value, table or []table.
+type table map[string]interface{}
+
+// parsed is a parsed document: the root table and the line on which each
+// table header appeared
+type parsed struct {
+	root  table
+	lines map[string]int
+}
+
+// syntaxError is a parse error at a specific line
+type syntaxError struct {
+	line int
+	msg  string
+}
+
+func (e *syntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.msg)
+}
+
+// parseTOML parses a configuration document
+func parseTOML(data string) (*parsed, error) {
+	p := &parsed{root: table{}, lines: map[string]int{}}
+	current := p.root
+	currentPath := ""
+
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(stripComment(lines[i]))
+		if line == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "[["):
+			if !strings.HasSuffix(line, "]]") {
+				return nil, &syntaxError{lineNo, "unterminated array table header"}
+			}
+			name := strings.TrimSpace(line[2 : len(line)-2])
+			t, err := p.appendTable(name, lineNo)
+			if err != nil {
+				return nil, err
+			}
+			current, currentPath = t, name
+
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") {
+				return nil, &syntaxError{lineNo, "unterminated table header"}
+			}
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			t, err := p.ensureTable(name, lineNo)
+			if err != nil {
+				return nil, err
+			}
+			current, currentPath = t, name
+
+		default:
+			key, rest, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, &syntaxError{lineNo, fmt.Sprintf("expected key = value, got %q", line)}
+			}
+			key = strings.TrimSpace(key)
+			if !validKey(key) {
+				return nil, &syntaxError{lineNo, fmt.Sprintf("invalid key %q", key)}
+			}
+
+			// Arrays may continue over several lines until brackets balance
+			rest = strings.TrimSpace(rest)
+			for unbalanced(rest) && i+1 < len(lines) {
+				i++
+				rest += " " + strings.TrimSpace(stripComment(lines[i]))
+			}
+
+			v, remaining, err := parseValue(rest)
+			if err != nil {
+				return nil, &syntaxError{lineNo, err.Error()}
+			}
+			if strings.TrimSpace(remaining) != "" {
+				return nil, &syntaxError{lineNo, fmt.Sprintf("unexpected %q after value", strings.TrimSpace(remaining))}
+			}
+			if _, exists := current[key]; exists {
+				return nil, &syntaxError{lineNo, fmt.Sprintf("duplicate key %s", joinKey(currentPath, key))}
+			}
+			current[key] = &value{v: v, line: lineNo}
+		}
+	}
+
+	return p, nil
+}
+
+// ensureTable returns the table at a dotted path, creating it if needed
+func (p *parsed) ensureTable(name string, line int) (table, error) {
+	t := p.root
+	for _, part := range strings.Split(name, ".") {
+		part = strings.TrimSpace(part)
+		if !validKey(part) {
+			return nil, &syntaxError{line, fmt.Sprintf("invalid table name %q", name)}
+		}
+		switch next := t[part].(type) {
+		case nil:
+			created := table{}
+			t[part] = created
+			t = created
+		case table:
+			t = next
+		case []table:
+			t = next[len(next)-1]
+		default:
+			return nil, &syntaxError{line, fmt.Sprintf("%s is already defined as a value", name)}
+		}
+	}
+	p.lines[name] = line
+	return t, nil
+}
+
+// appendTable adds a new table to the array of tables at a dotted path
+func (p *parsed) appendTable(name string, line int) (table, error) {
+	parent := p.root
+	parts := strings.Split(name, ".")
+	if len(parts) > 1 {
+		var err error
+		if parent, err = p.ensureTable(strings.Join(parts[:len(parts)-1], "."), line); err != nil {
+			return nil, err
+		}
+	}
+
+	last := strings.TrimSpace(parts[len(parts)-1])
+	if !validKey(last) {
+		return nil, &syntaxError{line, fmt.Sprintf("invalid table name %q", name)}
+	}
+
+	var array []table
+	switch existing := parent[last].(type) {
+	case nil:
+	case []table:
+		array = existing
+	default:
+		return nil, &syntaxError{line, fmt.Sprintf("%s is not an array of tables", name)}
+	}
+
+	t := table{}
+	parent[last] = append(array, t)
+	p.lines[fmt.Sprintf("%s[%d]", name, len(array))] = line
+	return t, nil
+}
+
+// validKey reports whether s is a bare TOML key
+func validKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r == '_' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// stripComment removes a trailing # comment that is not inside a string
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unbalanced reports whether s opens more brackets than it closes outside strings
+func unbalanced(s string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '[':
+			depth++
+		case quote == 0 && c == ']':
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+// parseValue parses a single value from the start of s, returning the rest
+func parseValue(s string) (interface{}, string, error) {
+	s = strings.TrimLeft(s, " \t")
+	if s == "" {
+		return nil, "", fmt.Errorf("missing value")
+	}
+
+	switch s[0] {
+	case '"':
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				str, err := unquoteBasic(s[1:i])
+				if err != nil {
+					return nil, "", fmt.Errorf("invalid string %s: %v", s[:i+1], err)
+				}
+				return str, s[i+1:], nil
+			}
+		}
+		return nil, "", fmt.Errorf("unterminated string")
+
+	case '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : end+1], s[end+2:], nil
+
+	case '[':
+		return parseArray(s[1:])
+	}
+
+	// Bare token: boolean or number
+	end := strings.IndexAny(s, ", \t]")
+	if end < 0 {
+		end = len(s)
+	}
+	token, rest := s[:end], s[end:]
+
+	switch token {
+	case "true":
+		return true, rest, nil
+	case "false":
+		return false, rest, nil
+	}
+
+	number := strings.ReplaceAll(token, "_", "")
+	if n, err := strconv.ParseInt(number, 10, 64); err == nil {
+		return n, rest, nil
+	}
+	if f, err := strconv.ParseFloat(number, 64); err == nil {
+		return f, rest, nil
+	}
+
+	return nil, "", fmt.Errorf("invalid value %q (strings must be quoted)", token)
+}
+
+// unquoteBasic decodes the body of a basic string. Only the escapes TOML
+// defines are accepted: \b \t \n \f \r \" \\ and \uXXXX or \UXXXXXXXX naming
+// a Unicode scalar value.
+func unquoteBasic(body string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c != '\\' {
+			if c < 0x20 && c != '\t' || c == 0x7f {
+				return "", fmt.Errorf("control character %U must be escaped", rune(c))
+			}
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i == len(body) {
+			return "", fmt.Errorf("incomplete escape")
+		}
+		switch body[i] {
+		case 'b':
+			b.WriteByte('\b')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(body[i])
+		case 'u', 'U':
+			digits := 4
+			if body[i] == 'U' {
+				digits = 8
+			}
+			if i+digits >= len(body) {
+				return "", fmt.Errorf("\\%c escape needs %d hex digits", body[i], digits)
+			}
+			hex := body[i+1 : i+1+digits]
+			code, err := strconv.ParseUint(hex, 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("\\%c escape needs %d hex digits", body[i], digits)
+			}
+			if !utf8.ValidRune(rune(code)) {
+				return "", fmt.Errorf("\\%c%s is not a Unicode scalar value", body[i], hex)
+			}
+			b.WriteRune(rune(code))
+			i += digits
+		default:
+			return "", fmt.Errorf("invalid escape \\%c", body[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// parseArray parses array items after the opening bracket
+func parseArray(s string) (interface{}, string, error) {
+	items := []interface{}{}
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if strings.HasPrefix(s, "]") {
+			return items, s[1:], nil
+		}
+		if s == "" {
+			return nil, "", fmt.Errorf("unterminated array")
+		}
+
+		item, rest, err := parseValue(s)
+		if err != nil {
+			return nil, "", err
+		}
+		items = append(items, item)
+
+		s = strings.TrimLeft(rest, " \t")
+		switch {
+		case strings.HasPrefix(s, ","):
+			s = s[1:]
+		case strings.HasPrefix(s, "]"):
+		default:
+			return nil, "", fmt.Errorf("expected , or ] in array")
+		}
+	}
+}
+
+// joinKey joins a table path and a key with a dot
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
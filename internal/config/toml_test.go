@@ -0,0 +1,146 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// plain returns the values of a parsed table without their line numbers
+func plain(t table) map[string]interface{} {
+	out := make(map[string]interface{}, len(t))
+	for key, entry := range t {
+		switch entry := entry.(type) {
+		case *value:
+			out[key] = entry.v
+		case table:
+			out[key] = plain(entry)
+		case []table:
+			tables := make([]interface{}, len(entry))
+			for i, t := range entry {
+				tables[i] = plain(t)
+			}
+			out[key] = tables
+		}
+	}
+	return out
+}
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]interface{}
+	}{
+		{
+			name:  "scalars",
+			input: "s = \"text\"\nl = 'C:\\path'\ni = 1_000\nn = -3\nf = 0.5\nb = true\nc = false",
+			want: map[string]interface{}{
+				"s": "text", "l": `C:\path`, "i": int64(1000), "n": int64(-3), "f": 0.5, "b": true, "c": false,
+			},
+		},
+		{
+			name:  "escapes",
+			input: `s = "tab\there\nquote\" back\\ \b\f\r \u00e9 \U0001F600"`,
+			want:  map[string]interface{}{"s": "tab\there\nquote\" back\\ \b\f\r é 😀"},
+		},
+		{
+			name:  "comments",
+			input: "# heading\nkey = \"a # not a comment\" # comment\nother = 'lit # eral' # literal\n\n   # indented",
+			want:  map[string]interface{}{"key": "a # not a comment", "other": "lit # eral"},
+		},
+		{
+			name:  "arrays",
+			input: "empty = []\nwords = [\"a\", 'b']\nnested = [[1, 2], [\"x\"]]\nmixed = [1, 2.5, true]",
+			want: map[string]interface{}{
+				"empty":  []interface{}{},
+				"words":  []interface{}{"a", "b"},
+				"nested": []interface{}{[]interface{}{int64(1), int64(2)}, []interface{}{"x"}},
+				"mixed":  []interface{}{int64(1), 2.5, true},
+			},
+		},
+		{
+			name:  "multiline array",
+			input: "roots = [\n  \"a\", # first\n  \"b]\",\n]\nafter = 1",
+			want:  map[string]interface{}{"roots": []interface{}{"a", "b]"}, "after": int64(1)},
+		},
+		{
+			name:  "tables",
+			input: "top = 1\n[http]\nport = 8080\n[http.tls]\ncert = \"c.pem\"\n[ log ]\nlevel = \"debug\"",
+			want: map[string]interface{}{
+				"top":  int64(1),
+				"http": map[string]interface{}{"port": int64(8080), "tls": map[string]interface{}{"cert": "c.pem"}},
+				"log":  map[string]interface{}{"level": "debug"},
+			},
+		},
+		{
+			name:  "arrays of tables",
+			input: "[[roots]]\nname = \"a\"\n[[roots]]\nname = \"b\"\n[roots.limits]\nmax = 1",
+			want: map[string]interface{}{
+				"roots": []interface{}{
+					map[string]interface{}{"name": "a"},
+					map[string]interface{}{"name": "b", "limits": map[string]interface{}{"max": int64(1)}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := parseTOML(tt.input)
+			if err != nil {
+				t.Fatalf("parseTOML: %v", err)
+			}
+			if got := plain(p.root); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsed %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"bell escape", `s = "\a"`, `line 1: invalid string "\a": invalid escape \a`},
+		{"hex escape", `s = "\x41"`, `invalid escape \x`},
+		{"octal escape", `s = "\101"`, `invalid escape \1`},
+		{"single quote escape", `s = "\'"`, `invalid escape \'`},
+		{"short unicode escape", `s = "\u41"`, `\u escape needs 4 hex digits`},
+		{"non-hex unicode escape", `s = "\u00g1"`, `\u escape needs 4 hex digits`},
+		{"signed unicode escape", `s = "\U+0000041"`, `\U escape needs 8 hex digits`},
+		{"surrogate escape", `s = "\ud800"`, `\ud800 is not a Unicode scalar value`},
+		{"escape beyond Unicode", `s = "\U00110000"`, `\U00110000 is not a Unicode scalar value`},
+		{"control character", "s = \"a\x01b\"", "control character U+0001 must be escaped"},
+		{"unterminated string", `s = "abc`, "line 1: unterminated string"},
+		{"unterminated literal", `s = 'abc`, "unterminated string"},
+		{"unquoted string", "s = abc", `invalid value "abc" (strings must be quoted)`},
+		{"missing value", "s =", "missing value"},
+		{"no equals", "\n\njust words", `line 3: expected key = value, got "just words"`},
+		{"invalid key", "a.b = 1", `invalid key "a.b"`},
+		{"trailing garbage", `s = "a" "b"`, `unexpected "\"b\"" after value`},
+		{"duplicate key", "[t]\nk = 1\nk = 2", "line 3: duplicate key t.k"},
+		{"unterminated array", "a = [1,", "unterminated array"},
+		{"unterminated array item", "a = [1, 2", "expected , or ] in array"},
+		{"missing comma", "a = [1 2]", "expected , or ] in array"},
+		{"unterminated table", "[t", "unterminated table header"},
+		{"unterminated array table", "[[t]", "unterminated array table header"},
+		{"empty table name", "[]", `invalid table name ""`},
+		{"table over value", "t = 1\n[t]", "line 2: t is already defined as a value"},
+		{"array table over table", "[t]\n[[t]]", "t is not an array of tables"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTOML(tt.input)
+			if err == nil {
+				t.Fatalf("parseTOML(%q) succeeded", tt.input)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
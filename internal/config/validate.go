@@ -0,0 +1,177 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/auth"
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/policy"
+)
+
+// Validate checks the configuration and returns every problem found, each
+// tied to where the offending setting was defined
+func (c *Config) Validate() error {
+	var errs Errors
+	fail := func(key, format string, args ...interface{}) {
+		errs = append(errs, &Error{Pos: c.Position(key), Key: key, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	// Listener
+	if c.HTTP.Port < 1 || c.HTTP.Port > 65535 {
+		fail("http.port", "must be between 1 and 65535")
+	}
+	durations := map[string]int64{
+		"http.read_header_timeout":  int64(c.HTTP.ReadHeaderTimeout),
+		"http.read_timeout":         int64(c.HTTP.ReadTimeout),
+		"http.write_timeout":        int64(c.HTTP.WriteTimeout),
+		"http.idle_timeout":         int64(c.HTTP.IdleTimeout),
+		"http.shutdown_timeout":     int64(c.HTTP.ShutdownTimeout),
+		"http.session_idle_timeout": int64(c.HTTP.SessionTimeout),
+	}
+	for key, d := range durations {
+		if d < 0 {
+			fail(key, "must not be negative")
+		}
+	}
+	if c.HTTP.MaxBodyBytes < 0 {
+		fail("http.max_body_bytes", "must not be negative")
+	}
+	if c.HTTP.MaxSessions < 0 {
+		fail("http.max_sessions", "must not be negative")
+	}
+
+	// TLS
+	tls := c.HTTP.TLS
+	if (tls.Cert == "") != (tls.Key == "") {
+		fail("http.tls.cert", "cert and key must be set together")
+	}
+	switch tls.ClientAuth {
+	case "", "none", "optional", "require":
+	default:
+		fail("http.tls.client_auth", "must be one of none, optional or require")
+	}
+	if tls.ClientAuth != "" && tls.ClientAuth != "none" && tls.ClientCA == "" {
+		fail("http.tls.client_auth", "requires http.tls.client_ca")
+	}
+	for key, path := range map[string]string{
+		"http.tls.cert":         tls.Cert,
+		"http.tls.key":          tls.Key,
+		"http.tls.client_ca":    tls.ClientCA,
+		"http.tls.identity_map": tls.IdentityMap,
+	} {
+		if path != "" {
+			if _, err := os.Stat(path); err != nil {
+				fail(key, "%v", err)
+			}
+		}
+	}
+
+	// Roots
+	seen := map[string]bool{}
+	for i, root := range c.Roots {
+		key := fmt.Sprintf("roots[%d]", i)
+		if seen[root.Name] {
+			fail(key, "duplicate root name %q", root.Name)
+		}
+		seen[root.Name] = true
+		if _, err := filesearch.ValidateRoot(filesearch.Root{Name: root.Name, Path: root.Path}); err != nil {
+			fail(key, "%v", err)
+		}
+	}
+
+	// Search limits
+	if c.Search.MaxFileSize <= 0 {
+		fail("search.max_file_size", "must be positive")
+	}
+	if c.Search.MaxResults <= 0 {
+		fail("search.max_results", "must be positive")
+	}
+
+	// Authentication and authorization
+	if path := c.Auth.CredentialsFile; path != "" {
+		if _, err := auth.LoadStaticAuthenticator(path); err != nil {
+			fail("auth.credentials_file", "%v", err)
+		}
+	}
+	if path := c.Auth.PolicyFile; path != "" {
+		if _, err := policy.Load(path); err != nil {
+			fail("auth.policy_file", "%v", err)
+		}
+	}
+	if oauth := c.Auth.OAuth; oauth.Issuer != "" {
+		if oauth.Resource == "" {
+			fail("auth.oauth.issuer", "auth.oauth.resource is required with an issuer")
+		}
+		if oauth.JWKS == "" {
+			fail("auth.oauth.issuer", "auth.oauth.jwks is required with an issuer")
+		}
+		if oauth.ScopeMap != "" {
+			if _, err := auth.LoadScopeMap(oauth.ScopeMap); err != nil {
+				fail("auth.oauth.scope_map", "%v", err)
+			}
+		}
+	}
+
+	// Rate limits
+	rl := c.RateLimit
+	if rl.Rate < 0 || rl.ExpensiveRate < 0 {
+		fail("rate_limit.rate", "rates must not be negative")
+	}
+	if rl.Burst < 0 || rl.ExpensiveBurst < 0 {
+		fail("rate_limit.burst", "bursts must not be negative")
+	}
+	if rl.DailyReadBytes < 0 {
+		fail("rate_limit.daily_read_bytes", "must not be negative")
+	}
+
+	// Logging
+	if c.Logging.Output == "" {
+		fail("logging.output", "must be stderr, stdout or a file path")
+	}
+	switch c.Logging.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		fail("logging.level", "must be one of debug, info, warn or error")
+	}
+	switch c.Logging.Format {
+	case "text", "json":
+	default:
+		fail("logging.format", "must be text or json")
+	}
+
+	if c.Audit.MaxSize < 0 {
+		fail("audit.max_size", "must not be negative")
+	}
+	if c.Audit.MaxFiles < 0 {
+		fail("audit.max_files", "must not be negative")
+	}
+
+	if c.Tracing.BufferSize < 0 {
+		fail("tracing.buffer_size", "must not be negative")
+	}
+
+	if c.Watch.Interval < 0 {
+		fail("watch.interval", "must not be negative")
+	}
+
+	if c.Health.IndexMaxAge < 0 {
+		fail("health.index_max_age", "must not be negative")
+	}
+
+	// Time limits
+	if c.Timeouts.Default < 0 {
+		fail("timeouts.default", "must not be negative")
+	}
+	if _, err := parseTimeouts(c.Timeouts.Methods); err != nil {
+		fail("timeouts.methods", "%v", err)
+	}
+	if _, err := parseTimeouts(c.Timeouts.Tools); err != nil {
+		fail("timeouts.tools", "%v", err)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+// Package conformance checks the server against recorded responses and the
+// rules of JSON-RPC and MCP. It replays request files over the stdio and
+// HTTP transports, each served in-process by a fresh server, and compares
+// the responses with golden files; and it runs scenarios that exercise the
+// lifecycle, notifications and batches.
+package conformance
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/app"
+	"github.com/aawadall/go-mcp-filesearch/internal/config"
+	"github.com/aawadall/go-mcp-filesearch/internal/server"
+	"github.com/aawadall/go-mcp-filesearch/pkg/client"
+)
+
+// Transports the harness serves the server over
+const (
+	TransportStdio = "stdio"
+	TransportHTTP  = "http"
+)
+
+// Transports lists every transport, in the order they are checked
+var Transports = []string{TransportStdio, TransportHTTP}
+
+// RootName is the name under which the fixture directory is searchable
+const RootName = "fixture"
+
+// DefaultTimeout bounds the wait for each reply and notification
+const DefaultTimeout = 10 * time.Second
+
+// Harness builds the servers under test and checks them
+type Harness struct {
+	// Root is the fixture directory served as the root RootName
+	Root string
+	// GoldenDir holds the golden response files
+	GoldenDir string
+	// Update rewrites golden files instead of comparing with them
+	Update bool
+	// Logger receives the servers' logs; nil discards them
+	Logger *slog.Logger
+	// Timeout bounds the wait for each reply; zero means DefaultTimeout
+	Timeout time.Duration
+}
+
+// Result is the outcome of one check on one transport
+type Result struct {
+	Name      string
+	Transport string
+	Failures  []string
+	Duration  time.Duration
+}
+
+// Passed reports whether the check found no problems
+func (r Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// timeout returns how long to wait for a reply
+func (h *Harness) timeout() time.Duration {
+	if h.Timeout > 0 {
+		return h.Timeout
+	}
+	return DefaultTimeout
+}
+
+// newServer builds a server the way the binaries do, with the fixture as
+// its only root and an in-memory index
+func (h *Harness) newServer() (*server.MCPServer, *config.Config, error) {
+	root, err := filepath.Abs(h.Root)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg := config.Default()
+	cfg.Roots = []config.RootConfig{{Name: RootName, Path: root}}
+
+	mcpServer, err := app.NewMCPServer(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	base := slog.Handler(slog.NewTextHandler(io.Discard, nil))
+	if h.Logger != nil {
+		base = h.Logger.Handler()
+	}
+	mcpServer.SetLogger(slog.New(mcpServer.LogHandler(base)))
+	return mcpServer, cfg, nil
+}
+
+// Dial starts a fresh server and connects to it over transport
+func (h *Harness) Dial(transport string) (*Conn, *server.MCPServer, error) {
+	mcpServer, cfg, err := h.newServer()
+	if err != nil {
+		return nil, nil, err
+	}
+	conn := newConn(h.timeout())
+
+	switch transport {
+	case TransportStdio:
+		err = conn.serveStdio(mcpServer)
+	case TransportHTTP:
+		err = conn.serveHTTP(cfg, mcpServer)
+	default:
+		err = fmt.Errorf("unknown transport %q", transport)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, mcpServer, nil
+}
+
+// Conn is a client connection to a server under test. It sends raw
+// messages, waits for their replies and collects the notifications the
+// server sends.
+type Conn struct {
+	timeout time.Duration
+	send    func(ctx context.Context, message []byte) error
+	close   func()
+	replies chan json.RawMessage
+
+	mu            sync.Mutex
+	notifications []json.RawMessage
+	notified      chan struct{}
+}
+
+// newConn returns a connection with no transport yet
+func newConn(timeout time.Duration) *Conn {
+	return &Conn{
+		timeout:  timeout,
+		replies:  make(chan json.RawMessage, 64),
+		notified: make(chan struct{}),
+	}
+}
+
+// serveStdio serves mcpServer over a pair of pipes, as the stdio binary
+// serves it over stdin and stdout
+func (c *Conn) serveStdio(mcpServer *server.MCPServer) error {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	served := make(chan struct{})
+	go func() {
+		mcpServer.Serve(ctx, inReader, outWriter)
+		outWriter.Close()
+		close(served)
+	}()
+	go func() {
+		scanner := bufio.NewScanner(outReader)
+		scanner.Buffer(make([]byte, 64*1024), 64<<20)
+		for scanner.Scan() {
+			c.receive(append([]byte{}, scanner.Bytes()...))
+		}
+	}()
+
+	var mu sync.Mutex
+	c.send = func(ctx context.Context, message []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		_, err := inWriter.Write(append(message, '\n'))
+		return err
+	}
+	c.close = func() {
+		inWriter.Close()
+		<-served
+		cancel()
+	}
+	return nil
+}
+
+// serveHTTP serves mcpServer over HTTP on a loopback listener and talks to
+// it with the streamable HTTP client transport, so that notifications
+// arrive on the session's event stream
+func (c *Conn) serveHTTP(cfg *config.Config, mcpServer *server.MCPServer) error {
+	_, srv, err := app.NewHTTPServer(cfg, mcpServer)
+	if err != nil {
+		return err
+	}
+	ts := httptest.NewServer(srv.Handler)
+
+	transport := client.NewStreamableHTTPTransport(ts.URL + "/mcp")
+	if err := transport.Connect(context.Background(), c.receive, func(error) {}); err != nil {
+		ts.Close()
+		return err
+	}
+	c.send = transport.Send
+	c.close = func() {
+		transport.Close()
+		ts.CloseClientConnections()
+		ts.Close()
+	}
+	return nil
+}
+
+// receive sorts a message from the server into replies and notifications
+func (c *Conn) receive(message []byte) {
+	var probe struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if json.Unmarshal(message, &probe) == nil && probe.Method != "" && len(probe.ID) == 0 {
+		c.mu.Lock()
+		c.notifications = append(c.notifications, message)
+		close(c.notified)
+		c.notified = make(chan struct{})
+		c.mu.Unlock()
+		return
+	}
+	c.replies <- message
+}
+
+// Send sends a message, a request, notification or batch, and returns the
+// server's reply, or nil if the message calls for none
+func (c *Conn) Send(message []byte) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	if err := c.send(ctx, message); err != nil {
+		return nil, err
+	}
+	if !expectsReply(message) {
+		return nil, nil
+	}
+	select {
+	case reply := <-c.replies:
+		return reply, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("no reply within %s", c.timeout)
+	}
+}
+
+// Notifications returns the notifications received so far
+func (c *Conn) Notifications() []json.RawMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]json.RawMessage{}, c.notifications...)
+}
+
+// WaitNotification waits for a notification with the given method and
+// returns its params
+func (c *Conn) WaitNotification(method string) (json.RawMessage, error) {
+	deadline := time.After(c.timeout)
+	seen := 0
+	for {
+		c.mu.Lock()
+		pending, notified := c.notifications[seen:], c.notified
+		seen = len(c.notifications)
+		c.mu.Unlock()
+
+		for _, message := range pending {
+			var n struct {
+				Method string          `json:"method"`
+				Params json.RawMessage `json:"params"`
+			}
+			if json.Unmarshal(message, &n) == nil && n.Method == method {
+				return n.Params, nil
+			}
+		}
+		select {
+		case <-notified:
+		case <-deadline:
+			return nil, fmt.Errorf("no %s notification within %s", method, c.timeout)
+		}
+	}
+}
+
+// Close disconnects and stops the server
+func (c *Conn) Close() {
+	c.close()
+}
+
+// expectsReply reports whether the server answers a message: requests and
+// invalid messages get a response, notifications none, and a batch an
+// array unless it holds only notifications
+func expectsReply(message []byte) bool {
+	var batch []json.RawMessage
+	if json.Unmarshal(message, &batch) == nil {
+		if len(batch) == 0 {
+			return true
+		}
+		for _, item := range batch {
+			if expectsReply(item) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var msg struct {
+		ID     interface{} `json:"id"`
+		Method string      `json:"method"`
+	}
+	if json.Unmarshal(message, &msg) != nil {
+		return true
+	}
+	return msg.ID != nil || msg.Method == ""
+}
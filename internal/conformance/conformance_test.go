@@ -0,0 +1,65 @@
+package conformance
+
+import (
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files from the responses")
+
+// newTestHarness returns a harness serving the fixture and golden files of
+// this package's testdata
+func newTestHarness() *Harness {
+	return &Harness{
+		Root:      filepath.Join("testdata", "root"),
+		GoldenDir: filepath.Join("testdata", "golden"),
+		Update:    *update,
+	}
+}
+
+// reportFailures fails t with the failures of results
+func reportFailures(t *testing.T, results []Result) {
+	t.Helper()
+	for _, r := range results {
+		for _, failure := range r.Failures {
+			t.Errorf("%s: %s", r.Transport, failure)
+		}
+	}
+}
+
+func TestReplayExamples(t *testing.T) {
+	var files []string
+	for _, pattern := range []string{"*.json", "*.jsonl"} {
+		matches, err := filepath.Glob(filepath.Join("..", "..", "examples", pattern))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		t.Fatal("no request files found in examples/")
+	}
+
+	h := newTestHarness()
+	for _, file := range files {
+		t.Run(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)), func(t *testing.T) {
+			reportFailures(t, h.CheckFile(file, Transports))
+		})
+	}
+}
+
+func TestScenarios(t *testing.T) {
+	h := newTestHarness()
+	for _, s := range Scenarios {
+		for _, transport := range Transports {
+			if !s.AppliesTo(transport) {
+				continue
+			}
+			t.Run(s.Name+"/"+transport, func(t *testing.T) {
+				reportFailures(t, []Result{h.RunScenario(s, transport)})
+			})
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+// Message is one message of a request file: a request, a notification or
+// a batch
+type Message struct {
+	// Name identifies the message in files that name their examples
+	Name string
+	Raw  json.RawMessage
+}
+
+// Exchange is a message and the server's reply to it, as recorded in
+// golden files
+type Exchange struct {
+	Name     string          `json:"name,omitempty"`
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// ReadRequests reads the messages of a request file. A file may hold a
+// sequence of JSON values, one per line as in JSON lines files or spread
+// over several lines; each value is a message or an array sent as a batch.
+// A file may instead be an object whose "examples" object maps names to
+// objects holding a "request", as in examples/test_requests.json; the
+// examples are sent in the order they appear.
+func ReadRequests(path string) ([]Message, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []Message
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(value, &fields) == nil && fields["examples"] != nil {
+			examples, err := namedExamples(fields["examples"])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			messages = append(messages, examples...)
+			continue
+		}
+		messages = append(messages, Message{Raw: value})
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("%s: no messages", path)
+	}
+	return messages, nil
+}
+
+// namedExamples returns the requests of an examples object in the order
+// they appear in it
+func namedExamples(data json.RawMessage) ([]Message, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, errors.New("examples must be an object")
+	}
+
+	var messages []Message
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		name, _ := token.(string)
+		var example struct {
+			Request json.RawMessage `json:"request"`
+		}
+		if err := decoder.Decode(&example); err != nil {
+			return nil, fmt.Errorf("example %s: %w", name, err)
+		}
+		if example.Request == nil {
+			return nil, fmt.Errorf("example %s has no request", name)
+		}
+		messages = append(messages, Message{Name: name, Raw: example.Request})
+	}
+	return messages, nil
+}
+
+// initializeRequest starts sessions for files that do not initialize
+// themselves
+var initializeRequest = json.RawMessage(fmt.Sprintf(
+	`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":%q,"capabilities":{},"clientInfo":{"name":"conformance","version":"1.0.0"}}}`,
+	models.MCPProtocolVersion))
+
+// Replay sends messages in order to a fresh server over transport and
+// returns the exchanges, normalized for comparison. When the first message
+// is not an initialize request, the session is initialized first, without
+// recording it.
+func (h *Harness) Replay(transport string, messages []Message) ([]Exchange, error) {
+	conn, _, err := h.Dial(transport)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if !isInitialize(messages[0].Raw) {
+		if _, err := conn.Send(initializeRequest); err != nil {
+			return nil, fmt.Errorf("initialize: %w", err)
+		}
+	}
+
+	root, err := filepath.Abs(h.Root)
+	if err != nil {
+		return nil, err
+	}
+	exchanges := make([]Exchange, 0, len(messages))
+	for i, message := range messages {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, message.Raw); err != nil {
+			return nil, fmt.Errorf("message %d: %w", i+1, err)
+		}
+		reply, err := conn.Send(compact.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i+1, err)
+		}
+		exchange := Exchange{Name: message.Name, Request: compact.Bytes()}
+		if reply != nil {
+			if exchange.Response, err = normalize(reply, root); err != nil {
+				return nil, fmt.Errorf("message %d: reply: %w", i+1, err)
+			}
+		}
+		exchanges = append(exchanges, exchange)
+	}
+	return exchanges, nil
+}
+
+// isInitialize reports whether a message is an initialize request
+func isInitialize(message json.RawMessage) bool {
+	var req models.JSONRPCRequest
+	return json.Unmarshal(message, &req) == nil && req.Method == "initialize"
+}
+
+// timestampPattern matches RFC 3339 times in text
+var timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+
+// volatileKeys name the fields whose values differ between runs
+var volatileKeys = map[string]bool{
+	"modTime": true,
+	"builtAt": true,
+}
+
+// normalize replaces what differs between runs and machines in a reply:
+// times and the fixture's absolute path
+func normalize(reply json.RawMessage, root string) (json.RawMessage, error) {
+	var value interface{}
+	if err := json.Unmarshal(reply, &value); err != nil {
+		return nil, err
+	}
+	var walk func(v interface{}) interface{}
+	walk = func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, item := range v {
+				if volatileKeys[key] {
+					v[key] = "<time>"
+				} else {
+					v[key] = walk(item)
+				}
+			}
+		case []interface{}:
+			for i, item := range v {
+				v[i] = walk(item)
+			}
+		case string:
+			v = strings.ReplaceAll(v, root, "<root>")
+			return timestampPattern.ReplaceAllString(v, "<time>")
+		}
+		return v
+	}
+	return encode(walk(value), "")
+}
+
+// encode marshals a value without escaping HTML characters, so that the
+// placeholders read as written in golden files
+func encode(value interface{}, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GoldenPath returns the golden file of a request file
+func (h *Harness) GoldenPath(requestFile string) string {
+	base := filepath.Base(requestFile)
+	return filepath.Join(h.GoldenDir, strings.TrimSuffix(base, filepath.Ext(base))+".golden.json")
+}
+
+// CheckFile replays a request file over each transport and compares the
+// exchanges with its golden file. With Update set, the golden file is
+// rewritten from the first transport and the others are compared with it.
+func (h *Harness) CheckFile(path string, transports []string) []Result {
+	results := make([]Result, 0, len(transports))
+	messages, err := ReadRequests(path)
+	if err != nil {
+		for _, transport := range transports {
+			results = append(results, Result{Name: path, Transport: transport, Failures: []string{err.Error()}})
+		}
+		return results
+	}
+
+	goldenPath := h.GoldenPath(path)
+	golden, goldenErr := os.ReadFile(goldenPath)
+	for i, transport := range transports {
+		result := Result{Name: path, Transport: transport}
+		started := time.Now()
+		exchanges, err := h.Replay(transport, messages)
+		result.Duration = time.Since(started)
+		if err != nil {
+			result.Failures = append(result.Failures, err.Error())
+			results = append(results, result)
+			continue
+		}
+
+		actual, err := encode(exchanges, "  ")
+		if err != nil {
+			result.Failures = append(result.Failures, err.Error())
+			results = append(results, result)
+			continue
+		}
+
+		switch {
+		case h.Update && i == 0:
+			err := os.MkdirAll(filepath.Dir(goldenPath), 0o755)
+			if err == nil {
+				err = os.WriteFile(goldenPath, actual, 0o644)
+			}
+			if err != nil {
+				result.Failures = append(result.Failures, err.Error())
+			}
+			golden, goldenErr = actual, err
+		case goldenErr != nil:
+			result.Failures = append(result.Failures, fmt.Sprintf("%v; run with -update to create it", goldenErr))
+		case !bytes.Equal(golden, actual):
+			result.Failures = append(result.Failures, fmt.Sprintf("responses differ from %s (-golden +actual):\n%s", goldenPath, Diff(string(golden), string(actual))))
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// Diff returns the lines that differ between two texts, prefixed with "-"
+// for lines only in a and "+" for lines only in b, with two lines of
+// context around each change
+func Diff(a, b string) string {
+	x := strings.Split(strings.TrimSuffix(a, "\n"), "\n")
+	y := strings.Split(strings.TrimSuffix(b, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			lines = append(lines, line{' ', x[i]})
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, line{'-', x[i]})
+			i++
+		default:
+			lines = append(lines, line{'+', y[j]})
+			j++
+		}
+	}
+
+	const context = 2
+	var out strings.Builder
+	last := -1
+	for k, l := range lines {
+		near := false
+		for d := max(0, k-context); d <= min(len(lines)-1, k+context); d++ {
+			if lines[d].op != ' ' {
+				near = true
+				break
+			}
+		}
+		if !near {
+			continue
+		}
+		if last >= 0 && k > last+1 {
+			out.WriteString("  ...\n")
+		}
+		fmt.Fprintf(&out, "%c %s\n", l.op, l.text)
+		last = k
+	}
+	return out.String()
+}
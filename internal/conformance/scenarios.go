@@ -0,0 +1,353 @@
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+	"github.com/aawadall/go-mcp-filesearch/internal/server"
+)
+
+// Scenario checks one rule of JSON-RPC or MCP against a fresh server
+type Scenario struct {
+	Name string
+	// Transports limits the scenario to some transports; empty means all
+	Transports []string
+	Run        func(t *T)
+}
+
+// T is the state of a scenario run. Its methods send messages and record
+// failures; Fatalf also ends the scenario.
+type T struct {
+	Conn      *Conn
+	Server    *server.MCPServer
+	Transport string
+
+	nextID   int
+	failures []string
+}
+
+// errFatal unwinds a scenario after Fatalf
+type errFatal struct{}
+
+// Errorf records a failure and continues
+func (t *T) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+// Fatalf records a failure and ends the scenario
+func (t *T) Fatalf(format string, args ...interface{}) {
+	t.Errorf(format, args...)
+	panic(errFatal{})
+}
+
+// Response is a decoded JSON-RPC response
+type Response struct {
+	ID     json.RawMessage      `json:"id"`
+	Result json.RawMessage      `json:"result"`
+	Error  *models.JSONRPCError `json:"error"`
+}
+
+// Send sends a raw message and returns the reply, or nil if none came
+func (t *T) Send(message string) json.RawMessage {
+	reply, err := t.Conn.Send([]byte(message))
+	if err != nil {
+		t.Fatalf("sending %s: %v", message, err)
+	}
+	return reply
+}
+
+// Call sends a request with a fresh ID and returns its response
+func (t *T) Call(method string, params interface{}) *Response {
+	t.nextID++
+	message, err := json.Marshal(models.JSONRPCRequest{
+		JSONRPC: models.JSONRPCVersion,
+		ID:      t.nextID,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		t.Fatalf("encoding %s: %v", method, err)
+	}
+	resp := t.decode(t.Send(string(message)))
+	if string(resp.ID) != fmt.Sprint(t.nextID) {
+		t.Errorf("%s: response id %s, want %d", method, resp.ID, t.nextID)
+	}
+	return resp
+}
+
+// MustCall sends a request and fails the scenario if it returns an error
+func (t *T) MustCall(method string, params interface{}) json.RawMessage {
+	resp := t.Call(method, params)
+	if resp.Error != nil {
+		t.Fatalf("%s: error %d: %s", method, resp.Error.Code, resp.Error.Message)
+	}
+	return resp.Result
+}
+
+// Notify sends a notification and fails if the server replies to it
+func (t *T) Notify(method string, params interface{}) {
+	message, err := json.Marshal(models.JSONRPCNotification{
+		JSONRPC: models.JSONRPCVersion,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		t.Fatalf("encoding %s: %v", method, err)
+	}
+	if reply := t.Send(string(message)); reply != nil {
+		t.Errorf("notification %s was answered with %s", method, reply)
+	}
+}
+
+// Initialize performs the initialize handshake
+func (t *T) Initialize() json.RawMessage {
+	result := t.MustCall("initialize", map[string]interface{}{
+		"protocolVersion": models.MCPProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]interface{}{"name": "conformance", "version": "1.0.0"},
+	})
+	t.Notify("notifications/initialized", nil)
+	return result
+}
+
+// WaitNotification waits for a notification and returns its params
+func (t *T) WaitNotification(method string) json.RawMessage {
+	params, err := t.Conn.WaitNotification(method)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	return params
+}
+
+// decode decodes a single response
+func (t *T) decode(reply json.RawMessage) *Response {
+	if reply == nil {
+		t.Fatalf("expected a response, got none")
+	}
+	var resp Response
+	if err := json.Unmarshal(reply, &resp); err != nil {
+		t.Fatalf("expected a response object, got %s", reply)
+	}
+	return &resp
+}
+
+// decodeBatch decodes an array of responses
+func (t *T) decodeBatch(reply json.RawMessage) []Response {
+	if reply == nil {
+		t.Fatalf("expected an array of responses, got none")
+	}
+	var responses []Response
+	if err := json.Unmarshal(reply, &responses); err != nil {
+		t.Fatalf("expected an array of responses, got %s", reply)
+	}
+	return responses
+}
+
+// wantError checks that a response is an error with the given code
+func (t *T) wantError(what string, resp *Response, code int) {
+	switch {
+	case resp.Error == nil:
+		t.Errorf("%s: expected error %d, got result %s", what, code, resp.Result)
+	case resp.Error.Code != code:
+		t.Errorf("%s: expected error %d, got %d: %s", what, code, resp.Error.Code, resp.Error.Message)
+	}
+}
+
+// Scenarios lists the checks of JSON-RPC and MCP behavior
+var Scenarios = []Scenario{
+	{Name: "lifecycle/requests-before-initialize-fail", Run: func(t *T) {
+		if resp := t.Call("tools/list", nil); resp.Error == nil {
+			t.Errorf("tools/list before initialize succeeded: %s", resp.Result)
+		}
+		t.Initialize()
+		t.MustCall("tools/list", nil)
+	}},
+	{Name: "lifecycle/initialize-result", Run: func(t *T) {
+		var result models.InitializeResult
+		if err := json.Unmarshal(t.Initialize(), &result); err != nil {
+			t.Fatalf("initialize result: %v", err)
+		}
+		if result.ProtocolVersion != models.MCPProtocolVersion {
+			t.Errorf("protocolVersion %q, want %q", result.ProtocolVersion, models.MCPProtocolVersion)
+		}
+		if result.ServerInfo.Name == "" || result.ServerInfo.Version == "" {
+			t.Errorf("serverInfo incomplete: %+v", result.ServerInfo)
+		}
+		if result.Capabilities.Tools == nil || result.Capabilities.Resources == nil {
+			t.Errorf("tools and resources capabilities missing: %+v", result.Capabilities)
+		}
+	}},
+	{Name: "lifecycle/initialized-notification-accepted", Run: func(t *T) {
+		t.Initialize()
+		t.Notify("notifications/initialized", nil)
+		t.MustCall("resources/list", nil)
+	}},
+	{Name: "notifications/not-answered", Run: func(t *T) {
+		t.Initialize()
+		t.Notify("tools/list", nil)
+		t.Notify("notifications/cancelled", map[string]interface{}{"requestId": 99})
+		t.Notify("no/such/method", nil)
+		// The connection still pairs requests with their responses
+		t.MustCall("tools/list", nil)
+	}},
+	{Name: "notifications/tools-list-changed", Run: func(t *T) {
+		t.Initialize()
+		t.Server.AddTool(models.Tool{
+			Name:        "conformance_probe",
+			InputSchema: map[string]interface{}{"type": "object"},
+		}, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return map[string]interface{}{"content": []interface{}{}}, nil
+		})
+		t.WaitNotification(models.NotificationToolsListChanged)
+
+		var list struct{ Tools []models.Tool }
+		json.Unmarshal(t.MustCall("tools/list", nil), &list)
+		found := false
+		for _, tool := range list.Tools {
+			found = found || tool.Name == "conformance_probe"
+		}
+		if !found {
+			t.Errorf("tools/list does not include the added tool")
+		}
+	}},
+	{Name: "notifications/log-messages", Run: func(t *T) {
+		t.Initialize()
+		t.MustCall("logging/setLevel", map[string]interface{}{"level": "debug"})
+		t.MustCall("tools/list", nil)
+		var msg struct {
+			Level string          `json:"level"`
+			Data  json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(t.WaitNotification(models.NotificationMessage), &msg); err != nil {
+			t.Fatalf("log message: %v", err)
+		}
+		if msg.Level == "" || len(msg.Data) == 0 {
+			t.Errorf("log message without level or data")
+		}
+	}},
+	{Name: "notifications/resource-updated", Run: func(t *T) {
+		t.Initialize()
+		uri := filesearch.ResourceURI(RootName, "")
+		t.MustCall("resources/subscribe", map[string]interface{}{"uri": uri})
+		t.MustCall("tools/call", map[string]interface{}{"name": filesearch.ToolRebuildIndex, "arguments": map[string]interface{}{}})
+		var params models.ResourceUpdatedParams
+		json.Unmarshal(t.WaitNotification(models.NotificationResourceUpdated), &params)
+		if params.URI != uri {
+			t.Errorf("updated %q, want %q", params.URI, uri)
+		}
+	}},
+	{Name: "batch/answered-with-array", Run: func(t *T) {
+		t.Initialize()
+		responses := t.decodeBatch(t.Send(`[
+			{"jsonrpc":"2.0","id":"a","method":"tools/list"},
+			{"jsonrpc":"2.0","id":"b","method":"resources/list"}
+		]`))
+		if len(responses) != 2 {
+			t.Fatalf("expected 2 responses, got %d", len(responses))
+		}
+		for i, id := range []string{`"a"`, `"b"`} {
+			if string(responses[i].ID) != id || responses[i].Error != nil {
+				t.Errorf("response %d: id %s, error %v", i, responses[i].ID, responses[i].Error)
+			}
+		}
+	}},
+	{Name: "batch/notifications-not-answered", Run: func(t *T) {
+		t.Initialize()
+		responses := t.decodeBatch(t.Send(`[
+			{"jsonrpc":"2.0","method":"notifications/initialized"},
+			{"jsonrpc":"2.0","id":7,"method":"tools/list"}
+		]`))
+		if len(responses) != 1 || string(responses[0].ID) != "7" {
+			t.Errorf("expected only the response to id 7, got %d responses", len(responses))
+		}
+	}},
+	{Name: "batch/only-notifications", Run: func(t *T) {
+		t.Initialize()
+		if reply := t.Send(`[{"jsonrpc":"2.0","method":"notifications/initialized"}]`); reply != nil {
+			t.Errorf("a batch of notifications was answered with %s", reply)
+		}
+		t.MustCall("tools/list", nil)
+	}},
+	{Name: "batch/empty-rejected", Run: func(t *T) {
+		t.Initialize()
+		reply := t.Send(`[]`)
+		if strings.HasPrefix(strings.TrimSpace(string(reply)), "[") {
+			t.Fatalf("an empty batch was answered with an array: %s", reply)
+		}
+		resp := t.decode(reply)
+		t.wantError("empty batch", resp, models.ErrCodeInvalidRequest)
+		if string(resp.ID) != "null" {
+			t.Errorf("empty batch: id %s, want null", resp.ID)
+		}
+	}},
+	{Name: "errors/unknown-method", Run: func(t *T) {
+		t.Initialize()
+		t.wantError("no/such/method", t.Call("no/such/method", nil), models.ErrCodeMethodNotFound)
+	}},
+	{Name: "errors/missing-method", Run: func(t *T) {
+		t.Initialize()
+		resp := t.decode(t.Send(`{"jsonrpc":"2.0","id":5}`))
+		t.wantError("request without method", resp, models.ErrCodeInvalidRequest)
+		if string(resp.ID) != "5" {
+			t.Errorf("id %s, want 5", resp.ID)
+		}
+	}},
+	{Name: "errors/parse-error", Transports: []string{TransportHTTP}, Run: func(t *T) {
+		t.Initialize()
+		resp := t.decode(t.Send(`{"jsonrpc":`))
+		t.wantError("malformed JSON", resp, models.ErrCodeParseError)
+	}},
+	{Name: "ids/echoed-exactly", Run: func(t *T) {
+		t.Initialize()
+		for _, id := range []string{`"req-1"`, `42`, `"42"`, `0`} {
+			resp := t.decode(t.Send(`{"jsonrpc":"2.0","id":` + id + `,"method":"tools/list"}`))
+			if string(resp.ID) != id {
+				t.Errorf("id %s answered with id %s", id, resp.ID)
+			}
+		}
+	}},
+}
+
+// RunScenario runs a scenario against a fresh server over transport
+func (h *Harness) RunScenario(s Scenario, transport string) (result Result) {
+	result = Result{Name: s.Name, Transport: transport}
+	started := time.Now()
+	defer func() { result.Duration = time.Since(started) }()
+
+	conn, mcpServer, err := h.Dial(transport)
+	if err != nil {
+		result.Failures = []string{err.Error()}
+		return result
+	}
+	defer conn.Close()
+
+	t := &T{Conn: conn, Server: mcpServer, Transport: transport}
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(errFatal); !ok {
+				t.Errorf("panic: %v", r)
+			}
+		}
+		result.Failures = t.failures
+	}()
+	s.Run(t)
+	return result
+}
+
+// AppliesTo reports whether the scenario runs over transport
+func (s Scenario) AppliesTo(transport string) bool {
+	if len(s.Transports) == 0 {
+		return true
+	}
+	for _, t := range s.Transports {
+		if t == transport {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,10 @@
+package main
+
+import "fmt"
+
+// Greeting is what hello prints
+const Greeting = "Hello, conformance"
+
+func main() {
+	fmt.Println(Greeting)
+}
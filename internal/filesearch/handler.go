@@ -1 +1,428 @@
-package filesearch
\ No newline at end of file
+package filesearch
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+// Names of the tools exposed by the Handler
+const (
+	ToolFindFiles     = "find_files"
+	ToolSearchContent = "search_content"
+	ToolReadFile      = "read_file"
+	ToolRebuildIndex  = "rebuild_index"
+)
+
+// ResourceScheme is the URI scheme of file resources: filesearch://<root>/<path>
+const ResourceScheme = "filesearch"
+
+// Handler exposes an Engine as MCP tools
+type Handler struct {
+	engine *Engine
+}
+
+// NewHandler creates a tool handler for the given engine
+func NewHandler(engine *Engine) *Handler {
+	return &Handler{engine: engine}
+}
+
+// Engine returns the underlying search engine
+func (h *Handler) Engine() *Engine {
+	return h.engine
+}
+
+// ResourceURI returns the resource URI of a path within a root
+func ResourceURI(root, path string) string {
+	return ResourceScheme + "://" + root + "/" + strings.TrimPrefix(path, "/")
+}
+
+// ParseResourceURI splits a file resource URI into its root and the name
+// within the root, percent-decoded and checked by CleanPath
+func ParseResourceURI(uri string) (root, name string, err error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != ResourceScheme {
+		return "", "", fmt.Errorf("%w: %s is not a %s URI", ErrInvalidPath, uri, ResourceScheme)
+	}
+	name, err = CleanPath(u.Path)
+	if err != nil {
+		return "", "", err
+	}
+	return u.Host, name, nil
+}
+
+// Tools returns the definitions of the file search tools
+func (h *Handler) Tools() []models.Tool {
+	readOnly := &models.ToolAnnotations{ReadOnlyHint: true}
+	rootProperty := map[string]interface{}{
+		"type":        "string",
+		"description": "Name of the root to search; all roots if omitted",
+	}
+	limitProperty := map[string]interface{}{
+		"type":        "integer",
+		"description": "Maximum number of results",
+	}
+
+	return []models.Tool{
+		{
+			Name:        ToolFindFiles,
+			Description: "Find files by name using glob patterns",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pattern": map[string]interface{}{
+						"type":        "string",
+						"description": "Glob matched against the file name, or the relative path if it contains '/'; '**' crosses directories",
+					},
+					"extensions": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Only return files with these extensions",
+					},
+					"root":  rootProperty,
+					"limit": limitProperty,
+				},
+			},
+			Annotations: readOnly,
+		},
+		{
+			Name:        ToolSearchContent,
+			Description: "Search file contents for a string or regular expression",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "Text or regular expression to search for",
+					},
+					"regex": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Treat query as a regular expression",
+					},
+					"caseSensitive": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Match case exactly",
+					},
+					"include": map[string]interface{}{
+						"type":        "string",
+						"description": "Only search files matching this glob",
+					},
+					"extensions": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Only search files with these extensions",
+					},
+					"contextLines": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Lines of context around each match (max %d)", MaxContextLines),
+					},
+					"root":  rootProperty,
+					"limit": limitProperty,
+				},
+				"required": []string{"query"},
+			},
+			Annotations: readOnly,
+		},
+		{
+			Name:        ToolReadFile,
+			Description: "Read the contents of a file",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"root": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the root containing the file",
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path of the file relative to the root",
+					},
+				},
+				"required": []string{"root", "path"},
+			},
+			Annotations: readOnly,
+		},
+		{
+			Name:        ToolRebuildIndex,
+			Description: "Rebuild the file index used to speed up name searches",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"root": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the root to reindex; all roots if omitted",
+					},
+				},
+			},
+		},
+	}
+}
+
+// Call executes the named file search tool. filter restricts which paths may
+// appear in the result.
+func (h *Handler) Call(ctx context.Context, name string, args map[string]interface{}, filter PathFilter) (interface{}, error) {
+	switch name {
+	case ToolFindFiles:
+		result, err := h.engine.Find(ctx, FindQuery{
+			Root:       stringArg(args, "root"),
+			Pattern:    stringArg(args, "pattern"),
+			Extensions: stringSliceArg(args, "extensions"),
+			Limit:      intArg(args, "limit"),
+			Filter:     filter,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return toolResult(formatFiles(result), result), nil
+
+	case ToolSearchContent:
+		result, err := h.engine.Grep(ctx, GrepQuery{
+			Root:          stringArg(args, "root"),
+			Query:         stringArg(args, "query"),
+			Regex:         boolArg(args, "regex"),
+			CaseSensitive: boolArg(args, "caseSensitive"),
+			Include:       stringArg(args, "include"),
+			Extensions:    stringSliceArg(args, "extensions"),
+			ContextLines:  intArg(args, "contextLines"),
+			Limit:         intArg(args, "limit"),
+			Filter:        filter,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return toolResult(formatMatches(result), result), nil
+
+	case ToolReadFile:
+		root, path := stringArg(args, "root"), stringArg(args, "path")
+		if root == "" || path == "" {
+			return nil, fmt.Errorf("%w: root and path arguments required", ErrInvalidArgument)
+		}
+		// The filter must see the name that is read
+		path, err := CleanPath(path)
+		if err != nil {
+			return nil, err
+		}
+		if filter != nil && !filter(root, path) {
+			return nil, fmt.Errorf("access to %s %w", ResourceURI(root, path), ErrNotPermitted)
+		}
+		content, err := h.engine.Read(ctx, root, path)
+		if err != nil {
+			return nil, err
+		}
+		if content.IsBinary() {
+			return map[string]interface{}{
+				"content": []map[string]interface{}{
+					{
+						"type": "resource",
+						"resource": map[string]interface{}{
+							"uri":      ResourceURI(root, content.Path),
+							"mimeType": content.MimeType,
+							"blob":     base64.StdEncoding.EncodeToString(content.Data),
+						},
+					},
+				},
+			}, nil
+		}
+		return toolResult(content.Text, content), nil
+
+	case ToolRebuildIndex:
+		stats, err := h.engine.BuildIndex(ctx, stringArg(args, "root"))
+		if err != nil {
+			return nil, err
+		}
+		return StatsResult(stats), nil
+
+	default:
+		return nil, fmt.Errorf("unknown tool: %s", name)
+	}
+}
+
+// BytesRead returns how much file content a file search tool result read:
+// the bytes search_content scanned or the size of the file read_file
+// returned. It returns false if result is not a file search result.
+func BytesRead(result interface{}) (int64, bool) {
+	content, ok := result.(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+
+	switch structured := content["structuredContent"].(type) {
+	case *FindResult:
+		return 0, true
+	case *GrepResult:
+		return structured.BytesScanned, true
+	case *FileContent:
+		return structured.Size, true
+	}
+
+	// Binary files are returned as embedded resources
+	var n int64
+	found := false
+	items, _ := content["content"].([]map[string]interface{})
+	for _, item := range items {
+		if resource, ok := item["resource"].(map[string]interface{}); ok {
+			if blob, ok := resource["blob"].(string); ok {
+				n += BlobSize(blob)
+				found = true
+			}
+		}
+	}
+	return n, found
+}
+
+// BlobSize returns the number of bytes encoded by a base64 blob
+func BlobSize(blob string) int64 {
+	n := int64(len(blob)) / 4 * 3
+	for i := len(blob) - 1; i >= 0 && blob[i] == '='; i-- {
+		n--
+	}
+	return n
+}
+
+// ResultPaths returns the resource URIs of the files a tool result read or
+// returned, each once and in result order
+func ResultPaths(result interface{}) []string {
+	content, ok := result.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var uris []string
+	seen := make(map[string]bool)
+	add := func(root, path string) {
+		uri := ResourceURI(root, path)
+		if !seen[uri] {
+			seen[uri] = true
+			uris = append(uris, uri)
+		}
+	}
+
+	switch structured := content["structuredContent"].(type) {
+	case *FindResult:
+		for _, f := range structured.Files {
+			add(f.Root, f.Path)
+		}
+	case *GrepResult:
+		for _, m := range structured.Matches {
+			add(m.Root, m.Path)
+		}
+	case *FileContent:
+		add(structured.Root, structured.Path)
+	}
+
+	// Binary files are returned as embedded resources
+	items, _ := content["content"].([]map[string]interface{})
+	for _, item := range items {
+		if resource, ok := item["resource"].(map[string]interface{}); ok {
+			if uri, ok := resource["uri"].(string); ok && !seen[uri] {
+				seen[uri] = true
+				uris = append(uris, uri)
+			}
+		}
+	}
+
+	return uris
+}
+
+// toolResult wraps text and the structured result in an MCP tool result
+func toolResult(text string, structured interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": text,
+			},
+		},
+		"structuredContent": structured,
+	}
+}
+
+// StatsResult wraps index statistics in a result shaped like that of the
+// rebuild_index tool
+func StatsResult(stats []IndexStats) map[string]interface{} {
+	return toolResult(formatStats(stats), map[string]interface{}{"roots": stats})
+}
+
+// formatFiles renders a find result one file per line
+func formatFiles(result *FindResult) string {
+	var b strings.Builder
+	for _, f := range result.Files {
+		fmt.Fprintf(&b, "%s/%s\n", f.Root, f.Path)
+	}
+	if len(result.Files) == 0 {
+		b.WriteString("No files found\n")
+	}
+	if result.Truncated {
+		b.WriteString("(results truncated)\n")
+	}
+	return b.String()
+}
+
+// formatMatches renders a grep result in file:line: text form
+func formatMatches(result *GrepResult) string {
+	var b strings.Builder
+	for _, m := range result.Matches {
+		for i, line := range m.Before {
+			fmt.Fprintf(&b, "%s/%s-%d- %s\n", m.Root, m.Path, m.Line-len(m.Before)+i, line)
+		}
+		fmt.Fprintf(&b, "%s/%s:%d: %s\n", m.Root, m.Path, m.Line, m.Text)
+		for i, line := range m.After {
+			fmt.Fprintf(&b, "%s/%s-%d- %s\n", m.Root, m.Path, m.Line+i+1, line)
+		}
+	}
+	if len(result.Matches) == 0 {
+		b.WriteString("No matches found\n")
+	}
+	if result.Truncated {
+		b.WriteString("(results truncated)\n")
+	}
+	return b.String()
+}
+
+// formatStats renders index statistics one root per line
+func formatStats(stats []IndexStats) string {
+	var b strings.Builder
+	for _, s := range stats {
+		fmt.Fprintf(&b, "%s: %d files, %d bytes, built %s\n", s.Root, s.Files, s.Bytes, s.BuiltAt.Format("2006-01-02T15:04:05Z"))
+	}
+	return b.String()
+}
+
+// stringArg returns a string argument or "" if absent
+func stringArg(args map[string]interface{}, name string) string {
+	s, _ := args[name].(string)
+	return s
+}
+
+// boolArg returns a boolean argument or false if absent
+func boolArg(args map[string]interface{}, name string) bool {
+	b, _ := args[name].(bool)
+	return b
+}
+
+// intArg returns an integer argument or 0 if absent; JSON numbers decode as float64
+func intArg(args map[string]interface{}, name string) int {
+	switch v := args[name].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+// stringSliceArg returns a string array argument, ignoring non-string items
+func stringSliceArg(args map[string]interface{}, name string) []string {
+	items, _ := args[name].([]interface{})
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
@@ -0,0 +1,224 @@
+package filesearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/trace"
+)
+
+// indexVersion is bumped when the on-disk index format changes
+const indexVersion = 1
+
+// RootIndex is the indexed file list of a single root
+type RootIndex struct {
+	Root    string     `json:"root"`
+	Path    string     `json:"path"`
+	BuiltAt time.Time  `json:"builtAt"`
+	Files   []FileInfo `json:"files"`
+}
+
+// IndexStats summarises the index of a single root
+type IndexStats struct {
+	Root    string    `json:"root"`
+	Files   int       `json:"files"`
+	Bytes   int64     `json:"bytes"`
+	BuiltAt time.Time `json:"builtAt"`
+}
+
+// indexFile is the on-disk representation of an Index
+type indexFile struct {
+	Version int          `json:"version"`
+	Roots   []*RootIndex `json:"roots"`
+}
+
+// Index caches the file lists of roots so that name searches do not have to
+// walk the filesystem. It is persisted as JSON when a path is configured.
+type Index struct {
+	path string
+
+	mu    sync.RWMutex
+	roots map[string]*RootIndex
+}
+
+// NewIndex creates an empty index stored at path. An empty path keeps the
+// index in memory only.
+func NewIndex(path string) *Index {
+	return &Index{path: path, roots: make(map[string]*RootIndex)}
+}
+
+// Path returns where the index is persisted
+func (ix *Index) Path() string {
+	return ix.path
+}
+
+// Load reads the index from disk. A missing file leaves the index empty.
+func (ix *Index) Load() error {
+	if ix.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(ix.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+
+	var file indexFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse index %s: %w", ix.path, err)
+	}
+	if file.Version != indexVersion {
+		// Stale format; it will be rebuilt
+		return nil
+	}
+
+	roots := make(map[string]*RootIndex, len(file.Roots))
+	for _, ri := range file.Roots {
+		roots[ri.Root] = ri
+	}
+
+	ix.mu.Lock()
+	ix.roots = roots
+	ix.mu.Unlock()
+	return nil
+}
+
+// Save writes the index to disk atomically
+func (ix *Index) Save() error {
+	if ix.path == "" {
+		return nil
+	}
+
+	ix.mu.RLock()
+	file := indexFile{Version: indexVersion}
+	for _, ri := range ix.roots {
+		file.Roots = append(file.Roots, ri)
+	}
+	ix.mu.RUnlock()
+	sort.Slice(file.Roots, func(i, j int) bool { return file.Roots[i].Root < file.Roots[j].Root })
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ix.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	tmp := ix.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return os.Rename(tmp, ix.path)
+}
+
+// Files returns the indexed files of root if the index covers it
+func (ix *Index) Files(root Root) ([]FileInfo, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	ri, ok := ix.roots[root.Name]
+	if !ok || ri.Path != root.Path {
+		return nil, false
+	}
+	return ri.Files, true
+}
+
+// Set replaces the index of a single root
+func (ix *Index) Set(ri *RootIndex) {
+	ix.mu.Lock()
+	ix.roots[ri.Root] = ri
+	ix.mu.Unlock()
+}
+
+// Remove drops the index of the named root
+func (ix *Index) Remove(name string) {
+	ix.mu.Lock()
+	delete(ix.roots, name)
+	ix.mu.Unlock()
+}
+
+// Stats summarises every indexed root, sorted by name
+func (ix *Index) Stats() []IndexStats {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	stats := make([]IndexStats, 0, len(ix.roots))
+	for _, ri := range ix.roots {
+		s := IndexStats{Root: ri.Root, Files: len(ri.Files), BuiltAt: ri.BuiltAt}
+		for _, f := range ri.Files {
+			s.Bytes += f.Size
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Root < stats[j].Root })
+	return stats
+}
+
+// BuildIndex walks the selected roots (every root if name is empty),
+// replaces their index entries and persists the index
+func (e *Engine) BuildIndex(ctx context.Context, name string) ([]IndexStats, error) {
+	if e.index == nil {
+		return nil, fmt.Errorf("indexing is not enabled")
+	}
+
+	ctx, span := trace.Start(ctx, "filesearch.build_index")
+	defer span.End()
+
+	roots, err := e.registry.Select(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, root := range roots {
+		ri := &RootIndex{Root: root.Name, Path: root.Path, Files: []FileInfo{}}
+		err := e.Walk(ctx, root, func(file FileInfo) error {
+			ri.Files = append(ri.Files, file)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to index root %s: %w", root.Name, err)
+		}
+		ri.BuiltAt = e.clock.Now().UTC()
+		e.index.Set(ri)
+	}
+
+	if err := e.index.Save(); err != nil {
+		return nil, err
+	}
+
+	stats := e.index.Stats()
+	if name == "" {
+		return stats, nil
+	}
+	for _, s := range stats {
+		if s.Root == name {
+			return []IndexStats{s}, nil
+		}
+	}
+	return nil, nil
+}
+
+// refreshIndex replaces the index of root with files found by a walk and
+// persists the index. It does nothing unless the index already covers root.
+func (e *Engine) refreshIndex(root Root, files []FileInfo) error {
+	if e.index == nil {
+		return nil
+	}
+	if _, ok := e.index.Files(root); !ok {
+		return nil
+	}
+	e.index.Set(&RootIndex{Root: root.Name, Path: root.Path, BuiltAt: e.clock.Now().UTC(), Files: files})
+	return e.index.Save()
+}
@@ -0,0 +1,25 @@
+package filesearch
+
+import "context"
+
+// ProgressFunc receives the number of files a search has examined so far
+// and the file it is examining
+type ProgressFunc func(files int, current string)
+
+// progressKey is the context key of a search's ProgressFunc
+type progressKey struct{}
+
+// WithProgress returns a context that reports the progress of searches run
+// with it to fn. fn is called from the searching goroutine for every file
+// examined and should return quickly.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// progressReporter returns the ProgressFunc on ctx, or one that does nothing
+func progressReporter(ctx context.Context) ProgressFunc {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && fn != nil {
+		return fn
+	}
+	return func(int, string) {}
+}
@@ -1 +1,230 @@
-package filesearch
\ No newline at end of file
+// Package filesearch implements the file search engine behind the MCP tools.
+// It keeps a registry of named search roots, walks them honouring ignore
+// patterns and size limits, and answers file name and content queries,
+// optionally backed by a persisted index of file metadata.
+package filesearch
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownRoot is returned for operations on a root that is not
+// registered
+var ErrUnknownRoot = errors.New("unknown root")
+
+// ErrInvalidPath is returned for paths that leave their directory or are
+// not in clean form
+var ErrInvalidPath = errors.New("invalid path")
+
+// Root is a named directory that can be searched. When FS is set, the
+// root's files are read from it instead of the operating system and Path
+// only labels the root.
+type Root struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	FS   fs.FS  `json:"-"`
+}
+
+// FileSystem returns the filesystem holding the root's files, with the root
+// directory at "."
+func (root Root) FileSystem() fs.FS {
+	if root.FS != nil {
+		return root.FS
+	}
+	return os.DirFS(root.Path)
+}
+
+// Registry holds the set of search roots. It is safe for concurrent use.
+type Registry struct {
+	mu    sync.RWMutex
+	roots map[string]Root
+}
+
+// NewRegistry creates a registry containing the given roots
+func NewRegistry(roots []Root) (*Registry, error) {
+	r := &Registry{roots: make(map[string]Root)}
+	for _, root := range roots {
+		if err := r.Add(root); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// ValidateRoot checks that root has a usable name and an existing directory
+// and returns it with an absolute, cleaned path. A root with its own FS
+// keeps its path as given.
+func ValidateRoot(root Root) (Root, error) {
+	if root.Name == "" || strings.ContainsAny(root.Name, "/\\") {
+		return root, fmt.Errorf("invalid root name %q", root.Name)
+	}
+
+	if root.FS != nil {
+		info, err := fs.Stat(root.FS, ".")
+		if err != nil {
+			return root, fmt.Errorf("root %s: %w", root.Name, err)
+		}
+		if !info.IsDir() {
+			return root, fmt.Errorf("root %s: not a directory", root.Name)
+		}
+		return root, nil
+	}
+
+	abs, err := filepath.Abs(root.Path)
+	if err != nil {
+		return root, fmt.Errorf("root %s: %w", root.Name, err)
+	}
+
+	info, err := os.Stat(abs)
+	if err != nil {
+		return root, fmt.Errorf("root %s: %w", root.Name, err)
+	}
+	if !info.IsDir() {
+		return root, fmt.Errorf("root %s: %s is not a directory", root.Name, abs)
+	}
+
+	root.Path = abs
+	return root, nil
+}
+
+// Add registers a root, replacing any existing root with the same name
+func (r *Registry) Add(root Root) error {
+	root, err := ValidateRoot(root)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.roots[root.Name] = root
+	r.mu.Unlock()
+	return nil
+}
+
+// Remove unregisters the named root. It reports whether the root existed.
+func (r *Registry) Remove(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.roots[name]
+	delete(r.roots, name)
+	return ok
+}
+
+// Get returns the named root
+func (r *Registry) Get(name string) (Root, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	root, ok := r.roots[name]
+	return root, ok
+}
+
+// List returns all roots sorted by name
+func (r *Registry) List() []Root {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	roots := make([]Root, 0, len(r.roots))
+	for _, root := range r.roots {
+		roots = append(roots, root)
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].Name < roots[j].Name })
+	return roots
+}
+
+// Select returns the named root, or every root if name is empty
+func (r *Registry) Select(name string) ([]Root, error) {
+	if name == "" {
+		return r.List(), nil
+	}
+	root, ok := r.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRoot, name)
+	}
+	return []Root{root}, nil
+}
+
+// Resolve maps a slash-separated path relative to the named root to an
+// absolute filesystem path, refusing paths that escape the root, including
+// through symbolic links. It fails for roots with their own FS, which have
+// no such path.
+func (r *Registry) Resolve(rootName, rel string) (string, error) {
+	root, ok := r.Get(rootName)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownRoot, rootName)
+	}
+	if root.FS != nil {
+		return "", fmt.Errorf("root %s is not on the operating system's filesystem", rootName)
+	}
+
+	cleaned := filepath.Clean("/" + filepath.FromSlash(rel))
+	abs := filepath.Join(root.Path, cleaned)
+
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", err
+	}
+	rootResolved, err := filepath.EvalSymlinks(root.Path)
+	if err != nil {
+		return "", err
+	}
+
+	if resolved != rootResolved && !strings.HasPrefix(resolved, rootResolved+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s escapes root %s", ErrInvalidPath, rel, rootName)
+	}
+
+	return abs, nil
+}
+
+// CleanPath returns the name within a root that rel refers to: slash
+// separated, without leading or trailing slashes, and "" for the root
+// itself. Paths naming a parent directory, or that cleaning would change,
+// such as "a//b" or "a/./b", are refused so that access checks and reads
+// see the same name.
+func CleanPath(rel string) (string, error) {
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(rel), "/"), "/")
+	if name == "" || name == "." {
+		return "", nil
+	}
+	for _, segment := range strings.Split(name, "/") {
+		switch segment {
+		case "..":
+			return "", fmt.Errorf("%w: %s names a parent directory", ErrInvalidPath, rel)
+		case "", ".":
+			return "", fmt.Errorf("%w: %s is not a clean path", ErrInvalidPath, rel)
+		}
+	}
+	return name, nil
+}
+
+// locate maps a path relative to the named root to the root and the name
+// of the path in the root's FileSystem, refusing paths that escape the root
+// or that CleanPath refuses
+func (r *Registry) locate(rootName, rel string) (Root, string, error) {
+	root, ok := r.Get(rootName)
+	if !ok {
+		return root, "", fmt.Errorf("%w: %s", ErrUnknownRoot, rootName)
+	}
+	name, err := CleanPath(rel)
+	if err != nil {
+		return root, "", err
+	}
+	if root.FS == nil {
+		// Symbolic links within the directory may point outside it
+		if _, err := r.Resolve(rootName, name); err != nil {
+			return root, "", err
+		}
+	}
+
+	if name == "" {
+		name = "."
+	}
+	return root, name, nil
+}
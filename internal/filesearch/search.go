@@ -0,0 +1,644 @@
+package filesearch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/clock"
+	"github.com/aawadall/go-mcp-filesearch/internal/glob"
+	"github.com/aawadall/go-mcp-filesearch/internal/trace"
+)
+
+// Defaults for search options
+const (
+	DefaultMaxFileSize = 1 << 20
+	DefaultMaxResults  = 200
+	MaxContextLines    = 10
+)
+
+// DefaultIgnore lists directories that are never worth searching
+var DefaultIgnore = []string{".git", ".hg", ".svn", "node_modules"}
+
+// binarySniffLen is how much of a file is inspected to detect binary content
+const binarySniffLen = 8000
+
+// Options controls what the engine searches and how much it returns.
+// Ignore patterns without a "/" match any file or directory base name;
+// patterns with a "/" match the slash-separated path relative to the root.
+type Options struct {
+	Ignore      []string
+	MaxFileSize int64
+	MaxResults  int
+}
+
+// PathFilter reports whether a path within a root may be returned to the
+// caller. A nil filter allows everything.
+type PathFilter func(root, path string) bool
+
+// ErrNotPermitted is returned when the caller may not access a path or
+// tool. Errors wrapping it read "<subject> not permitted".
+var ErrNotPermitted = errors.New("not permitted")
+
+// ErrInvalidArgument is returned for searches and reads the caller asked
+// for wrongly, such as an empty query or a path naming a directory
+var ErrInvalidArgument = errors.New("invalid argument")
+
+// FileInfo describes a file or directory found in a root
+type FileInfo struct {
+	Root    string    `json:"root"`
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	IsDir   bool      `json:"isDir,omitempty"`
+}
+
+// FindQuery selects files by name. Pattern is a glob matched against the
+// base name, or against the relative path if it contains a "/".
+type FindQuery struct {
+	Root       string
+	Pattern    string
+	Extensions []string
+	Limit      int
+	Filter     PathFilter
+}
+
+// FindResult is the outcome of a Find
+type FindResult struct {
+	Files     []FileInfo `json:"files"`
+	Truncated bool       `json:"truncated"`
+}
+
+// GrepQuery selects lines of file content. Query is a literal string unless
+// Regex is set. Include restricts the search to files matching a glob, and
+// Extensions to files with one of the given extensions.
+type GrepQuery struct {
+	Root          string
+	Query         string
+	Regex         bool
+	CaseSensitive bool
+	Include       string
+	Extensions    []string
+	ContextLines  int
+	Limit         int
+	Filter        PathFilter
+}
+
+// GrepMatch is a single matching line with optional surrounding context
+type GrepMatch struct {
+	Root   string   `json:"root"`
+	Path   string   `json:"path"`
+	Line   int      `json:"line"`
+	Text   string   `json:"text"`
+	Before []string `json:"before,omitempty"`
+	After  []string `json:"after,omitempty"`
+}
+
+// GrepResult is the outcome of a Grep
+type GrepResult struct {
+	Matches      []GrepMatch `json:"matches"`
+	FilesScanned int         `json:"filesScanned"`
+	BytesScanned int64       `json:"bytesScanned"`
+	Truncated    bool        `json:"truncated"`
+}
+
+// FileContent is the content of a single file
+type FileContent struct {
+	Root     string `json:"root"`
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Data     []byte `json:"data,omitempty"`
+}
+
+// IsBinary reports whether the content could not be returned as text
+func (c *FileContent) IsBinary() bool {
+	return c.Data != nil
+}
+
+// Engine answers file name and content queries over the roots in a registry
+type Engine struct {
+	registry *Registry
+	index    *Index
+	opts     Options
+	clock    clock.Clock
+}
+
+// NewEngine creates a search engine over registry. index may be nil, in
+// which case every query walks the filesystem.
+func NewEngine(registry *Registry, index *Index, opts Options) *Engine {
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = DefaultMaxFileSize
+	}
+	if opts.MaxResults <= 0 {
+		opts.MaxResults = DefaultMaxResults
+	}
+	return &Engine{registry: registry, index: index, opts: opts, clock: clock.System}
+}
+
+// SetClock replaces the clock that dates index builds. It must be called
+// before the engine is used.
+func (e *Engine) SetClock(c clock.Clock) {
+	e.clock = c
+}
+
+// Registry returns the roots searched by the engine
+func (e *Engine) Registry() *Registry {
+	return e.registry
+}
+
+// Index returns the engine's file index, which may be nil
+func (e *Engine) Index() *Index {
+	return e.index
+}
+
+// Options returns the engine's effective options
+func (e *Engine) Options() Options {
+	return e.opts
+}
+
+// ignored reports whether a file or directory is excluded from searches
+func (e *Engine) ignored(rel string) bool {
+	base := path.Base(rel)
+	for _, pattern := range e.opts.Ignore {
+		if strings.Contains(pattern, "/") {
+			if glob.Match(pattern, rel) {
+				return true
+			}
+		} else if glob.Match(pattern, base) {
+			return true
+		}
+	}
+	return false
+}
+
+// limit returns the effective result limit for a query
+func (e *Engine) limit(requested int) int {
+	if requested <= 0 || requested > e.opts.MaxResults {
+		return e.opts.MaxResults
+	}
+	return requested
+}
+
+// Walk calls fn for every file in root that is not ignored, in lexical order
+func (e *Engine) Walk(ctx context.Context, root Root, fn func(FileInfo) error) error {
+	ctx, span := trace.Start(ctx, "filesearch.walk")
+	defer span.End()
+	span.SetAttr("filesearch.root", root.Name)
+	visited := 0
+	defer func() { span.SetAttr("filesearch.files", visited) }()
+
+	return fs.WalkDir(root.FileSystem(), ".", func(rel string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			// Unreadable entries are skipped rather than failing the search
+			if d != nil && d.IsDir() && rel != "." {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if rel == "." {
+			return nil
+		}
+
+		if e.ignored(rel) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		visited++
+		return fn(FileInfo{Root: root.Name, Path: rel, Size: info.Size(), ModTime: info.ModTime()})
+	})
+}
+
+// files lists the files of root from the index if it covers the root, or
+// by walking the filesystem otherwise
+func (e *Engine) files(ctx context.Context, root Root, fn func(FileInfo) error) error {
+	if e.index != nil {
+		if files, ok := e.index.Files(root); ok {
+			_, span := trace.Start(ctx, "filesearch.index")
+			defer span.End()
+			span.SetAttr("filesearch.root", root.Name)
+			span.SetAttr("filesearch.files", len(files))
+			for _, file := range files {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				if err := fn(file); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return e.Walk(ctx, root, fn)
+}
+
+// errLimitReached stops a walk once enough results have been collected
+var errLimitReached = fmt.Errorf("result limit reached")
+
+// matchesName reports whether rel matches a name pattern as used by FindQuery
+func matchesName(pattern, rel string) bool {
+	if pattern == "" {
+		return true
+	}
+	if strings.Contains(pattern, "/") {
+		return glob.Match(pattern, rel)
+	}
+	return glob.Match(pattern, path.Base(rel))
+}
+
+// hasExtension reports whether rel ends in one of the given extensions
+func hasExtension(extensions []string, rel string) bool {
+	if len(extensions) == 0 {
+		return true
+	}
+	ext := path.Ext(rel)
+	for _, want := range extensions {
+		if !strings.HasPrefix(want, ".") {
+			want = "." + want
+		}
+		if strings.EqualFold(ext, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns files whose names match the query
+func (e *Engine) Find(ctx context.Context, q FindQuery) (*FindResult, error) {
+	ctx, span := trace.Start(ctx, "filesearch.find")
+	defer span.End()
+	span.SetAttr("filesearch.pattern", q.Pattern)
+
+	roots, err := e.registry.Select(q.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := e.limit(q.Limit)
+	result := &FindResult{Files: []FileInfo{}}
+	progress := progressReporter(ctx)
+	examined := 0
+
+	for _, root := range roots {
+		err := e.files(ctx, root, func(file FileInfo) error {
+			examined++
+			progress(examined, file.Root+"/"+file.Path)
+			if !matchesName(q.Pattern, file.Path) || !hasExtension(q.Extensions, file.Path) {
+				return nil
+			}
+			if q.Filter != nil && !q.Filter(file.Root, file.Path) {
+				return nil
+			}
+			if len(result.Files) >= limit {
+				result.Truncated = true
+				return errLimitReached
+			}
+			result.Files = append(result.Files, file)
+			return nil
+		})
+		if err == errLimitReached {
+			break
+		}
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+	}
+
+	span.SetAttr("filesearch.results", len(result.Files))
+	span.SetAttr("filesearch.truncated", result.Truncated)
+	return result, nil
+}
+
+// lineMatcher builds the predicate used to test each line in a Grep
+func lineMatcher(q GrepQuery) (func(string) bool, error) {
+	if q.Query == "" {
+		return nil, fmt.Errorf("%w: query required", ErrInvalidArgument)
+	}
+
+	if q.Regex {
+		expr := q.Query
+		if !q.CaseSensitive {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid regular expression: %w", ErrInvalidArgument, err)
+		}
+		return re.MatchString, nil
+	}
+
+	if q.CaseSensitive {
+		return func(line string) bool { return strings.Contains(line, q.Query) }, nil
+	}
+	needle := strings.ToLower(q.Query)
+	return func(line string) bool { return strings.Contains(strings.ToLower(line), needle) }, nil
+}
+
+// Grep returns lines of file content matching the query
+func (e *Engine) Grep(ctx context.Context, q GrepQuery) (*GrepResult, error) {
+	ctx, span := trace.Start(ctx, "filesearch.grep")
+	defer span.End()
+	span.SetAttr("filesearch.regex", q.Regex)
+
+	match, err := lineMatcher(q)
+	if err != nil {
+		return nil, err
+	}
+
+	roots, err := e.registry.Select(q.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	contextLines := q.ContextLines
+	if contextLines < 0 {
+		contextLines = 0
+	}
+	if contextLines > MaxContextLines {
+		contextLines = MaxContextLines
+	}
+
+	limit := e.limit(q.Limit)
+	result := &GrepResult{Matches: []GrepMatch{}}
+	progress := progressReporter(ctx)
+	examined := 0
+
+	for _, root := range roots {
+		err := e.files(ctx, root, func(file FileInfo) error {
+			examined++
+			progress(examined, file.Root+"/"+file.Path)
+			if file.Size > e.opts.MaxFileSize || !matchesName(q.Include, file.Path) || !hasExtension(q.Extensions, file.Path) {
+				return nil
+			}
+			if q.Filter != nil && !q.Filter(file.Root, file.Path) {
+				return nil
+			}
+
+			_, fileSpan := trace.Start(ctx, "filesearch.scan")
+			fileSpan.SetAttr("filesearch.path", file.Path)
+			matches, scanned, err := e.grepFile(root.FileSystem(), file, match, contextLines, limit-len(result.Matches))
+			fileSpan.SetAttr("filesearch.bytes", scanned)
+			fileSpan.RecordError(err)
+			fileSpan.End()
+			if err != nil {
+				return nil
+			}
+
+			result.FilesScanned++
+			result.BytesScanned += scanned
+			result.Matches = append(result.Matches, matches...)
+			if len(result.Matches) >= limit {
+				result.Truncated = true
+				return errLimitReached
+			}
+			return nil
+		})
+		if err == errLimitReached {
+			break
+		}
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+	}
+
+	span.SetAttr("filesearch.files_scanned", result.FilesScanned)
+	span.SetAttr("filesearch.bytes_scanned", result.BytesScanned)
+	span.SetAttr("filesearch.results", len(result.Matches))
+	span.SetAttr("filesearch.truncated", result.Truncated)
+	return result, nil
+}
+
+// grepFile scans a single file of fsys for matching lines, returning at
+// most max matches and the number of bytes read. Binary files yield no
+// matches.
+func (e *Engine) grepFile(fsys fs.FS, file FileInfo, match func(string) bool, contextLines, max int) ([]GrepMatch, int64, error) {
+	f, err := fsys.Open(file.Path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	head, _ := reader.Peek(binarySniffLen)
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, int64(len(head)), nil
+	}
+
+	var (
+		matches []GrepMatch
+		before  []string
+		pending []int // indexes of matches still collecting trailing context
+		scanned int64
+	)
+
+	scanner := bufio.NewScanner(reader)
+	// A line may be as long as the largest file searched
+	maxLine := int(e.opts.MaxFileSize)
+	scanner.Buffer(make([]byte, min(64*1024, maxLine)), maxLine)
+
+	lineNo := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNo++
+		scanned += int64(len(line)) + 1
+
+		// Feed trailing context to earlier matches
+		stillPending := pending[:0]
+		for _, i := range pending {
+			matches[i].After = append(matches[i].After, line)
+			if len(matches[i].After) < contextLines {
+				stillPending = append(stillPending, i)
+			}
+		}
+		pending = stillPending
+
+		if len(matches) < max && match(line) {
+			m := GrepMatch{Root: file.Root, Path: file.Path, Line: lineNo, Text: line}
+			if contextLines > 0 {
+				m.Before = append([]string(nil), before...)
+				pending = append(pending, len(matches))
+			}
+			matches = append(matches, m)
+		} else if len(matches) >= max && len(pending) == 0 {
+			break
+		}
+
+		if contextLines > 0 {
+			before = append(before, line)
+			if len(before) > contextLines {
+				before = before[1:]
+			}
+		}
+	}
+
+	return matches, scanned, scanner.Err()
+}
+
+// pathError describes a filesystem error in terms of the root-relative path
+// so that absolute server paths are not revealed to callers
+func pathError(rel string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return &relPathError{rel: rel, msg: "no such file or directory", err: err}
+	}
+	if errors.Is(err, fs.ErrPermission) {
+		return &relPathError{rel: rel, msg: "permission denied", err: err}
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return &relPathError{rel: rel, msg: pathErr.Err.Error(), err: err}
+	}
+	return err
+}
+
+// relPathError reports a filesystem error against the root-relative path,
+// keeping the cause so that callers can test it with errors.Is
+type relPathError struct {
+	rel string
+	msg string
+	err error
+}
+
+func (e *relPathError) Error() string {
+	return e.rel + ": " + e.msg
+}
+
+func (e *relPathError) Unwrap() error {
+	return e.err
+}
+
+// Read returns the contents of a file within a root
+func (e *Engine) Read(ctx context.Context, rootName, rel string) (content *FileContent, err error) {
+	_, span := trace.Start(ctx, "filesearch.read")
+	defer func() {
+		if content != nil {
+			span.SetAttr("filesearch.bytes", content.Size)
+		}
+		span.RecordError(err)
+		span.End()
+	}()
+	span.SetAttr("filesearch.root", rootName)
+	span.SetAttr("filesearch.path", rel)
+
+	root, name, err := e.registry.locate(rootName, rel)
+	if err != nil {
+		return nil, pathError(rel, err)
+	}
+	fsys := root.FileSystem()
+
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, pathError(rel, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%w: %s is a directory", ErrInvalidArgument, rel)
+	}
+	if info.Size() > e.opts.MaxFileSize {
+		return nil, fmt.Errorf("%w: %s is %d bytes, larger than the %d byte limit", ErrInvalidArgument, rel, info.Size(), e.opts.MaxFileSize)
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, pathError(rel, err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, e.opts.MaxFileSize))
+	if err != nil {
+		return nil, pathError(rel, err)
+	}
+
+	content = &FileContent{
+		Root:     rootName,
+		Path:     name,
+		Size:     int64(len(data)),
+		MimeType: mimeType(rel),
+	}
+
+	if utf8.Valid(data) && bytes.IndexByte(data, 0) < 0 {
+		content.Text = string(data)
+	} else {
+		content.Data = data
+		if content.MimeType == "text/plain" {
+			content.MimeType = "application/octet-stream"
+		}
+	}
+
+	return content, nil
+}
+
+// List returns the entries of a directory within a root, skipping ignored ones
+func (e *Engine) List(ctx context.Context, rootName, rel string) ([]FileInfo, error) {
+	_, span := trace.Start(ctx, "filesearch.list")
+	defer span.End()
+	span.SetAttr("filesearch.root", rootName)
+	span.SetAttr("filesearch.path", rel)
+
+	root, name, err := e.registry.locate(rootName, rel)
+	if err != nil {
+		return nil, pathError(rel, err)
+	}
+
+	entries, err := fs.ReadDir(root.FileSystem(), name)
+	if err != nil {
+		return nil, pathError(rel, err)
+	}
+
+	base := name
+	if base == "." {
+		base = ""
+	}
+	files := make([]FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		entryPath := path.Join(base, entry.Name())
+		if e.ignored(entryPath) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, FileInfo{
+			Root:    rootName,
+			Path:    entryPath,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			IsDir:   entry.IsDir(),
+		})
+	}
+
+	return files, nil
+}
+
+// mimeType guesses a MIME type from the file extension, defaulting to text
+func mimeType(rel string) string {
+	if t := mime.TypeByExtension(path.Ext(rel)); t != "" {
+		return t
+	}
+	return "text/plain"
+}
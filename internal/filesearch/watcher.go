@@ -0,0 +1,221 @@
+package filesearch
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultWatchInterval is how often a Watcher polls its roots unless told
+// otherwise
+const DefaultWatchInterval = 5 * time.Second
+
+// watchQueueSize bounds the changes waiting to be handled. A poll that
+// finds more waits for the handler to catch up.
+const watchQueueSize = 1024
+
+// Kinds of file change reported by a Watcher
+const (
+	ChangeCreated  = "created"
+	ChangeModified = "modified"
+	ChangeRemoved  = "removed"
+)
+
+// Change is a file created, modified or removed in a root between two polls
+type Change struct {
+	Root string `json:"root"`
+	Path string `json:"path"`
+	Op   string `json:"op"`
+}
+
+// WatchConfig configures a Watcher
+type WatchConfig struct {
+	// Interval is the time between polls; zero uses DefaultWatchInterval
+	Interval time.Duration
+	// OnChange receives the changes found by the polls, oldest first, in
+	// batches of whatever has queued up since it last returned
+	OnChange func([]Change)
+	// OnError receives failures to save the index after it was updated
+	OnError func(error)
+}
+
+// Watcher polls the roots of an engine for changed files. Each poll walks
+// every root, brings the index of roots it covers up to date and queues the
+// changes for OnChange, which runs on a goroutine of its own so that a slow
+// handler does not hold up polling until the queue is full.
+type Watcher struct {
+	engine func() *Engine
+	config WatchConfig
+	queue  chan Change
+
+	running atomic.Bool
+
+	// snapshots holds the files of each root found by the latest poll,
+	// keyed by root name. Only the polling goroutine uses it.
+	snapshots map[string]snapshot
+}
+
+// snapshot is the state of a root at one poll
+type snapshot struct {
+	path  string
+	files map[string]FileInfo
+}
+
+// NewWatcher creates a watcher of the roots of the engine returned by
+// engine, which is asked again at every poll so that the watcher follows
+// configuration reloads. engine may return nil while there is nothing to
+// watch.
+func NewWatcher(engine func() *Engine, config WatchConfig) *Watcher {
+	if config.Interval <= 0 {
+		config.Interval = DefaultWatchInterval
+	}
+	return &Watcher{
+		engine:    engine,
+		config:    config,
+		queue:     make(chan Change, watchQueueSize),
+		snapshots: make(map[string]snapshot),
+	}
+}
+
+// Run polls the roots until ctx is done. The first poll of a root records
+// its files without reporting them as changes.
+func (w *Watcher) Run(ctx context.Context) {
+	w.running.Store(true)
+	defer w.running.Store(false)
+
+	var dispatching sync.WaitGroup
+	dispatching.Add(1)
+	go func() {
+		defer dispatching.Done()
+		w.dispatch(ctx)
+	}()
+	defer dispatching.Wait()
+
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+	for {
+		w.poll(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Running reports whether Run is polling
+func (w *Watcher) Running() bool {
+	return w.running.Load()
+}
+
+// QueueDepth returns the number of changes waiting to be handled
+func (w *Watcher) QueueDepth() int {
+	return len(w.queue)
+}
+
+// dispatch passes queued changes to OnChange until ctx is done
+func (w *Watcher) dispatch(ctx context.Context) {
+	for {
+		var batch []Change
+		select {
+		case change := <-w.queue:
+			batch = append(batch, change)
+		case <-ctx.Done():
+			return
+		}
+	drain:
+		for len(batch) < watchQueueSize {
+			select {
+			case change := <-w.queue:
+				batch = append(batch, change)
+			default:
+				break drain
+			}
+		}
+		if w.config.OnChange != nil {
+			w.config.OnChange(batch)
+		}
+	}
+}
+
+// poll walks every root once, updating the index and queueing the changes
+// found since the previous poll
+func (w *Watcher) poll(ctx context.Context) {
+	engine := w.engine()
+	if engine == nil {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, root := range engine.Registry().List() {
+		seen[root.Name] = true
+
+		files := []FileInfo{}
+		current := make(map[string]FileInfo)
+		err := engine.Walk(ctx, root, func(file FileInfo) error {
+			files = append(files, file)
+			current[file.Path] = file
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// An unreadable root is reported by the readiness checks and
+			// retried at the next poll
+			continue
+		}
+
+		previous, known := w.snapshots[root.Name]
+		w.snapshots[root.Name] = snapshot{path: root.Path, files: current}
+		var changes []Change
+		if known && previous.path == root.Path {
+			changes = diffFiles(root.Name, previous.files, current)
+			if len(changes) == 0 {
+				continue
+			}
+		}
+
+		if err := engine.refreshIndex(root, files); err != nil && w.config.OnError != nil {
+			w.config.OnError(err)
+		}
+		for _, change := range changes {
+			select {
+			case w.queue <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	// Forget roots that were removed, so that they start afresh if added
+	// back
+	for name := range w.snapshots {
+		if !seen[name] {
+			delete(w.snapshots, name)
+		}
+	}
+}
+
+// diffFiles lists the changes between two polls of a root, by path
+func diffFiles(root string, before, after map[string]FileInfo) []Change {
+	var changes []Change
+	for path, file := range after {
+		old, ok := before[path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Root: root, Path: path, Op: ChangeCreated})
+		case old.Size != file.Size || !old.ModTime.Equal(file.ModTime):
+			changes = append(changes, Change{Root: root, Path: path, Op: ChangeModified})
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changes = append(changes, Change{Root: root, Path: path, Op: ChangeRemoved})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
@@ -0,0 +1,53 @@
+// Package glob implements the glob patterns used in permissions and
+// policies. A "*" matches any run of characters except "/", "**" matches
+// any run of characters including "/", and "?" matches a single character
+// other than "/".
+package glob
+
+// Match reports whether name matches pattern
+func Match(pattern, name string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			// "**" may cross path separators, "*" may not
+			deep := len(pattern) > 1 && pattern[1] == '*'
+			if deep {
+				pattern = pattern[2:]
+			} else {
+				pattern = pattern[1:]
+			}
+
+			for i := 0; i <= len(name); i++ {
+				if Match(pattern, name[i:]) {
+					return true
+				}
+				if i < len(name) && name[i] == '/' && !deep {
+					return false
+				}
+			}
+			return false
+		case '?':
+			if len(name) == 0 || name[0] == '/' {
+				return false
+			}
+		default:
+			if len(name) == 0 || name[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// MatchAny reports whether name matches any of the given patterns
+func MatchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if Match(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -15,10 +15,36 @@ const (
 	ServerName         = "simple-mcp-server"
 )
 
+// MCP notification methods sent by the server
+const (
+	NotificationToolsListChanged     = "notifications/tools/list_changed"
+	NotificationResourcesListChanged = "notifications/resources/list_changed"
+	NotificationResourceUpdated      = "notifications/resources/updated"
+	NotificationMessage              = "notifications/message"
+	NotificationProgress             = "notifications/progress"
+)
+
+// NotificationCancelled is sent by a client that no longer wants the
+// response to one of its requests
+const NotificationCancelled = "notifications/cancelled"
+
+// NotificationInitialized is sent by a client once initialize has
+// succeeded, before its other requests
+const NotificationInitialized = "notifications/initialized"
+
 // JSON-RPC 2.0 standard error codes
 const (
 	ErrCodeMethodNotFound = -32601 // Method not found
 	ErrCodeParseError     = -32700 // Parse error
+	ErrCodeInvalidRequest = -32600 // Invalid request
+	ErrCodeInvalidParams  = -32602 // Invalid params
+	ErrCodeInternalError  = -32603 // Internal error
+)
+
+// Server-defined error codes (JSON-RPC reserves -32000 to -32099)
+const (
+	ErrCodeRateLimited = -32029 // Rate limit or quota exceeded
+	ErrCodeTimeout     = -32030 // Request exceeded its time limit
 )
 
 // JSON-RPC 2.0 structures for request/response communication
@@ -39,6 +65,14 @@ type JSONRPCResponse struct {
 	Error   *JSONRPCError `json:"error,omitempty"`
 }
 
+// JSONRPCNotification represents a JSON-RPC 2.0 notification, a message
+// without an ID that expects no response
+type JSONRPCNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
 // JSONRPCError represents a JSON-RPC 2.0 error object
 type JSONRPCError struct {
 	Code    int         `json:"code"`
@@ -72,6 +106,37 @@ type ServerCapabilities struct {
 	Resources map[string]interface{} `json:"resources,omitempty"`
 	Tools     map[string]interface{} `json:"tools,omitempty"`
 	Prompts   map[string]interface{} `json:"prompts,omitempty"`
+	Logging   *struct{}              `json:"logging,omitempty"`
+}
+
+// LoggingMessageParams are the parameters of a notifications/message log
+// message sent to clients that enabled logging with logging/setLevel
+type LoggingMessageParams struct {
+	Level  string      `json:"level"`
+	Logger string      `json:"logger,omitempty"`
+	Data   interface{} `json:"data"`
+}
+
+// ProgressParams are the parameters of a notifications/progress message
+// sent while handling a request whose _meta carried a progressToken
+type ProgressParams struct {
+	ProgressToken interface{} `json:"progressToken"`
+	Progress      float64     `json:"progress"`
+	Total         float64     `json:"total,omitempty"`
+	Message       string      `json:"message,omitempty"`
+}
+
+// CancelledParams are the parameters of a notifications/cancelled message
+type CancelledParams struct {
+	RequestID interface{} `json:"requestId"`
+	Reason    string      `json:"reason,omitempty"`
+}
+
+// ResourceUpdatedParams are the parameters of a
+// notifications/resources/updated message sent to clients subscribed to the
+// resource
+type ResourceUpdatedParams struct {
+	URI string `json:"uri"`
 }
 
 // InitializeResult contains the result of the initialize method
@@ -94,4 +159,15 @@ type Tool struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description,omitempty"`
 	InputSchema map[string]interface{} `json:"inputSchema"`
+	Annotations *ToolAnnotations       `json:"annotations,omitempty"`
+}
+
+// ToolAnnotations describes the behaviour of a tool to clients
+type ToolAnnotations struct {
+	ReadOnlyHint bool `json:"readOnlyHint,omitempty"`
+}
+
+// IsReadOnly reports whether the tool is declared not to modify state
+func (t Tool) IsReadOnly() bool {
+	return t.Annotations != nil && t.Annotations.ReadOnlyHint
 }
@@ -0,0 +1,176 @@
+// Package policy decides which principals may call which tools and read
+// which roots and paths. Policies are declared as an ordered list of allow
+// and deny rules; a matching deny rule always wins over an allow rule and
+// requests matched by no rule get the configured default effect.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/glob"
+)
+
+// Rule effects
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// AnonymousPrincipal is the name used for callers that were not authenticated
+const AnonymousPrincipal = "anonymous"
+
+// Rule grants or denies a set of principals access to tools and resources.
+// All pattern lists are glob patterns; an empty Tools or Roots list means the
+// rule does not cover tools or resources respectively, while an empty Paths
+// list covers every path within the matched roots.
+//
+// For allow rules, Write extends the grant to tools that modify state. For
+// deny rules, Write narrows the denial to those tools only.
+type Rule struct {
+	Name       string   `json:"name"`
+	Effect     string   `json:"effect"`
+	Principals []string `json:"principals"`
+	Tools      []string `json:"tools,omitempty"`
+	Roots      []string `json:"roots,omitempty"`
+	Paths      []string `json:"paths,omitempty"`
+	Write      bool     `json:"write,omitempty"`
+}
+
+// Config is the declarative form of a policy
+type Config struct {
+	Default string `json:"default"`
+	Rules   []Rule `json:"rules"`
+}
+
+// Decision is the outcome of a policy evaluation
+type Decision struct {
+	Allowed bool
+	Rule    string
+}
+
+// Engine evaluates access requests against a policy
+type Engine struct {
+	defaultAllow bool
+	rules        []Rule
+}
+
+// New validates the configuration and returns an engine for it. The default
+// effect is deny unless explicitly set to allow.
+func New(cfg Config) (*Engine, error) {
+	engine := &Engine{}
+
+	switch cfg.Default {
+	case "", EffectDeny:
+	case EffectAllow:
+		engine.defaultAllow = true
+	default:
+		return nil, fmt.Errorf("invalid default effect %q", cfg.Default)
+	}
+
+	for i, rule := range cfg.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if rule.Effect == "" {
+			rule.Effect = EffectAllow
+		}
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return nil, fmt.Errorf("%s: invalid effect %q", rule.Name, rule.Effect)
+		}
+		if len(rule.Principals) == 0 {
+			return nil, fmt.Errorf("%s: at least one principal pattern is required", rule.Name)
+		}
+		if len(rule.Tools) == 0 && len(rule.Roots) == 0 {
+			return nil, fmt.Errorf("%s: rule must cover tools or roots", rule.Name)
+		}
+		if len(rule.Paths) > 0 && len(rule.Roots) == 0 {
+			return nil, fmt.Errorf("%s: paths require roots", rule.Name)
+		}
+		engine.rules = append(engine.rules, rule)
+	}
+
+	return engine, nil
+}
+
+// Load reads a policy configuration from a JSON file
+func Load(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse policy %s: %w", path, err)
+	}
+
+	engine, err := New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", path, err)
+	}
+	return engine, nil
+}
+
+// CanCallTool decides whether principal may call the named tool. write
+// reports whether the tool modifies state.
+func (e *Engine) CanCallTool(principal, tool string, write bool) Decision {
+	return e.evaluate(principal, func(rule Rule) bool {
+		if !glob.MatchAny(rule.Tools, tool) {
+			return false
+		}
+		if rule.Effect == EffectAllow {
+			return !write || rule.Write
+		}
+		return write || !rule.Write
+	})
+}
+
+// CanRead decides whether principal may read path within the named root
+func (e *Engine) CanRead(principal, root, path string) Decision {
+	return e.evaluate(principal, func(rule Rule) bool {
+		if !glob.MatchAny(rule.Roots, root) {
+			return false
+		}
+		return len(rule.Paths) == 0 || glob.MatchAny(rule.Paths, path)
+	})
+}
+
+// CanListRoot decides whether principal may see the named root at all, that
+// is whether some path within it could be readable
+func (e *Engine) CanListRoot(principal, root string) Decision {
+	return e.evaluate(principal, func(rule Rule) bool {
+		if !glob.MatchAny(rule.Roots, root) {
+			return false
+		}
+		// A deny limited to some paths does not hide the whole root
+		return rule.Effect == EffectAllow || len(rule.Paths) == 0
+	})
+}
+
+// evaluate applies the rules whose principal patterns match and whose
+// request predicate holds
+func (e *Engine) evaluate(principal string, covers func(Rule) bool) Decision {
+	if principal == "" {
+		principal = AnonymousPrincipal
+	}
+
+	var allowedBy string
+	for _, rule := range e.rules {
+		if !glob.MatchAny(rule.Principals, principal) || !covers(rule) {
+			continue
+		}
+		if rule.Effect == EffectDeny {
+			return Decision{Allowed: false, Rule: rule.Name}
+		}
+		if allowedBy == "" {
+			allowedBy = rule.Name
+		}
+	}
+
+	if allowedBy != "" {
+		return Decision{Allowed: true, Rule: allowedBy}
+	}
+	return Decision{Allowed: e.defaultAllow, Rule: "default"}
+}
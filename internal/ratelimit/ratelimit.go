@@ -0,0 +1,165 @@
+// Package ratelimit implements per-client token buckets and daily byte
+// quotas used to protect shared server instances from runaway callers.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// idleBucketTTL is how long an untouched bucket is kept before it is pruned
+const idleBucketTTL = 10 * time.Minute
+
+// bucket is a single token bucket
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// Limiter is a set of token buckets keyed by client. Each bucket refills at
+// Rate tokens per second up to Burst tokens.
+type Limiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+// NewLimiter creates a limiter allowing rate requests per second per key with
+// bursts of up to burst requests. A non-positive rate disables limiting.
+func NewLimiter(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from the bucket for key. If none is available it
+// returns false and how long until one will be.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil || l.rate <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, lastSeen: now}
+		l.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.lastSeen).Seconds()
+	b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+	b.lastSeen = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// prune drops buckets that have been idle long enough to be full again
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < idleBucketTTL {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > idleBucketTTL {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastPrune = now
+}
+
+// Quota tracks bytes consumed per key per UTC day
+type Quota struct {
+	limit int64
+	now   func() time.Time
+
+	mu   sync.Mutex
+	day  string
+	used map[string]int64
+}
+
+// NewQuota creates a quota of limit bytes per key per day. A non-positive
+// limit disables the quota.
+func NewQuota(limit int64) *Quota {
+	return &Quota{
+		limit: limit,
+		now:   time.Now,
+		used:  make(map[string]int64),
+	}
+}
+
+// rollover resets usage when the UTC day changes
+func (q *Quota) rollover(now time.Time) {
+	day := now.UTC().Format("2006-01-02")
+	if day != q.day {
+		q.day = day
+		q.used = make(map[string]int64)
+	}
+}
+
+// Check reports whether key has quota left. If not, it also returns how long
+// until the quota resets.
+func (q *Quota) Check(key string) (bool, time.Duration) {
+	if q == nil || q.limit <= 0 {
+		return true, 0
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.rollover(now)
+
+	if q.used[key] < q.limit {
+		return true, 0
+	}
+
+	midnight := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	return false, midnight.Sub(now)
+}
+
+// Consume records n bytes used by key
+func (q *Quota) Consume(key string, n int64) {
+	if q == nil || q.limit <= 0 {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.rollover(q.now())
+	q.used[key] += n
+}
+
+// Used returns the bytes consumed by key today
+func (q *Quota) Used(key string) int64 {
+	if q == nil {
+		return 0
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.rollover(q.now())
+	return q.used[key]
+}
@@ -0,0 +1,181 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a time source that only moves when told to
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// newTestLimiter returns a limiter reading the time from a fake clock
+func newTestLimiter(rate float64, burst int) (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	l := NewLimiter(rate, burst)
+	l.now = clock.Now
+	return l, clock
+}
+
+// takeAll takes tokens for key until one is refused and returns how many
+// were granted and the wait reported for the refusal
+func takeAll(l *Limiter, key string) (int, time.Duration) {
+	for n := 0; ; n++ {
+		if ok, wait := l.Allow(key); !ok {
+			return n, wait
+		}
+	}
+}
+
+func TestLimiterBurst(t *testing.T) {
+	l, _ := newTestLimiter(1, 5)
+	if n, wait := takeAll(l, "alice"); n != 5 || wait != time.Second {
+		t.Errorf("granted %d then wait %v, want 5 then %v", n, wait, time.Second)
+	}
+
+	// A burst below one still admits one request
+	l, _ = newTestLimiter(1, 0)
+	if n, _ := takeAll(l, "alice"); n != 1 {
+		t.Errorf("with burst 0 granted %d, want 1", n)
+	}
+}
+
+func TestLimiterRefill(t *testing.T) {
+	l, clock := newTestLimiter(2, 4)
+	takeAll(l, "alice")
+
+	// Half a token refills in a quarter second
+	clock.Advance(250 * time.Millisecond)
+	if ok, wait := l.Allow("alice"); ok || wait != 250*time.Millisecond {
+		t.Errorf("after 250ms: allowed %v, wait %v, want refused with 250ms", ok, wait)
+	}
+	clock.Advance(250 * time.Millisecond)
+	if n, _ := takeAll(l, "alice"); n != 1 {
+		t.Errorf("after 500ms granted %d, want 1", n)
+	}
+
+	// Refilling stops at the burst
+	clock.Advance(time.Hour)
+	if n, _ := takeAll(l, "alice"); n != 4 {
+		t.Errorf("after an hour granted %d, want the burst of 4", n)
+	}
+}
+
+func TestLimiterIsolatesKeys(t *testing.T) {
+	l, _ := newTestLimiter(1, 2)
+	if n, _ := takeAll(l, "alice"); n != 2 {
+		t.Fatalf("alice granted %d, want 2", n)
+	}
+	if n, _ := takeAll(l, "bob"); n != 2 {
+		t.Errorf("bob granted %d after alice used her burst, want 2", n)
+	}
+}
+
+func TestLimiterPrunesIdleBuckets(t *testing.T) {
+	l, clock := newTestLimiter(1, 2)
+	takeAll(l, "alice")
+	takeAll(l, "bob")
+
+	clock.Advance(idleBucketTTL / 2)
+	l.Allow("bob")
+	clock.Advance(idleBucketTTL/2 + time.Second)
+	l.Allow("carol")
+
+	l.mu.Lock()
+	_, alice := l.buckets["alice"]
+	_, bob := l.buckets["bob"]
+	l.mu.Unlock()
+	if alice || !bob {
+		t.Errorf("after pruning alice kept %v and bob kept %v, want only bob", alice, bob)
+	}
+	if n, _ := takeAll(l, "alice"); n != 2 {
+		t.Errorf("alice granted %d after her bucket was pruned, want 2", n)
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	var nilLimiter *Limiter
+	for _, l := range []*Limiter{nilLimiter, NewLimiter(0, 1), NewLimiter(-1, 1)} {
+		for i := 0; i < 100; i++ {
+			if ok, _ := l.Allow("alice"); !ok {
+				t.Fatalf("disabled limiter %v refused a request", l)
+			}
+		}
+	}
+}
+
+// newTestQuota returns a quota reading the time from a fake clock
+func newTestQuota(limit int64, start time.Time) (*Quota, *fakeClock) {
+	clock := &fakeClock{now: start}
+	q := NewQuota(limit)
+	q.now = clock.Now
+	return q, clock
+}
+
+func TestQuotaLimit(t *testing.T) {
+	start := time.Date(2026, 1, 1, 18, 0, 0, 0, time.UTC)
+	q, _ := newTestQuota(100, start)
+
+	q.Consume("alice", 60)
+	if ok, _ := q.Check("alice"); !ok {
+		t.Error("refused with 40 bytes left")
+	}
+	// The read that crosses the limit completes; the next is refused
+	q.Consume("alice", 60)
+	if used := q.Used("alice"); used != 120 {
+		t.Errorf("used %d, want 120", used)
+	}
+	if ok, wait := q.Check("alice"); ok || wait != 6*time.Hour {
+		t.Errorf("over quota: allowed %v, wait %v, want refused until midnight in 6h", ok, wait)
+	}
+}
+
+func TestQuotaRollsOverAtUTCMidnight(t *testing.T) {
+	// 18:00 in UTC-5 is 23:00 UTC
+	zone := time.FixedZone("UTC-5", -5*60*60)
+	q, clock := newTestQuota(100, time.Date(2026, 1, 1, 18, 0, 0, 0, zone))
+	q.Consume("alice", 100)
+
+	clock.Advance(59 * time.Minute)
+	if ok, wait := q.Check("alice"); ok || wait != time.Minute {
+		t.Errorf("a minute before UTC midnight: allowed %v, wait %v, want refused for 1m", ok, wait)
+	}
+
+	clock.Advance(time.Minute)
+	if ok, _ := q.Check("alice"); !ok {
+		t.Error("refused after UTC midnight")
+	}
+	if used := q.Used("alice"); used != 0 {
+		t.Errorf("used %d after rollover, want 0", used)
+	}
+
+	// Local midnight does not reset the quota
+	q.Consume("alice", 100)
+	clock.Advance(5 * time.Hour)
+	if ok, _ := q.Check("alice"); ok {
+		t.Error("quota reset at local midnight")
+	}
+}
+
+func TestQuotaIsolatesKeys(t *testing.T) {
+	q, _ := newTestQuota(100, time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	q.Consume("alice", 100)
+	if ok, _ := q.Check("alice"); ok {
+		t.Error("alice allowed over quota")
+	}
+	if ok, _ := q.Check("bob"); !ok || q.Used("bob") != 0 {
+		t.Errorf("bob refused or charged for alice's reads: used %d", q.Used("bob"))
+	}
+}
+
+func TestQuotaDisabled(t *testing.T) {
+	var nilQuota *Quota
+	for _, q := range []*Quota{nilQuota, NewQuota(0)} {
+		q.Consume("alice", 1<<40)
+		if ok, _ := q.Check("alice"); !ok {
+			t.Errorf("disabled quota %v refused a read", q)
+		}
+	}
+}
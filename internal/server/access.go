@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/auth"
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+	"github.com/aawadall/go-mcp-filesearch/internal/policy"
+)
+
+// principalName returns the name of the caller on ctx, or "" if anonymous
+func principalName(ctx context.Context) string {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		return principal.Name
+	}
+	return ""
+}
+
+// resourceLocation splits a resource URI into the root it belongs to and the
+// path within that root. File resource paths are decoded and checked by
+// filesearch.CleanPath, so that the path checked is the one read.
+func resourceLocation(uri string) (root, path string, err error) {
+	if isFileResource(uri) {
+		return filesearch.ParseResourceURI(uri)
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", uri, nil
+	}
+	return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
+
+// checkToolAccess returns an error if the caller may not call tool, either
+// because their token scopes do not grant it or because policy denies it
+func (s *MCPServer) checkToolAccess(ctx context.Context, tool models.Tool) error {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && principal.Permissions != nil {
+		if !principal.Permissions.AllowsTool(tool.Name) {
+			return fmt.Errorf("tool %s %w", tool.Name, filesearch.ErrNotPermitted)
+		}
+	}
+
+	engine := s.Policy()
+	if engine == nil {
+		return nil
+	}
+
+	name := principalName(ctx)
+	decision := engine.CanCallTool(name, tool.Name, !tool.IsReadOnly())
+	if !decision.Allowed {
+		s.log().InfoContext(ctx, "Policy denied tool call", "principal", displayName(name), "tool", tool.Name, "rule", decision.Rule)
+		return fmt.Errorf("tool %s %w", tool.Name, filesearch.ErrNotPermitted)
+	}
+
+	return nil
+}
+
+// checkResourceAccess returns an error if the caller may not read the resource
+func (s *MCPServer) checkResourceAccess(ctx context.Context, uri string) error {
+	root, path, err := resourceLocation(uri)
+	if err != nil {
+		return err
+	}
+
+	// Scopes are matched against the file's URI in the form resources are
+	// listed in
+	scoped := uri
+	if isFileResource(uri) {
+		scoped = filesearch.ResourceURI(root, path)
+	}
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && principal.Permissions != nil {
+		if !principal.Permissions.AllowsResource(scoped) {
+			return fmt.Errorf("access to resource %s %w", uri, filesearch.ErrNotPermitted)
+		}
+	}
+
+	engine := s.Policy()
+	if engine == nil {
+		return nil
+	}
+
+	name := principalName(ctx)
+	decision := engine.CanRead(name, root, path)
+	if !decision.Allowed {
+		s.log().InfoContext(ctx, "Policy denied resource read", "principal", displayName(name), "uri", uri, "rule", decision.Rule)
+		return fmt.Errorf("access to resource %s %w", uri, filesearch.ErrNotPermitted)
+	}
+
+	return nil
+}
+
+// visibleTools returns the tools the caller is allowed to call
+func (s *MCPServer) visibleTools(ctx context.Context) []models.Tool {
+	principal, hasPrincipal := auth.PrincipalFromContext(ctx)
+	name := principalName(ctx)
+	engine := s.Policy()
+
+	all := s.listTools()
+	tools := make([]models.Tool, 0, len(all))
+	for _, tool := range all {
+		if hasPrincipal && principal.Permissions != nil && !principal.Permissions.AllowsTool(tool.Name) {
+			continue
+		}
+		if engine != nil && !engine.CanCallTool(name, tool.Name, !tool.IsReadOnly()).Allowed {
+			continue
+		}
+		tools = append(tools, tool)
+	}
+	return tools
+}
+
+// visibleResources returns the resources the caller is allowed to read
+func (s *MCPServer) visibleResources(ctx context.Context) []models.Resource {
+	principal, hasPrincipal := auth.PrincipalFromContext(ctx)
+	name := principalName(ctx)
+	engine := s.Policy()
+
+	all := s.listResources()
+	resources := make([]models.Resource, 0, len(all))
+	for _, resource := range all {
+		if hasPrincipal && principal.Permissions != nil && !principal.Permissions.AllowsResource(resource.URI) {
+			continue
+		}
+		if engine != nil {
+			root, path, err := resourceLocation(resource.URI)
+			if err != nil || !engine.CanRead(name, root, path).Allowed {
+				continue
+			}
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+// displayName returns a printable name for a possibly anonymous principal
+func displayName(name string) string {
+	if name == "" {
+		return policy.AnonymousPrincipal
+	}
+	return name
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/auth"
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/policy"
+)
+
+// newAccessTestServer returns an initialized server searching a root "proj"
+// holding docs/readme.md and secret.txt
+func newAccessTestServer(t *testing.T) *MCPServer {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range map[string]string{"docs/readme.md": "# Docs", "secret.txt": "secret"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	registry, err := filesearch.NewRegistry([]filesearch.Root{{Name: "proj", Path: dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewEmptyMCPServer()
+	s.SetFileSearch(filesearch.NewHandler(filesearch.NewEngine(registry, nil, filesearch.Options{})))
+	s.initialized.Store(true)
+	return s
+}
+
+// escapingURIs name secret.txt through the docs directory
+var escapingURIs = []string{
+	"filesearch://proj/docs/../secret.txt",
+	"filesearch://proj/docs/%2e%2e/secret.txt",
+	"filesearch://proj/docs/%2E%2E/secret.txt",
+	"filesearch://proj/docs%2f..%2fsecret.txt",
+	"filesearch://proj/docs/./../secret.txt",
+	"filesearch://proj/docs//../secret.txt",
+}
+
+// uncleanURIs name docs/readme.md in a form other than the clean one. As
+// URIs, "filesearch://proj//docs/readme.md" is one too, but the path
+// arguments of tools may start with a slash.
+var uncleanURIs = []string{
+	"filesearch://proj/docs//readme.md",
+	"filesearch://proj/docs/./readme.md",
+	"filesearch://proj/docs/%2e/readme.md",
+}
+
+// checkReads reads each URI on s and checks the outcome
+func checkReads(t *testing.T, s *MCPServer, ctx context.Context, uris []string, want error) {
+	t.Helper()
+	for _, uri := range uris {
+		_, err := s.handleReadResource(ctx, map[string]interface{}{"uri": uri})
+		switch {
+		case want == nil && err != nil:
+			t.Errorf("read %s: %v", uri, err)
+		case want != nil && !errors.Is(err, want):
+			t.Errorf("read %s: error = %v, want %v", uri, err, want)
+		}
+	}
+}
+
+func TestResourceAccessPolicyChecksCleanPath(t *testing.T) {
+	s := newAccessTestServer(t)
+	engine, err := policy.New(policy.Config{Rules: []policy.Rule{
+		{Principals: []string{"*"}, Roots: []string{"proj"}, Paths: []string{"docs", "docs/**"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetPolicy(engine)
+	ctx := context.Background()
+
+	checkReads(t, s, ctx, []string{"filesearch://proj/docs/readme.md", "filesearch://proj/docs/"}, nil)
+	checkReads(t, s, ctx, []string{"filesearch://proj/secret.txt"}, filesearch.ErrNotPermitted)
+	checkReads(t, s, ctx, escapingURIs, filesearch.ErrInvalidPath)
+	checkReads(t, s, ctx, append(uncleanURIs, "filesearch://proj//docs/readme.md"), filesearch.ErrInvalidPath)
+}
+
+func TestResourceAccessScopesCheckCleanPath(t *testing.T) {
+	s := newAccessTestServer(t)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Name:        "alice",
+		Method:      auth.MethodOAuth,
+		Permissions: &auth.Permissions{Resources: []string{"filesearch://proj/docs/**"}},
+	})
+
+	checkReads(t, s, ctx, []string{"filesearch://proj/docs/readme.md"}, nil)
+	checkReads(t, s, ctx, []string{"filesearch://proj/secret.txt"}, filesearch.ErrNotPermitted)
+	checkReads(t, s, ctx, escapingURIs, filesearch.ErrInvalidPath)
+	checkReads(t, s, ctx, append(uncleanURIs, "filesearch://proj//docs/readme.md"), filesearch.ErrInvalidPath)
+}
+
+// readFileArgs returns the read_file arguments naming uri's root and path
+// as given, before decoding or cleaning
+func readFileArgs(uri string) map[string]interface{} {
+	root, path, _ := strings.Cut(strings.TrimPrefix(uri, filesearch.ResourceScheme+"://"), "/")
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		path = unescaped
+	}
+	return map[string]interface{}{"root": root, "path": path}
+}
+
+func TestReadFileToolChecksCleanPath(t *testing.T) {
+	s := newAccessTestServer(t)
+	engine, err := policy.New(policy.Config{Rules: []policy.Rule{
+		{Principals: []string{"*"}, Tools: []string{filesearch.ToolReadFile}},
+		{Principals: []string{"*"}, Roots: []string{"proj"}, Paths: []string{"docs/**"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetPolicy(engine)
+
+	call := func(uri string) error {
+		_, err := s.handleCallTool(context.Background(), map[string]interface{}{
+			"name":      filesearch.ToolReadFile,
+			"arguments": readFileArgs(uri),
+		})
+		return err
+	}
+	if err := call("filesearch://proj/docs/readme.md"); err != nil {
+		t.Errorf("read_file of an allowed file: %v", err)
+	}
+	if err := call("filesearch://proj/secret.txt"); !errors.Is(err, filesearch.ErrNotPermitted) {
+		t.Errorf("read_file of a denied file: error = %v, want %v", err, filesearch.ErrNotPermitted)
+	}
+	for _, uri := range append(escapingURIs, uncleanURIs...) {
+		if err := call(uri); !errors.Is(err, filesearch.ErrInvalidPath) {
+			t.Errorf("read_file %v: error = %v, want %v", readFileArgs(uri), err, filesearch.ErrInvalidPath)
+		}
+	}
+}
+
+func TestRESTFilesChecksCleanPath(t *testing.T) {
+	s := newAccessTestServer(t)
+	engine, err := policy.New(policy.Config{Rules: []policy.Rule{
+		{Principals: []string{"*"}, Tools: []string{filesearch.ToolReadFile}},
+		{Principals: []string{"*"}, Roots: []string{"proj"}, Paths: []string{"docs/**"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetPolicy(engine)
+	h := NewHTTPMCPServer(s)
+
+	get := func(target string) int {
+		// Called directly, as the mux would redirect most unclean paths
+		w := httptest.NewRecorder()
+		h.RESTFilesHandler(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w.Code
+	}
+	if code := get("/api/files/proj/docs/readme.md"); code != http.StatusOK {
+		t.Errorf("GET of an allowed file: status %d, want %d", code, http.StatusOK)
+	}
+	if code := get("/api/files/proj/secret.txt"); code != http.StatusForbidden {
+		t.Errorf("GET of a denied file: status %d, want %d", code, http.StatusForbidden)
+	}
+	for _, uri := range append(escapingURIs, uncleanURIs...) {
+		target := "/api/files/" + strings.TrimPrefix(uri, filesearch.ResourceScheme+"://")
+		if code := get(target); code != http.StatusBadRequest {
+			t.Errorf("GET %s: status %d, want %d", target, code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/auth"
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+)
+
+// AdminScope is the credential scope that grants access to /admin endpoints
+const AdminScope = "admin"
+
+// ReloadFunc re-reads the configuration and applies it to the running server
+type ReloadFunc func() (ReloadResult, error)
+
+// SetReloader enables POST /admin/reload, which calls reload
+func (h *HTTPMCPServer) SetReloader(reload ReloadFunc) {
+	h.reload = reload
+}
+
+// isAdmin reports whether the caller may use the admin endpoints: only
+// callers identified by a credential or client certificate holding the
+// admin scope. Without authentication nobody may.
+func (h *HTTPMCPServer) isAdmin(r *http.Request) bool {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		return false
+	}
+	for _, scope := range principal.Scopes {
+		if scope == AdminScope {
+			return true
+		}
+	}
+	return false
+}
+
+// requireAdmin writes a 403 response and returns false if the caller may
+// not use the admin endpoints
+func (h *HTTPMCPServer) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if !h.isAdmin(r) {
+		writeJSONError(w, http.StatusForbidden, "admin scope required")
+		return false
+	}
+	return true
+}
+
+// adminErrorStatus maps an error from a root or index operation to an HTTP
+// status
+func adminErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errNoFileSearch), errors.Is(err, errIndexDisabled), errors.Is(err, filesearch.ErrUnknownRoot):
+		return http.StatusNotFound
+	case errors.Is(err, errRootExists):
+		return http.StatusConflict
+	default:
+		return http.StatusUnprocessableEntity
+	}
+}
+
+// writeJSON sends v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONError sends an error response in the same shape as
+// authentication failures
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error":   http.StatusText(status),
+		"message": message,
+	})
+}
+
+// AdminReloadHandler reloads the configuration. Invalid configurations are
+// rejected and the running configuration is kept.
+func (h *HTTPMCPServer) AdminReloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+	if h.reload == nil {
+		writeJSONError(w, http.StatusNotFound, "configuration reload is not enabled")
+		return
+	}
+
+	result, err := h.reload()
+	if err != nil {
+		h.mcpServer.log().WarnContext(r.Context(), "Configuration reload rejected", "principal", displayName(principalName(r.Context())), "error", err)
+		writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	h.mcpServer.log().InfoContext(r.Context(), "Configuration reloaded", "principal", displayName(principalName(r.Context())))
+	writeJSON(w, http.StatusOK, result)
+}
+
+// AdminIndexHandler reports the index statistics of every indexed root
+func (h *HTTPMCPServer) AdminIndexHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	stats, err := h.mcpServer.IndexStats()
+	if err != nil {
+		writeJSONError(w, adminErrorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"roots": stats})
+}
+
+// AdminReindexHandler rebuilds the index of the root named by the root query
+// parameter, or of every root, and responds with the new statistics
+func (h *HTTPMCPServer) AdminReindexHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	root := r.URL.Query().Get("root")
+	stats, err := h.mcpServer.Reindex(r.Context(), root)
+	if err != nil {
+		writeJSONError(w, adminErrorStatus(err), err.Error())
+		return
+	}
+
+	h.mcpServer.log().InfoContext(r.Context(), "Index rebuilt", "principal", displayName(principalName(r.Context())), "root", root)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"roots": stats})
+}
+
+// AdminRootsHandler lists the search roots on GET, adds one on POST from a
+// {"name", "path"} body, and removes the one named in the path on DELETE
+// /admin/roots/{name}
+func (h *HTTPMCPServer) AdminRootsHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) {
+		return
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/admin/roots"), "/")
+
+	switch {
+	case r.Method == "GET" && name == "":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"roots": h.mcpServer.Roots()})
+
+	case r.Method == "POST" && name == "":
+		var root filesearch.Root
+		if err := json.NewDecoder(r.Body).Decode(&root); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid root: "+err.Error())
+			return
+		}
+		root, err := h.mcpServer.AddRoot(root)
+		if err != nil {
+			writeJSONError(w, adminErrorStatus(err), err.Error())
+			return
+		}
+		h.mcpServer.log().InfoContext(r.Context(), "Root added", "principal", displayName(principalName(r.Context())), "root", root.Name, "path", root.Path)
+		writeJSON(w, http.StatusCreated, root)
+
+	case r.Method == "DELETE" && name != "":
+		if err := h.mcpServer.RemoveRoot(name); err != nil {
+			writeJSONError(w, adminErrorStatus(err), err.Error())
+			return
+		}
+		h.mcpServer.log().InfoContext(r.Context(), "Root removed", "principal", displayName(principalName(r.Context())), "root", name)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// AdminSessionsHandler lists the live sessions on GET, and ends the one named
+// in the path on DELETE /admin/sessions/{id}, cancelling its in-flight calls
+func (h *HTTPMCPServer) AdminSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) {
+		return
+	}
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/admin/sessions"), "/")
+
+	switch {
+	case r.Method == "GET" && id == "":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"sessions": h.sessions.list()})
+
+	case r.Method == "DELETE" && id != "":
+		if !h.sessions.remove(id) {
+			writeJSONError(w, http.StatusNotFound, "session not found")
+			return
+		}
+		cancelled := h.mcpServer.calls.cancelSession(id)
+		h.mcpServer.log().InfoContext(r.Context(), "Session ended", "principal", displayName(principalName(r.Context())), "session", id, "cancelled_calls", cancelled)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// AdminCallsHandler lists the in-flight requests on GET, and cancels the one
+// named in the path on DELETE /admin/calls/{id}
+func (h *HTTPMCPServer) AdminCallsHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) {
+		return
+	}
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/admin/calls"), "/")
+
+	switch {
+	case r.Method == "GET" && id == "":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"calls": h.mcpServer.InFlightCalls()})
+
+	case r.Method == "DELETE" && id != "":
+		if !h.mcpServer.CancelCall(id) {
+			writeJSONError(w, http.StatusNotFound, "call not in flight")
+			return
+		}
+		h.mcpServer.log().InfoContext(r.Context(), "Call cancelled", "principal", displayName(principalName(r.Context())), "call", id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/audit"
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+)
+
+// SetAuditLog records every tool call and resource read in log. A nil log
+// disables auditing. It must be called before the server starts serving.
+func (s *MCPServer) SetAuditLog(log *audit.Logger) {
+	s.auditLog = log
+}
+
+// auditToolCall records a tool call with the files its result returned
+func (s *MCPServer) auditToolCall(ctx context.Context, name string, paramsMap map[string]interface{}, result interface{}, err error, denied bool) {
+	if s.auditLog == nil {
+		return
+	}
+	args, _ := paramsMap["arguments"].(map[string]interface{})
+	s.writeAudit(ctx, audit.Entry{
+		Method:    "tools/call",
+		Tool:      name,
+		Arguments: args,
+		Paths:     filesearch.ResultPaths(result),
+	}, result, err, denied)
+}
+
+// auditResourceRead records a resource read with the files it returned
+func (s *MCPServer) auditResourceRead(ctx context.Context, uri string, paths []string, result interface{}, err error, denied bool) {
+	if s.auditLog == nil {
+		return
+	}
+	s.writeAudit(ctx, audit.Entry{
+		Method:   "resources/read",
+		Resource: uri,
+		Paths:    paths,
+	}, result, err, denied)
+}
+
+// writeAudit completes entry with the caller, the size of the result and
+// the outcome, and appends it to the audit log. A failure to write is
+// logged but does not fail the request.
+func (s *MCPServer) writeAudit(ctx context.Context, entry audit.Entry, result interface{}, err error, denied bool) {
+	entry.Principal = principalName(ctx)
+	if client, ok := clientSessionFromContext(ctx); ok {
+		entry.Session = client.id
+	}
+	entry.RequestID, _ = RequestIDFromContext(ctx)
+
+	switch {
+	case denied:
+		entry.Outcome = audit.OutcomeDenied
+	case err != nil:
+		entry.Outcome = audit.OutcomeError
+	default:
+		entry.Outcome = audit.OutcomeSuccess
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	} else if data, marshalErr := json.Marshal(result); marshalErr == nil {
+		entry.Bytes = int64(len(data))
+	}
+
+	if err := s.auditLog.Write(entry); err != nil {
+		s.log().ErrorContext(ctx, "Failed to write audit log", "error", err)
+	}
+}
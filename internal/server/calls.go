@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+// CallInfo describes a request being handled
+type CallInfo struct {
+	ID        string    `json:"id"`
+	Method    string    `json:"method"`
+	Tool      string    `json:"tool,omitempty"`
+	Principal string    `json:"principal,omitempty"`
+	Session   string    `json:"session,omitempty"`
+	Started   time.Time `json:"started"`
+}
+
+// inflightCall is a request being handled and the function that cancels it
+type inflightCall struct {
+	info CallInfo
+	// rpcID is the JSON encoding of the ID the client gave the request
+	rpcID  string
+	cancel context.CancelFunc
+}
+
+// callRegistry tracks in-flight requests so that they can be listed and
+// cancelled
+type callRegistry struct {
+	mu    sync.Mutex
+	calls map[string]*inflightCall
+}
+
+func newCallRegistry() *callRegistry {
+	return &callRegistry{calls: make(map[string]*inflightCall)}
+}
+
+// start registers the request on ctx, which must carry a request ID, as
+// started at the given time, and returns a cancellable context for it and a
+// function to call when it finishes
+func (cr *callRegistry) start(ctx context.Context, req models.JSONRPCRequest, started time.Time) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	call := &inflightCall{
+		info: CallInfo{
+			Method:    req.Method,
+			Principal: principalName(ctx),
+			Started:   started,
+		},
+		rpcID:  rpcIDKey(req.ID),
+		cancel: cancel,
+	}
+	call.info.ID, _ = RequestIDFromContext(ctx)
+	if req.Method == "tools/call" {
+		params, _ := req.Params.(map[string]interface{})
+		call.info.Tool, _ = params["name"].(string)
+	}
+	if client, ok := clientSessionFromContext(ctx); ok {
+		call.info.Session = client.id
+	}
+
+	cr.mu.Lock()
+	cr.calls[call.info.ID] = call
+	cr.mu.Unlock()
+
+	return ctx, func() {
+		cr.mu.Lock()
+		delete(cr.calls, call.info.ID)
+		cr.mu.Unlock()
+		cancel()
+	}
+}
+
+// list returns the in-flight requests, oldest first
+func (cr *callRegistry) list() []CallInfo {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	calls := make([]CallInfo, 0, len(cr.calls))
+	for _, call := range cr.calls {
+		calls = append(calls, call.info)
+	}
+	sort.Slice(calls, func(i, j int) bool { return calls[i].Started.Before(calls[j].Started) })
+	return calls
+}
+
+// cancel cancels the request with the given ID and reports whether it was
+// in flight
+func (cr *callRegistry) cancel(id string) bool {
+	cr.mu.Lock()
+	call, ok := cr.calls[id]
+	cr.mu.Unlock()
+
+	if ok {
+		call.cancel()
+	}
+	return ok
+}
+
+// cancelRequest cancels the request a principal made in a session under the
+// given JSON-RPC ID, and reports whether it was in flight
+func (cr *callRegistry) cancelRequest(sessionID, principal string, rpcID interface{}) bool {
+	key := rpcIDKey(rpcID)
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	for _, call := range cr.calls {
+		if call.rpcID == key && call.info.Session == sessionID && call.info.Principal == principal {
+			call.cancel()
+			return true
+		}
+	}
+	return false
+}
+
+// rpcIDKey encodes a JSON-RPC ID so that the number 1 and the string "1"
+// stay distinct
+func rpcIDKey(id interface{}) string {
+	data, _ := json.Marshal(id)
+	return string(data)
+}
+
+// cancelSession cancels every request made in a session and returns how
+// many there were
+func (cr *callRegistry) cancelSession(sessionID string) int {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	cancelled := 0
+	for _, call := range cr.calls {
+		if call.info.Session == sessionID {
+			call.cancel()
+			cancelled++
+		}
+	}
+	return cancelled
+}
+
+// InFlightCalls returns the requests currently being handled, oldest first
+func (s *MCPServer) InFlightCalls() []CallInfo {
+	return s.calls.list()
+}
+
+// CancelCall cancels the in-flight request with the given server-assigned
+// ID. The request finishes with an error once the work it is doing notices
+// the cancellation. It reports whether the request was in flight.
+func (s *MCPServer) CancelCall(id string) bool {
+	return s.calls.cancel(id)
+}
+
+// handleCancelled cancels the request named by a notifications/cancelled
+// message. Only requests the sender made in the same session are cancelled;
+// a sessionless HTTP request is cancelled by closing its connection.
+func (s *MCPServer) handleCancelled(ctx context.Context, params interface{}) {
+	client, ok := clientSessionFromContext(ctx)
+	if !ok {
+		return
+	}
+	paramsMap, _ := params.(map[string]interface{})
+	id := paramsMap["requestId"]
+	if id == nil {
+		return
+	}
+	if s.calls.cancelRequest(client.id, principalName(ctx), id) {
+		reason, _ := paramsMap["reason"].(string)
+		s.log().DebugContext(ctx, "Request cancelled by client", "rpc_id", id, "reason", reason)
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/glob"
+)
+
+// SessionIDHeader carries the MCP session identifier on HTTP requests and responses
+const SessionIDHeader = "Mcp-Session-Id"
+
+// CORSConfig controls which browser origins may call the HTTP server.
+// Origins are glob patterns such as "https://*.example.com" or
+// "http://localhost:*"; "*" allows any origin.
+type CORSConfig struct {
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	ExposedHeaders []string
+	MaxAge         int
+}
+
+// DefaultCORSConfig only admits pages served from the local machine, which
+// prevents DNS rebinding attacks against a server bound to localhost
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedOrigins: []string{
+			"http://localhost", "http://localhost:*",
+			"https://localhost", "https://localhost:*",
+			"http://127.0.0.1", "http://127.0.0.1:*",
+			"https://127.0.0.1", "https://127.0.0.1:*",
+			"http://[::1]", "http://[::1]:*",
+		},
+		AllowedMethods: []string{"GET", "POST", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key", SessionIDHeader},
+		ExposedHeaders: []string{SessionIDHeader},
+		MaxAge:         600,
+	}
+}
+
+// allowsOrigin reports whether requests from origin are accepted. "*" is
+// matched here because a glob "*" does not cross the "/" in "https://".
+func (c CORSConfig) allowsOrigin(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return glob.MatchAny(c.AllowedOrigins, origin)
+}
+
+// LoopbackHosts lists the Host headers of requests addressed to the local
+// machine. A server bound to a loopback address should only accept these,
+// since a page on a rebound DNS name reaches it under that name without an
+// Origin header on same-origin requests.
+func LoopbackHosts() []string {
+	return []string{
+		"localhost", "localhost:*",
+		"127.0.0.1", "127.0.0.1:*",
+		"[::1]", "[::1]:*",
+	}
+}
+
+// IsLoopback reports whether host, a listen host such as "127.0.0.1" or
+// "localhost", only accepts connections from the local machine
+func IsLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// checkHost returns false after writing a 403 response if the request's
+// Host header is not allowed. Any host is allowed if no allowlist is set.
+func (h *HTTPMCPServer) checkHost(w http.ResponseWriter, r *http.Request) bool {
+	if len(h.allowedHosts) == 0 || glob.MatchAny(h.allowedHosts, strings.ToLower(r.Host)) {
+		return true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":   http.StatusText(http.StatusForbidden),
+		"message": "host not allowed: " + r.Host,
+	})
+	return false
+}
+
+// applyCORS validates the request Origin and sets CORS response headers. It
+// returns false after writing a 403 response if the origin is not allowed.
+// Requests without an Origin header come from non-browser clients or
+// same-origin pages and are accepted here; checkHost guards those.
+func (h *HTTPMCPServer) applyCORS(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	w.Header().Add("Vary", "Origin")
+
+	if !h.cors.allowsOrigin(origin) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   http.StatusText(http.StatusForbidden),
+			"message": "origin not allowed: " + origin,
+		})
+		return false
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if len(h.cors.ExposedHeaders) > 0 {
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(h.cors.ExposedHeaders, ", "))
+	}
+
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(h.cors.AllowedMethods, ", "))
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(h.cors.AllowedHeaders, ", "))
+		if h.cors.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(h.cors.MaxAge))
+		}
+	}
+
+	return true
+}
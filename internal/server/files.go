@@ -0,0 +1,279 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/auth"
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/glob"
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+// SetFileSearch exposes the file search tools and one resource per root
+func (s *MCPServer) SetFileSearch(handler *filesearch.Handler) {
+	s.mu.Lock()
+	s.files = handler
+	s.mu.Unlock()
+}
+
+// SetEnabledTools restricts the advertised tools to those matching the given
+// glob patterns. A nil list enables every tool.
+func (s *MCPServer) SetEnabledTools(patterns []string) {
+	s.mu.Lock()
+	s.enabledTools = patterns
+	s.mu.Unlock()
+}
+
+// Errors returned by the root and index operations
+var (
+	errNoFileSearch  = errors.New("file search is not configured")
+	errIndexDisabled = errors.New("indexing is not enabled")
+	errRootExists    = errors.New("root already exists")
+)
+
+// Roots returns the search roots, sorted by name
+func (s *MCPServer) Roots() []filesearch.Root {
+	files := s.FileSearch()
+	if files == nil {
+		return nil
+	}
+	return files.Engine().Registry().List()
+}
+
+// AddRoot adds a search root to the running server and tells clients their
+// resource lists changed. It returns the root with its path made absolute.
+// The root lasts until a restart; configuration reloads keep it.
+func (s *MCPServer) AddRoot(root filesearch.Root) (filesearch.Root, error) {
+	files := s.FileSearch()
+	if files == nil {
+		return root, errNoFileSearch
+	}
+	registry := files.Engine().Registry()
+	if _, exists := registry.Get(root.Name); exists {
+		return root, fmt.Errorf("%w: %s", errRootExists, root.Name)
+	}
+
+	root, err := filesearch.ValidateRoot(root)
+	if err != nil {
+		return root, err
+	}
+	if err := registry.Add(root); err != nil {
+		return root, err
+	}
+
+	s.Notify(models.NotificationResourcesListChanged, nil)
+	return root, nil
+}
+
+// RemoveRoot removes a search root and its index from the running server
+// and tells clients their resource lists changed
+func (s *MCPServer) RemoveRoot(name string) error {
+	files := s.FileSearch()
+	if files == nil {
+		return errNoFileSearch
+	}
+	if !files.Engine().Registry().Remove(name) {
+		return fmt.Errorf("%w: %s", filesearch.ErrUnknownRoot, name)
+	}
+
+	if index := files.Engine().Index(); index != nil {
+		index.Remove(name)
+		if err := index.Save(); err != nil {
+			s.log().Warn("Failed to save index after removing root", "root", name, "error", err)
+		}
+	}
+
+	s.Notify(models.NotificationResourcesListChanged, nil)
+	return nil
+}
+
+// IndexStats returns the index statistics of every indexed root
+func (s *MCPServer) IndexStats() ([]filesearch.IndexStats, error) {
+	files := s.FileSearch()
+	if files == nil {
+		return nil, errNoFileSearch
+	}
+	index := files.Engine().Index()
+	if index == nil {
+		return nil, errIndexDisabled
+	}
+	return index.Stats(), nil
+}
+
+// Reindex rebuilds the index of the named root, or of every root if name is
+// empty, and returns the statistics of the rebuilt roots
+func (s *MCPServer) Reindex(ctx context.Context, name string) ([]filesearch.IndexStats, error) {
+	files := s.FileSearch()
+	if files == nil {
+		return nil, errNoFileSearch
+	}
+	if files.Engine().Index() == nil {
+		return nil, errIndexDisabled
+	}
+	if _, ok := files.Engine().Registry().Get(name); name != "" && !ok {
+		return nil, fmt.Errorf("%w: %s", filesearch.ErrUnknownRoot, name)
+	}
+	stats, err := files.Engine().BuildIndex(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyRootsUpdated(stats)
+	return stats, nil
+}
+
+// listTools returns every enabled tool, registered and file search
+func (s *MCPServer) listTools() []models.Tool {
+	s.mu.RLock()
+	files, enabledTools := s.files, s.enabledTools
+	tools := make([]models.Tool, 0, len(s.tools))
+	registered := make(map[string]bool, len(s.tools))
+	for _, t := range s.tools {
+		tools = append(tools, t.tool)
+		registered[t.tool.Name] = true
+	}
+	s.mu.RUnlock()
+
+	// Registered tools hide file search tools of the same name
+	if files != nil {
+		for _, tool := range files.Tools() {
+			if !registered[tool.Name] {
+				tools = append(tools, tool)
+			}
+		}
+	}
+
+	if enabledTools == nil {
+		return tools
+	}
+
+	enabled := tools[:0]
+	for _, tool := range tools {
+		if glob.MatchAny(enabledTools, tool.Name) {
+			enabled = append(enabled, tool)
+		}
+	}
+	return enabled
+}
+
+// listResources returns the registered resources followed by one resource
+// per search root
+func (s *MCPServer) listResources() []models.Resource {
+	s.mu.RLock()
+	files := s.files
+	resources := make([]models.Resource, 0, len(s.resources))
+	for _, r := range s.resources {
+		resources = append(resources, r.resource)
+	}
+	s.mu.RUnlock()
+
+	if files == nil {
+		return resources
+	}
+
+	for _, root := range files.Engine().Registry().List() {
+		resources = append(resources, models.Resource{
+			URI:         filesearch.ResourceURI(root.Name, ""),
+			Name:        root.Name,
+			Description: fmt.Sprintf("Search root %s", root.Path),
+			MimeType:    "inode/directory",
+		})
+	}
+	return resources
+}
+
+// pathFilter returns a filter hiding paths the caller may not read
+func (s *MCPServer) pathFilter(ctx context.Context) filesearch.PathFilter {
+	principal, hasPrincipal := auth.PrincipalFromContext(ctx)
+	scoped := hasPrincipal && principal.Permissions != nil
+	engine := s.Policy()
+	if !scoped && engine == nil {
+		return nil
+	}
+
+	name := principalName(ctx)
+	return func(root, path string) bool {
+		if scoped && !principal.Permissions.AllowsResource(filesearch.ResourceURI(root, path)) {
+			return false
+		}
+		return engine == nil || engine.CanRead(name, root, path).Allowed
+	}
+}
+
+// isFileResource reports whether uri names a file or directory in a root
+func isFileResource(uri string) bool {
+	return strings.HasPrefix(uri, filesearch.ResourceScheme+"://")
+}
+
+// directoryListing is the result of reading a directory resource. Clients
+// receive the listing as text; the REST API uses the entries.
+type directoryListing struct {
+	Contents []map[string]interface{} `json:"contents"`
+	entries  []filesearch.FileInfo
+}
+
+// readFileResource returns the contents of a file resource, or a listing
+// if it names a directory, and the URIs of the files read or listed
+func (s *MCPServer) readFileResource(ctx context.Context, uri string) (interface{}, []string, error) {
+	files := s.FileSearch()
+	if files == nil {
+		return nil, nil, invalidParams("resource not found: %s", uri)
+	}
+
+	root, path, err := resourceLocation(uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	engine := files.Engine()
+
+	if entries, err := engine.List(ctx, root, path); err == nil {
+		filter := s.pathFilter(ctx)
+		var listing strings.Builder
+		var listed []string
+		visible := []filesearch.FileInfo{}
+		for _, entry := range entries {
+			if filter != nil && !filter(entry.Root, entry.Path) {
+				continue
+			}
+			visible = append(visible, entry)
+			listed = append(listed, filesearch.ResourceURI(entry.Root, entry.Path))
+			if entry.IsDir {
+				fmt.Fprintf(&listing, "%s/\n", entry.Path)
+			} else {
+				fmt.Fprintf(&listing, "%s\n", entry.Path)
+			}
+		}
+		return &directoryListing{
+			Contents: []map[string]interface{}{
+				{
+					"uri":      uri,
+					"mimeType": "text/plain",
+					"text":     listing.String(),
+				},
+			},
+			entries: visible,
+		}, listed, nil
+	}
+
+	content, err := engine.Read(ctx, root, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entry := map[string]interface{}{
+		"uri":      uri,
+		"mimeType": content.MimeType,
+	}
+	if content.IsBinary() {
+		entry["blob"] = base64.StdEncoding.EncodeToString(content.Data)
+	} else {
+		entry["text"] = content.Text
+	}
+
+	return map[string]interface{}{
+		"contents": []map[string]interface{}{entry},
+	}, []string{uri}, nil
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Readiness check statuses. A server is ready unless a check fails;
+// warnings are reported but do not take it out of service.
+const (
+	CheckPass = "pass"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// checkTimeout bounds how long a single readiness check may take
+const checkTimeout = 2 * time.Second
+
+// CheckResult is the outcome of a readiness check
+type CheckResult struct {
+	Status  string                 `json:"status"`
+	Message string                 `json:"message,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// ReadinessCheck reports the state of one subsystem
+type ReadinessCheck func(ctx context.Context) CheckResult
+
+// namedCheck is a readiness check registered under a name
+type namedCheck struct {
+	name  string
+	check ReadinessCheck
+}
+
+// AddReadinessCheck adds a check reported by /readyz. It must be called
+// before the server starts serving.
+func (h *HTTPMCPServer) AddReadinessCheck(name string, check ReadinessCheck) {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// checkShutdown fails once the server has begun shutting down, so that load
+// balancers stop sending it new requests
+func (h *HTTPMCPServer) checkShutdown(ctx context.Context) CheckResult {
+	select {
+	case <-h.done:
+		return CheckResult{Status: CheckFail, Message: "shutting down"}
+	default:
+		return CheckResult{Status: CheckPass}
+	}
+}
+
+// CheckRoots verifies that every search root is a readable directory
+func (s *MCPServer) CheckRoots(ctx context.Context) CheckResult {
+	files := s.FileSearch()
+	if files == nil {
+		return CheckResult{Status: CheckPass, Message: "file search is not configured"}
+	}
+
+	roots := files.Engine().Registry().List()
+	if len(roots) == 0 {
+		return CheckResult{Status: CheckWarn, Message: "no roots configured"}
+	}
+
+	result := CheckResult{Status: CheckPass, Details: map[string]interface{}{}}
+	var failed int
+	for _, root := range roots {
+		if err := readableDir(root.FileSystem()); err != nil {
+			result.Details[root.Name] = err.Error()
+			failed++
+			continue
+		}
+		result.Details[root.Name] = "ok"
+	}
+	if failed > 0 {
+		result.Status = CheckFail
+		result.Message = fmt.Sprintf("%d of %d roots not accessible", failed, len(roots))
+	}
+	return result
+}
+
+// readableDir returns an error if the root of fsys is not a directory that
+// can be listed. Errors omit the path so that it is not revealed to callers.
+func readableDir(fsys fs.FS) error {
+	f, err := fsys.Open(".")
+	if err != nil {
+		return bareError(err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return bareError(err)
+	}
+	dir, ok := f.(fs.ReadDirFile)
+	if !info.IsDir() || !ok {
+		return errors.New("not a directory")
+	}
+	if _, err := dir.ReadDir(1); err != nil && err != io.EOF {
+		return bareError(err)
+	}
+	return nil
+}
+
+// bareError strips the path from a filesystem error
+func bareError(err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+	return err
+}
+
+// CheckIndex reports whether every root is indexed and how old each index
+// is. Roots that are not indexed, or whose index is older than maxAge, are
+// reported as warnings: searches still work by walking the filesystem. A
+// zero maxAge does not check the age.
+func (s *MCPServer) CheckIndex(maxAge time.Duration) CheckResult {
+	files := s.FileSearch()
+	if files == nil {
+		return CheckResult{Status: CheckPass, Message: "file search is not configured"}
+	}
+	index := files.Engine().Index()
+	if index == nil {
+		return CheckResult{Status: CheckPass, Message: "indexing is disabled"}
+	}
+
+	indexed := make(map[string]map[string]interface{})
+	result := CheckResult{Status: CheckPass, Details: map[string]interface{}{}}
+	var missing, stale int
+	for _, stats := range index.Stats() {
+		age := s.now().Sub(stats.BuiltAt).Round(time.Second)
+		detail := map[string]interface{}{
+			"files":   stats.Files,
+			"builtAt": stats.BuiltAt,
+			"age":     age.String(),
+		}
+		if maxAge > 0 && age > maxAge {
+			detail["stale"] = true
+			stale++
+		}
+		indexed[stats.Root] = detail
+	}
+
+	for _, root := range files.Engine().Registry().List() {
+		if detail, ok := indexed[root.Name]; ok {
+			result.Details[root.Name] = detail
+			continue
+		}
+		result.Details[root.Name] = map[string]interface{}{"indexed": false}
+		missing++
+	}
+
+	switch {
+	case missing > 0 && stale > 0:
+		result.Status = CheckWarn
+		result.Message = fmt.Sprintf("%d roots not indexed, %d indexes older than %s", missing, stale, maxAge)
+	case missing > 0:
+		result.Status = CheckWarn
+		result.Message = fmt.Sprintf("%d roots not indexed", missing)
+	case stale > 0:
+		result.Status = CheckWarn
+		result.Message = fmt.Sprintf("%d indexes older than %s", stale, maxAge)
+	}
+	return result
+}
+
+// runChecks runs every readiness check concurrently, failing checks that do
+// not finish within checkTimeout
+func (h *HTTPMCPServer) runChecks(ctx context.Context) map[string]CheckResult {
+	results := make(map[string]CheckResult, len(h.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func(c namedCheck) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			done := make(chan CheckResult, 1)
+			go func() { done <- c.check(checkCtx) }()
+
+			var result CheckResult
+			select {
+			case result = <-done:
+			case <-checkCtx.Done():
+				result = CheckResult{Status: CheckFail, Message: "check timed out"}
+			}
+
+			mu.Lock()
+			results[c.name] = result
+			mu.Unlock()
+		}(c)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// HealthzHandler reports that the process is alive and serving HTTP. It does
+// not check any subsystem.
+func (h *HTTPMCPServer) HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": CheckPass,
+		"uptime": time.Since(h.started).Round(time.Second).String(),
+	})
+}
+
+// ReadyzHandler runs the readiness checks and responds 200 if none failed,
+// or 503 otherwise, with the result of each check
+func (h *HTTPMCPServer) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	results := h.runChecks(r.Context())
+
+	status := CheckPass
+	for _, result := range results {
+		if result.Status == CheckFail {
+			status = CheckFail
+			break
+		}
+		if result.Status == CheckWarn {
+			status = CheckWarn
+		}
+	}
+
+	code := http.StatusOK
+	if status == CheckFail {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, map[string]interface{}{
+		"status": status,
+		"ready":  status != CheckFail,
+		"checks": results,
+	})
+}
+
+// CheckWatcher fails if the file watcher was started and has stopped, and
+// reports how many changes are waiting to be notified
+func (s *MCPServer) CheckWatcher(ctx context.Context) CheckResult {
+	watcher := s.fileWatcher()
+	if watcher == nil {
+		return CheckResult{Status: CheckPass, Message: "file watching is disabled"}
+	}
+	if !watcher.Running() {
+		return CheckResult{Status: CheckFail, Message: "file watcher stopped"}
+	}
+	return CheckResult{Status: CheckPass, Details: map[string]interface{}{"queued": watcher.QueueDepth()}}
+}
@@ -3,57 +3,160 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/aawadall/go-mcp-filesearch/internal/auth"
+	"github.com/aawadall/go-mcp-filesearch/internal/metrics"
 	"github.com/aawadall/go-mcp-filesearch/internal/models"
 )
 
 // HTTPMCPServer wraps an MCPServer to provide HTTP transport
 type HTTPMCPServer struct {
-	mcpServer *MCPServer
-	mux       *http.ServeMux
+	mcpServer     *MCPServer
+	mux           *http.ServeMux
+	identities    map[string]ClientIdentity
+	authenticator auth.Authenticator
+	metadata      *auth.ProtectedResourceMetadata
+	cors          CORSConfig
+	allowedHosts  []string
+	limiter       *rateLimiter
+	maxBodyBytes  int64
+	sessions      *sessionStore
+	reload        ReloadFunc
+	checks        []namedCheck
+	started       time.Time
+	ui            bool
+
+	// baseCtx is the parent of every request context; it is cancelled if a
+	// graceful shutdown does not finish in time
+	baseCtx      context.Context
+	cancelBase   context.CancelFunc
+	done         chan struct{}
+	shutdownOnce sync.Once
+}
+
+// authRealm is the realm advertised in WWW-Authenticate challenges
+const authRealm = "mcp"
+
+// unauthenticatedPaths lists endpoints reachable without credentials
+var unauthenticatedPaths = map[string]bool{
+	"/health":                          true,
+	"/healthz":                         true,
+	"/readyz":                          true,
+	auth.ProtectedResourceMetadataPath: true,
 }
 
 // NewHTTPMCPServer creates a new HTTP MCP server that wraps the given MCP server
 func NewHTTPMCPServer(mcpServer *MCPServer) *HTTPMCPServer {
+	baseCtx, cancelBase := context.WithCancel(context.Background())
 	httpServer := &HTTPMCPServer{
-		mcpServer: mcpServer,
-		mux:       http.NewServeMux(),
+		mcpServer:    mcpServer,
+		mux:          http.NewServeMux(),
+		cors:         DefaultCORSConfig(),
+		maxBodyBytes: DefaultMaxBodyBytes,
+		sessions:     newSessionStore(),
+		baseCtx:      baseCtx,
+		cancelBase:   cancelBase,
+		done:         make(chan struct{}),
+		started:      time.Now(),
+		ui:           true,
 	}
 
 	// Set up routes
 	httpServer.setupRoutes()
 
+	httpServer.AddReadinessCheck("shutdown", httpServer.checkShutdown)
+	httpServer.AddReadinessCheck("roots", mcpServer.CheckRoots)
+	httpServer.AddReadinessCheck("watcher", mcpServer.CheckWatcher)
+
+	// Forward server notifications to the sessions' event streams
+	mcpServer.AddNotifier(httpServer.sessions.broadcast)
+	go httpServer.pruneSessions(sessionPruneInterval)
+
+	mcpServer.Metrics().NewGaugeFunc("mcp_sessions_active", "Open HTTP sessions.", nil, func() []metrics.Sample {
+		return []metrics.Sample{{Value: float64(httpServer.sessions.count())}}
+	})
+
 	return httpServer
 }
 
-// setupRoutes configures all the HTTP routes for the MCP server
-func (h *HTTPMCPServer) setupRoutes() {
-	// Main MCP endpoint - handles all MCP protocol requests
-	h.mux.HandleFunc("/mcp", h.handleMCPRequest)
+// SetClientIdentities configures how verified client certificate subjects
+// map to principal names and scopes
+func (h *HTTPMCPServer) SetClientIdentities(identities map[string]ClientIdentity) {
+	h.identities = identities
+}
 
-	// Health check endpoint
-	h.mux.HandleFunc("/health", h.HealthCheckHandler)
+// SetAuthenticator requires callers to authenticate with the given
+// authenticator. Callers identified by a client certificate are accepted
+// without further credentials.
+func (h *HTTPMCPServer) SetAuthenticator(a auth.Authenticator) {
+	h.authenticator = a
+}
 
-	// Server information endpoint
-	h.mux.HandleFunc("/info", h.InfoHandler)
+// SetCORS replaces the default CORS configuration
+func (h *HTTPMCPServer) SetCORS(cors CORSConfig) {
+	h.cors = cors
+}
+
+// SetAllowedHosts only accepts requests whose Host header matches one of
+// the glob patterns, such as LoopbackHosts for a server bound to localhost.
+// Nil accepts any host.
+func (h *HTTPMCPServer) SetAllowedHosts(hosts []string) {
+	h.allowedHosts = hosts
+}
 
-	// Root endpoint with basic info
-	h.mux.HandleFunc("/", h.RootHandler)
+// SetRateLimits enables per-client rate limiting and read quotas
+func (h *HTTPMCPServer) SetRateLimits(cfg RateLimitConfig) {
+	h.limiter = newRateLimiter(cfg)
+}
+
+// SetMaxBodyBytes limits the size of MCP request bodies; zero removes the limit
+func (h *HTTPMCPServer) SetMaxBodyBytes(n int64) {
+	h.maxBodyBytes = n
+}
+
+// SetResourceMetadata publishes OAuth protected resource metadata so that
+// clients can discover which authorization servers issue tokens for it
+func (h *HTTPMCPServer) SetResourceMetadata(metadata *auth.ProtectedResourceMetadata) {
+	h.metadata = metadata
+}
+
+// setupRoutes configures all the HTTP routes for the MCP server
+func (h *HTTPMCPServer) setupRoutes() {
+	for _, route := range h.routes() {
+		h.mux.HandleFunc(route.pattern, route.handler)
+	}
 }
 
 // ServeHTTP delegates to the underlying mux
 func (h *HTTPMCPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Set CORS headers for web clients
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	// Reject requests addressed to unexpected hosts, such as a rebound DNS name
+	if !h.checkHost(w, r) {
+		return
+	}
+
+	// Reject disallowed browser origins and set CORS headers for allowed ones
+	if !h.applyCORS(w, r) {
+		return
+	}
 
 	// Handle preflight requests
 	if r.Method == "OPTIONS" {
-		w.WriteHeader(http.StatusOK)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Identify the caller before handing the request to any endpoint
+	r, ok := h.authenticate(w, r)
+	if !ok {
 		return
 	}
 
@@ -61,20 +164,149 @@ func (h *HTTPMCPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.mux.ServeHTTP(w, r)
 }
 
+// prefixKey is the context key of the prefix a request was served under
+type prefixKey struct{}
+
+// Mount returns a handler serving the endpoints under prefix, such as
+// /search for /search/mcp, for mounting in another mux. Requests for the
+// prefix itself are redirected to prefix + "/" and requests outside it
+// get 404.
+func (h *HTTPMCPServer) Mount(prefix string) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, ok := strings.CutPrefix(r.URL.Path, prefix)
+		switch {
+		case !ok || (path != "" && path[0] != '/'):
+			http.NotFound(w, r)
+			return
+		case path == "":
+			http.Redirect(w, r, prefix+"/", http.StatusMovedPermanently)
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), prefixKey{}, prefix))
+		http.StripPrefix(prefix, h).ServeHTTP(w, r)
+	})
+}
+
+// authenticate attaches the caller's principal to the request context. It
+// writes a 401 response and returns false if authentication is required
+// and fails.
+func (h *HTTPMCPServer) authenticate(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	// Identify callers presenting a verified client certificate
+	if principal, ok := ClientCertPrincipal(r, h.identities); ok {
+		return r.WithContext(auth.WithPrincipal(r.Context(), principal)), true
+	}
+
+	if h.authenticator == nil || unauthenticatedPaths[r.URL.Path] || h.isUIPath(r) {
+		return r, true
+	}
+
+	principal, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		h.writeUnauthorized(w, r, err)
+		return r, false
+	}
+
+	return r.WithContext(auth.WithPrincipal(r.Context(), principal)), true
+}
+
+// writeUnauthorized sends a 401 response with a bearer challenge, or a 403
+// response if the token was valid but lacks a required scope
+func (h *HTTPMCPServer) writeUnauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusUnauthorized
+	challenge := fmt.Sprintf(`Bearer realm="%s"`, authRealm)
+
+	switch {
+	case errors.Is(err, auth.ErrInsufficientScope):
+		status = http.StatusForbidden
+		challenge += `, error="insufficient_scope"`
+	case !errors.Is(err, auth.ErrNoCredentials):
+		challenge += `, error="invalid_token"`
+	}
+
+	// Point clients at the metadata document so they can find the authorization server
+	if h.metadata != nil {
+		challenge += fmt.Sprintf(`, resource_metadata="%s"`, metadataURL(r))
+	}
+
+	w.Header().Set("WWW-Authenticate", challenge)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":   http.StatusText(status),
+		"message": err.Error(),
+	})
+}
+
+// baseURL returns the scheme and host the request was sent to, followed by
+// the prefix the server is mounted under
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	prefix, _ := r.Context().Value(prefixKey{}).(string)
+	return scheme + "://" + r.Host + prefix
+}
+
+// metadataURL returns the absolute URL of the protected resource metadata
+func metadataURL(r *http.Request) string {
+	return baseURL(r) + auth.ProtectedResourceMetadataPath
+}
+
 // handleMCPRequest handles the main MCP protocol requests
 func (h *HTTPMCPServer) handleMCPRequest(w http.ResponseWriter, r *http.Request) {
-	// Only allow POST requests for MCP operations
-	if r.Method != "POST" {
+	// GET opens a session's notification stream and DELETE ends the
+	// session; every other operation is a POST
+	switch r.Method {
+	case "POST":
+	case "GET":
+		h.handleEventStream(w, r)
+		return
+	case "DELETE":
+		h.handleDeleteSession(w, r)
+		return
+	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	// Requests naming a session run in it, so that its log level applies
+	// and its log messages reach its event stream
+	if r.Header.Get(SessionIDHeader) != "" {
+		sess, ok := h.lookupSession(w, r)
+		if !ok {
+			return
+		}
+		r = r.WithContext(withClientSession(r.Context(), sess.client))
+	}
+
+	r, span := h.startHTTPSpan(r)
+	defer span.End()
+
 	// Set content type for JSON responses
 	w.Header().Set("Content-Type", "application/json")
 
-	// Read request body
+	// Read request body, refusing oversized requests
+	h.limitBody(w, r)
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
+		if isBodyTooLarge(err) {
+			h.mcpServer.metrics.observeError(models.ErrCodeInvalidRequest)
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(models.JSONRPCResponse{
+				JSONRPC: models.JSONRPCVersion,
+				ID:      nil,
+				Error: &models.JSONRPCError{
+					Code:    models.ErrCodeInvalidRequest,
+					Message: fmt.Sprintf("Request body exceeds %d bytes", h.maxBodyBytes),
+				},
+			})
+			return
+		}
 		http.Error(w, "Failed to read request body", http.StatusBadRequest)
 		return
 	}
@@ -86,18 +318,50 @@ func (h *HTTPMCPServer) handleMCPRequest(w http.ResponseWriter, r *http.Request)
 	// Try to parse as single JSON-RPC request
 	var req models.JSONRPCRequest
 	if err := json.Unmarshal(body, &req); err == nil {
+		// A notification is accepted without a response
+		if isNotification(req) {
+			h.notify(w, r, req)
+			w.Header().Del("Content-Type")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
 		// Single request
-		mcpResponse := h.mcpServer.handleRequest(req)
+		if req.Method == "initialize" && !h.roomForSession() {
+			http.Error(w, "Too many sessions", http.StatusServiceUnavailable)
+			return
+		}
+		mcpResponse := h.dispatch(w, r, req)
+		if req.Method == "initialize" && mcpResponse.Error == nil {
+			h.startSession(w, r)
+		}
 		response = mcpResponse
 	} else {
 		// Try to parse as batch request
 		var requests []models.JSONRPCRequest
 		if err := json.Unmarshal(body, &requests); err == nil {
-			// Batch request
-			responses := h.mcpServer.handleBatchRequest(requests)
-			response = responses
+			// Batch request, answered with the responses to its requests
+			responses := make([]models.JSONRPCResponse, 0, len(requests))
+			for _, req := range requests {
+				if isNotification(req) {
+					h.notify(w, r, req)
+					continue
+				}
+				responses = append(responses, h.dispatch(w, r, req))
+			}
+			switch {
+			case len(requests) == 0:
+				response = emptyBatchResponse()
+			case len(responses) == 0:
+				w.Header().Del("Content-Type")
+				w.WriteHeader(http.StatusAccepted)
+				return
+			default:
+				response = responses
+			}
 		} else {
 			// Invalid JSON
+			h.mcpServer.metrics.observeError(models.ErrCodeParseError)
 			response = models.JSONRPCResponse{
 				JSONRPC: models.JSONRPCVersion,
 				ID:      nil,
@@ -116,12 +380,17 @@ func (h *HTTPMCPServer) handleMCPRequest(w http.ResponseWriter, r *http.Request)
 	}
 }
 
-// RootHandler provides basic information about the server
+// RootHandler serves the search UI to browsers and basic information about
+// the server to everything else
 func (h *HTTPMCPServer) RootHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
 		http.NotFound(w, r)
 		return
 	}
+	if h.ui && wantsHTML(r) {
+		h.serveUIPage(w, r)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -135,11 +404,24 @@ func (h *HTTPMCPServer) RootHandler(w http.ResponseWriter, r *http.Request) {
 			"mcp":    "/mcp - Main MCP protocol endpoint",
 			"health": "/health - Health check",
 			"info":   "/info - Server information",
+			"api":    OpenAPIPath + " - OpenAPI description of the HTTP endpoints",
 		},
 		"usage": "Send POST requests to /mcp with JSON-RPC 2.0 formatted MCP requests",
 	})
 }
 
+// ResourceMetadataHandler serves the OAuth protected resource metadata
+func (h *HTTPMCPServer) ResourceMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	if h.metadata == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.metadata)
+}
+
 // HealthCheckHandler provides a simple health check endpoint
 func (h *HTTPMCPServer) HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
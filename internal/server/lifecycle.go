@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Defaults for the hardened HTTP server
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultWriteTimeout      = 60 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+	DefaultMaxBodyBytes      = 4 << 20
+	DefaultShutdownTimeout   = 30 * time.Second
+)
+
+// HTTPTimeouts bounds how long a client may take to send a request and
+// receive a response, and how long idle keep-alive connections are kept
+type HTTPTimeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+}
+
+// DefaultHTTPTimeouts returns the default server timeouts
+func DefaultHTTPTimeouts() HTTPTimeouts {
+	return HTTPTimeouts{
+		ReadHeader: DefaultReadHeaderTimeout,
+		Read:       DefaultReadTimeout,
+		Write:      DefaultWriteTimeout,
+		Idle:       DefaultIdleTimeout,
+	}
+}
+
+// NewServer returns an http.Server serving h on addr with the given
+// timeouts. Requests served by it are cancelled if a graceful shutdown
+// runs out of time.
+func (h *HTTPMCPServer) NewServer(addr string, timeouts HTTPTimeouts) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadHeaderTimeout: timeouts.ReadHeader,
+		ReadTimeout:       timeouts.Read,
+		WriteTimeout:      timeouts.Write,
+		IdleTimeout:       timeouts.Idle,
+		BaseContext: func(net.Listener) context.Context {
+			return h.baseCtx
+		},
+	}
+}
+
+// Done is closed when the server begins shutting down. Long-lived responses
+// such as event streams must finish when it is closed.
+func (h *HTTPMCPServer) Done() <-chan struct{} {
+	return h.done
+}
+
+// Shutdown gracefully stops srv: it stops accepting connections, tells
+// long-lived streams to close and waits for in-flight requests to finish.
+// If ctx expires first, in-flight requests are cancelled and remaining
+// connections are closed.
+func (h *HTTPMCPServer) Shutdown(ctx context.Context, srv *http.Server) error {
+	h.shutdownOnce.Do(func() { close(h.done) })
+
+	err := srv.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		h.cancelBase()
+		srv.Close()
+	}
+	return err
+}
+
+// limitBody caps the request body at the configured maximum size
+func (h *HTTPMCPServer) limitBody(w http.ResponseWriter, r *http.Request) {
+	if h.maxBodyBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxBodyBytes)
+	}
+}
+
+// isBodyTooLarge reports whether err came from exceeding the body limit
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
@@ -0,0 +1,333 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+	"github.com/aawadall/go-mcp-filesearch/internal/trace"
+)
+
+// MCP log levels, from the syslog severities of RFC 5424, mapped onto slog
+// levels. slog's Debug, Info, Warn and Error line up with debug, info,
+// warning and error.
+var logLevels = []struct {
+	name  string
+	level slog.Level
+}{
+	{"debug", slog.LevelDebug},
+	{"info", slog.LevelInfo},
+	{"notice", slog.LevelInfo + 2},
+	{"warning", slog.LevelWarn},
+	{"error", slog.LevelError},
+	{"critical", slog.LevelError + 4},
+	{"alert", slog.LevelError + 8},
+	{"emergency", slog.LevelError + 12},
+}
+
+// parseLogLevel converts an MCP log level name to a slog level
+func parseLogLevel(name string) (slog.Level, bool) {
+	for _, l := range logLevels {
+		if l.name == name {
+			return l.level, true
+		}
+	}
+	return 0, false
+}
+
+// logLevelName returns the MCP name of the highest level not above level
+func logLevelName(level slog.Level) string {
+	name := logLevels[0].name
+	for _, l := range logLevels {
+		if level >= l.level {
+			name = l.name
+		}
+	}
+	return name
+}
+
+// clientSession is the per-client state shared by the transports: its ID
+// for logging, the log level it asked for and how to send it messages
+type clientSession struct {
+	id   string
+	send func(models.JSONRPCNotification)
+	// initialized is set once the client has sent initialize
+	initialized atomic.Bool
+
+	mu       sync.Mutex
+	logLevel slog.Level
+	logging  bool
+	// watched holds the URIs of the resources the client subscribed to
+	watched map[string]bool
+}
+
+func newClientSession(id string, send func(models.JSONRPCNotification)) *clientSession {
+	return &clientSession{id: id, send: send}
+}
+
+// setLogLevel starts sending the client log messages at level and above
+func (c *clientSession) setLogLevel(level slog.Level) {
+	c.mu.Lock()
+	c.logLevel = level
+	c.logging = true
+	c.mu.Unlock()
+}
+
+// wants reports whether a notification should be delivered to the client.
+// Nothing is sent before the client has initialized the session, log
+// messages only once it has chosen a level, and resource updates only for
+// resources it subscribed to.
+func (c *clientSession) wants(n models.JSONRPCNotification) bool {
+	if !c.initialized.Load() {
+		return false
+	}
+	if updated, ok := n.Params.(models.ResourceUpdatedParams); ok {
+		return c.watching(updated.URI)
+	}
+
+	params, ok := n.Params.(models.LoggingMessageParams)
+	if n.Method != models.NotificationMessage || !ok {
+		return true
+	}
+	level, _ := parseLogLevel(params.Level)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.logging && level >= c.logLevel
+}
+
+// contextKey is the type of the context keys defined by this package
+type contextKey int
+
+const (
+	clientSessionKey contextKey = iota
+	requestIDKey
+	restRequestKey
+)
+
+// withClientSession attaches the calling client's session to ctx
+func withClientSession(ctx context.Context, client *clientSession) context.Context {
+	return context.WithValue(ctx, clientSessionKey, client)
+}
+
+// clientSessionFromContext returns the calling client's session, if any
+func clientSessionFromContext(ctx context.Context) (*clientSession, bool) {
+	client, ok := ctx.Value(clientSessionKey).(*clientSession)
+	return client, ok && client != nil
+}
+
+// requestCounter numbers requests so that log lines from one request can be
+// correlated
+var requestCounter atomic.Uint64
+
+// withRequestID attaches a new server-assigned request ID to ctx
+func withRequestID(ctx context.Context) context.Context {
+	id := strconv.FormatUint(requestCounter.Add(1), 10)
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the server-assigned ID of the request being
+// handled
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey).(string)
+	return id, ok
+}
+
+// handleSetLevel sets the minimum level of log messages sent to the caller
+func (s *MCPServer) handleSetLevel(ctx context.Context, params interface{}) (interface{}, error) {
+	if err := s.checkInitialized(ctx); err != nil {
+		return nil, err
+	}
+
+	paramsMap, ok := params.(map[string]interface{})
+	if !ok {
+		return nil, invalidParams("invalid params")
+	}
+
+	name, _ := paramsMap["level"].(string)
+	level, ok := parseLogLevel(name)
+	if !ok {
+		return nil, invalidParams("invalid log level: %q", name)
+	}
+
+	client, ok := clientSessionFromContext(ctx)
+	if !ok {
+		return nil, invalidRequest("logging requires a session")
+	}
+	client.setLogLevel(level)
+	s.lowerClientLogLevel(level)
+
+	return map[string]interface{}{}, nil
+}
+
+// lowerClientLogLevel records the lowest level any client has asked for, so
+// that records below the server's own level are still produced for them
+func (s *MCPServer) lowerClientLogLevel(level slog.Level) {
+	for {
+		current := s.minClientLogLevel.Load()
+		if current <= int64(level) {
+			return
+		}
+		if s.minClientLogLevel.CompareAndSwap(current, int64(level)) {
+			return
+		}
+	}
+}
+
+// LogHandler wraps base so that every record also carries the request and
+// session IDs from its context and is forwarded to clients that enabled
+// logging. Records logged while handling a request go only to the client
+// that made it. Records without a session, such as those of requests
+// without one and of the server itself, stay in the server's log.
+func (s *MCPServer) LogHandler(base slog.Handler) slog.Handler {
+	return &clientLogHandler{base: base, server: s}
+}
+
+// clientLogHandler is the slog.Handler returned by LogHandler
+type clientLogHandler struct {
+	base   slog.Handler
+	server *MCPServer
+	attrs  []slog.Attr
+	group  string
+}
+
+func (h *clientLogHandler) clientsEnabled(level slog.Level) bool {
+	return int64(level) >= h.server.minClientLogLevel.Load()
+}
+
+func (h *clientLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.base.Enabled(ctx, level) || h.clientsEnabled(level)
+}
+
+func (h *clientLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	client, hasClient := clientSessionFromContext(ctx)
+
+	// Tag the record with the request, session and trace it belongs to
+	requestID, hasRequest := RequestIDFromContext(ctx)
+	spanContext, hasSpan := trace.SpanContextFromContext(ctx)
+	if hasRequest || hasClient || hasSpan {
+		r = r.Clone()
+		if hasRequest {
+			r.AddAttrs(slog.String("request_id", requestID))
+		}
+		if hasClient {
+			r.AddAttrs(slog.String("session_id", client.id))
+		}
+		if hasSpan {
+			r.AddAttrs(slog.String("trace_id", spanContext.TraceID.String()))
+		}
+	}
+
+	var err error
+	if h.base.Enabled(ctx, r.Level) {
+		err = h.base.Handle(ctx, r)
+	}
+
+	if hasClient && h.clientsEnabled(r.Level) {
+		notification := models.JSONRPCNotification{
+			JSONRPC: models.JSONRPCVersion,
+			Method:  models.NotificationMessage,
+			Params: models.LoggingMessageParams{
+				Level:  logLevelName(r.Level),
+				Logger: models.ServerName,
+				Data:   h.recordData(r),
+			},
+		}
+		if client.wants(notification) {
+			client.send(notification)
+		}
+	}
+
+	return err
+}
+
+// recordData converts a record to the data of a log message notification
+func (h *clientLogHandler) recordData(r slog.Record) map[string]interface{} {
+	data := map[string]interface{}{"message": r.Message}
+	for _, a := range h.attrs {
+		data[a.Key] = attrValue(a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		data[groupKey(h.group, a.Key)] = attrValue(a)
+		return true
+	})
+	return data
+}
+
+// attrValue returns the JSON-friendly value of an attribute
+func attrValue(a slog.Attr) interface{} {
+	v := a.Value.Resolve().Any()
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	return v
+}
+
+// groupKey qualifies key with a dotted group prefix
+func groupKey(group, key string) string {
+	if group == "" {
+		return key
+	}
+	return group + "." + key
+}
+
+func (h *clientLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.base = h.base.WithAttrs(attrs)
+	clone.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, a := range attrs {
+		clone.attrs = append(clone.attrs, slog.Attr{Key: groupKey(h.group, a.Key), Value: a.Value})
+	}
+	return &clone
+}
+
+func (h *clientLogHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.base = h.base.WithGroup(name)
+	clone.group = groupKey(h.group, name)
+	return &clone
+}
+
+// noClientLogLevel is the minimum client level before any client has
+// enabled logging
+const noClientLogLevel = math.MaxInt64
+
+// SetLogger sets the logger the server logs to; nil, the default, uses
+// slog.Default. Wrap the logger's handler with LogHandler to also send the
+// records to clients.
+func (s *MCPServer) SetLogger(logger *slog.Logger) {
+	s.mu.Lock()
+	s.logger = logger
+	s.mu.Unlock()
+}
+
+// log returns the logger the server logs to
+func (s *MCPServer) log() *slog.Logger {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.logger == nil {
+		return slog.Default()
+	}
+	return s.logger
+}
+
+// logRequest logs a handled request: at debug level if it succeeded, and at
+// info level with the error if it failed
+func (s *MCPServer) logRequest(ctx context.Context, req models.JSONRPCRequest, started time.Time, err error) {
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.Any("rpc_id", req.ID),
+		slog.Duration("duration", time.Since(started)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		s.log().LogAttrs(ctx, slog.LevelInfo, "Request failed", attrs...)
+		return
+	}
+	s.log().LogAttrs(ctx, slog.LevelDebug, "Request handled", attrs...)
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+func TestLogRecordsReachOnlyTheirSession(t *testing.T) {
+	s := NewEmptyMCPServer()
+	var broadcast []models.JSONRPCNotification
+	defer s.AddNotifier(func(n models.JSONRPCNotification) { broadcast = append(broadcast, n) })()
+
+	var received []models.JSONRPCNotification
+	client := newClientSession("a", func(n models.JSONRPCNotification) { received = append(received, n) })
+	client.initialized.Store(true)
+	client.setLogLevel(slog.LevelDebug)
+	s.lowerClientLogLevel(slog.LevelDebug)
+	logger := slog.New(s.LogHandler(slog.NewTextHandler(io.Discard, nil)))
+
+	// The server's own records, such as those of a reload, stay in its log
+	logger.Info("Configuration reloaded")
+	logger.InfoContext(withRequestID(context.Background()), "Sessionless request")
+	if len(received) != 0 || len(broadcast) != 0 {
+		t.Errorf("records without a session sent: %v to the client, %v to every client", received, broadcast)
+	}
+
+	logger.InfoContext(withClientSession(context.Background(), client), "Client request")
+	if len(received) != 1 || len(broadcast) != 0 {
+		t.Fatalf("client record sent: %v to the client, %v to every client, want only to the client", received, broadcast)
+	}
+	if data := received[0].Params.(models.LoggingMessageParams).Data.(map[string]interface{}); data["message"] != "Client request" {
+		t.Errorf("client got %v", data)
+	}
+}
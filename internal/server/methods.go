@@ -0,0 +1,240 @@
+package server
+
+import (
+	"context"
+	"sort"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+// OpenRPCVersion is the version of the OpenRPC specification followed by
+// the rpc.discover document
+const OpenRPCVersion = "1.3.2"
+
+// rpcMethod is a JSON-RPC method served by MCPServer, with the description
+// published by rpc.discover
+type rpcMethod struct {
+	name    string
+	summary string
+	// params of tools/call are filled in by rpc.discover, since they
+	// depend on the tools the caller may see
+	params []contentDescriptor
+	result contentDescriptor
+	handle func(s *MCPServer, ctx context.Context, params interface{}) (interface{}, error)
+}
+
+// contentDescriptor is an OpenRPC content descriptor: a named parameter or
+// result and its schema
+type contentDescriptor struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Required    bool                   `json:"required,omitempty"`
+	Schema      map[string]interface{} `json:"schema"`
+}
+
+// rpcMethods returns the JSON-RPC methods in the order rpc.discover lists
+// them
+func rpcMethods() []rpcMethod {
+	return []rpcMethod{
+		{
+			name:    "initialize",
+			summary: "Start a session and exchange capabilities",
+			params: []contentDescriptor{
+				{Name: "protocolVersion", Description: "MCP protocol version the client speaks", Required: true, Schema: schemaOf("")},
+				{Name: "capabilities", Description: "Client capabilities", Schema: schemaOf(map[string]interface{}{})},
+				{Name: "clientInfo", Description: "Client name and version", Schema: schemaOf(models.ClientInfo{})},
+			},
+			result: contentDescriptor{Name: "initializeResult", Schema: schemaOf(models.InitializeResult{})},
+			handle: (*MCPServer).handleInitialize,
+		},
+		{
+			name:    "resources/list",
+			summary: "List the resources the caller may read",
+			result: contentDescriptor{Name: "resources", Schema: objectSchema(map[string]interface{}{
+				"resources": schemaOf([]models.Resource{}),
+			})},
+			handle: (*MCPServer).handleListResources,
+		},
+		{
+			name:    "resources/read",
+			summary: "Read a resource, or list a directory of a search root",
+			params: []contentDescriptor{
+				{Name: "uri", Description: "URI of the resource", Required: true, Schema: schemaOf("")},
+			},
+			result: contentDescriptor{Name: "contents", Schema: objectSchema(map[string]interface{}{
+				"contents": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"uri":      schemaOf(""),
+							"mimeType": schemaOf(""),
+							"text":     schemaOf(""),
+							"blob":     schemaOf([]byte{}),
+						},
+						"required": []string{"uri"},
+					},
+				},
+			})},
+			handle: (*MCPServer).handleReadResource,
+		},
+		{
+			name:    "resources/subscribe",
+			summary: "Receive notifications/resources/updated for a resource; search roots are updated when their index is rebuilt",
+			params: []contentDescriptor{
+				{Name: "uri", Description: "URI of the resource", Required: true, Schema: schemaOf("")},
+			},
+			result: contentDescriptor{Name: "empty", Schema: schemaOf(map[string]interface{}{})},
+			handle: (*MCPServer).handleSubscribe,
+		},
+		{
+			name:    "resources/unsubscribe",
+			summary: "Stop notifications for a resource",
+			params: []contentDescriptor{
+				{Name: "uri", Description: "URI of the resource", Required: true, Schema: schemaOf("")},
+			},
+			result: contentDescriptor{Name: "empty", Schema: schemaOf(map[string]interface{}{})},
+			handle: (*MCPServer).handleUnsubscribe,
+		},
+		{
+			name:    "tools/list",
+			summary: "List the tools the caller may call",
+			result: contentDescriptor{Name: "tools", Schema: objectSchema(map[string]interface{}{
+				"tools": schemaOf([]models.Tool{}),
+			})},
+			handle: (*MCPServer).handleListTools,
+		},
+		{
+			name:    "tools/call",
+			summary: "Call a tool",
+			result: contentDescriptor{Name: "toolResult", Schema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"content":           schemaOf([]map[string]interface{}{}),
+					"structuredContent": schemaOf(map[string]interface{}{}),
+					"isError":           schemaOf(false),
+				},
+				"required": []string{"content"},
+			}},
+			handle: (*MCPServer).handleCallTool,
+		},
+		{
+			name:    "logging/setLevel",
+			summary: "Set the lowest level of log messages sent to the session",
+			params: []contentDescriptor{
+				{Name: "level", Required: true, Schema: logLevelSchema()},
+			},
+			result: contentDescriptor{Name: "empty", Schema: schemaOf(map[string]interface{}{})},
+			handle: (*MCPServer).handleSetLevel,
+		},
+		{
+			name:    "rpc.discover",
+			summary: "Describe the methods and tools of this server as an OpenRPC document",
+			result: contentDescriptor{Name: "openrpcDocument", Schema: map[string]interface{}{
+				"$ref": "https://raw.githubusercontent.com/open-rpc/meta-schema/master/schema.json",
+			}},
+			handle: (*MCPServer).handleDiscover,
+		},
+	}
+}
+
+// findMethod returns the method with the given name
+func (s *MCPServer) findMethod(name string) (rpcMethod, bool) {
+	for _, method := range s.methods {
+		if method.name == name {
+			return method, true
+		}
+	}
+	return rpcMethod{}, false
+}
+
+// objectSchema returns the schema of an object with the given properties,
+// all required
+func objectSchema(properties map[string]interface{}) map[string]interface{} {
+	required := make([]string, 0, len(properties))
+	for name := range properties {
+		required = append(required, name)
+	}
+	sort.Strings(required)
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// logLevelSchema enumerates the MCP log level names
+func logLevelSchema() map[string]interface{} {
+	names := make([]string, len(logLevels))
+	for i, level := range logLevels {
+		names[i] = level.name
+	}
+	return map[string]interface{}{"type": "string", "enum": names}
+}
+
+// handleDiscover returns an OpenRPC document describing every method and
+// the input schema of every tool visible to the caller. It needs no
+// initialize, so that clients can be generated from a fresh connection.
+func (s *MCPServer) handleDiscover(ctx context.Context, params interface{}) (interface{}, error) {
+	tools := s.visibleTools(ctx)
+	names := make([]string, len(tools))
+	arguments := make([]interface{}, len(tools))
+	schemas := map[string]interface{}{}
+	for i, tool := range tools {
+		names[i] = tool.Name
+		arguments[i] = map[string]interface{}{"$ref": "#/components/schemas/" + tool.Name}
+
+		schema := map[string]interface{}{}
+		for key, value := range tool.InputSchema {
+			schema[key] = value
+		}
+		schema["title"] = tool.Name
+		if tool.Description != "" {
+			schema["description"] = tool.Description
+		}
+		if tool.IsReadOnly() {
+			schema["readOnly"] = true
+		}
+		schemas[tool.Name] = schema
+	}
+
+	methods := make([]map[string]interface{}, 0, len(s.methods))
+	for _, method := range s.methods {
+		params := method.params
+		if method.name == "tools/call" {
+			params = []contentDescriptor{
+				{Name: "name", Description: "Name of the tool", Required: true, Schema: map[string]interface{}{"type": "string", "enum": names}},
+				{Name: "arguments", Description: "Arguments matching the tool's input schema", Schema: map[string]interface{}{"oneOf": arguments}},
+				{Name: "_meta", Description: "Request metadata; a progressToken asks for progress notifications", Schema: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"progressToken": map[string]interface{}{"type": []string{"string", "integer"}},
+					},
+				}},
+			}
+		}
+		if params == nil {
+			params = []contentDescriptor{}
+		}
+		methods = append(methods, map[string]interface{}{
+			"name":           method.name,
+			"summary":        method.summary,
+			"paramStructure": "by-name",
+			"params":         params,
+			"result":         method.result,
+		})
+	}
+
+	return map[string]interface{}{
+		"openrpc": OpenRPCVersion,
+		"info": map[string]interface{}{
+			"title":       models.ServerName,
+			"version":     models.ServerVersion,
+			"description": "Model Context Protocol " + models.MCPProtocolVersion + " server for searching files",
+		},
+		"methods": methods,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+		},
+	}, nil
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/metrics"
+)
+
+// knownMethods are the JSON-RPC methods reported by name in metrics. Any
+// other method is reported as "other" so that clients cannot create
+// unbounded label values.
+var knownMethods = map[string]bool{
+	"initialize":            true,
+	"resources/list":        true,
+	"resources/read":        true,
+	"resources/subscribe":   true,
+	"resources/unsubscribe": true,
+	"tools/list":            true,
+	"tools/call":            true,
+	"logging/setLevel":      true,
+	"rpc.discover":          true,
+}
+
+// serverMetrics instruments request handling
+type serverMetrics struct {
+	registry     *metrics.Registry
+	requests     *metrics.Counter
+	duration     *metrics.Histogram
+	toolCalls    *metrics.Counter
+	toolDuration *metrics.Histogram
+	errors       *metrics.Counter
+	inFlight     *metrics.Gauge
+	bytesScanned *metrics.Counter
+}
+
+// newServerMetrics registers the server's metrics, including index size
+// gauges computed from the active file search handler and the depth of the
+// file watcher's queue
+func newServerMetrics(s *MCPServer) *serverMetrics {
+	reg := metrics.NewRegistry()
+	m := &serverMetrics{
+		registry:     reg,
+		requests:     reg.NewCounter("mcp_requests_total", "JSON-RPC requests handled, by method.", "method"),
+		duration:     reg.NewHistogram("mcp_request_duration_seconds", "Time to handle a JSON-RPC request, by method.", metrics.DefaultBuckets, "method"),
+		toolCalls:    reg.NewCounter("mcp_tool_calls_total", "Tool calls, by tool and outcome.", "tool", "outcome"),
+		toolDuration: reg.NewHistogram("mcp_tool_call_duration_seconds", "Time to execute a tool call, by tool.", metrics.DefaultBuckets, "tool"),
+		errors:       reg.NewCounter("mcp_errors_total", "JSON-RPC error responses, by error code.", "code"),
+		inFlight:     reg.NewGauge("mcp_in_flight_requests", "JSON-RPC requests currently being handled."),
+		bytesScanned: reg.NewCounter("mcp_search_bytes_scanned_total", "Bytes of file content scanned by content searches."),
+	}
+
+	indexStats := func(value func(filesearch.IndexStats) float64) func() []metrics.Sample {
+		return func() []metrics.Sample {
+			files := s.FileSearch()
+			if files == nil || files.Engine().Index() == nil {
+				return nil
+			}
+			var samples []metrics.Sample
+			for _, stats := range files.Engine().Index().Stats() {
+				samples = append(samples, metrics.Sample{LabelValues: []string{stats.Root}, Value: value(stats)})
+			}
+			return samples
+		}
+	}
+	reg.NewGaugeFunc("mcp_index_files", "Files in the index, by root.", []string{"root"},
+		indexStats(func(stats filesearch.IndexStats) float64 { return float64(stats.Files) }))
+	reg.NewGaugeFunc("mcp_index_bytes", "Total size of the indexed files, by root.", []string{"root"},
+		indexStats(func(stats filesearch.IndexStats) float64 { return float64(stats.Bytes) }))
+	reg.NewGaugeFunc("mcp_watcher_queue_depth", "File changes found by the watcher and not yet notified.", nil,
+		func() []metrics.Sample {
+			watcher := s.fileWatcher()
+			if watcher == nil {
+				return nil
+			}
+			return []metrics.Sample{{Value: float64(watcher.QueueDepth())}}
+		})
+
+	return m
+}
+
+// Metrics returns the registry holding the server's metrics
+func (s *MCPServer) Metrics() *metrics.Registry {
+	return s.metrics.registry
+}
+
+// methodLabel returns the metrics label for a JSON-RPC method
+func methodLabel(method string) string {
+	if knownMethods[method] {
+		return method
+	}
+	return "other"
+}
+
+// observeRequest records a handled request
+func (m *serverMetrics) observeRequest(method string, started time.Time) {
+	label := methodLabel(method)
+	m.requests.Inc(label)
+	m.duration.Observe(time.Since(started).Seconds(), label)
+}
+
+// observeToolCall records a tool call and the bytes it scanned
+func (m *serverMetrics) observeToolCall(tool string, started time.Time, result interface{}, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	m.toolCalls.Inc(tool, outcome)
+	m.toolDuration.Observe(time.Since(started).Seconds(), tool)
+
+	if content, ok := result.(map[string]interface{}); ok {
+		if grep, ok := content["structuredContent"].(*filesearch.GrepResult); ok {
+			m.bytesScanned.Add(float64(grep.BytesScanned))
+		}
+	}
+}
+
+// observeError records a JSON-RPC error response
+func (m *serverMetrics) observeError(code int) {
+	m.errors.Inc(strconv.Itoa(code))
+}
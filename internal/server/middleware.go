@@ -0,0 +1,287 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"runtime/debug"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/glob"
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+// MethodHandler runs a JSON-RPC request and returns its result
+type MethodHandler func(ctx context.Context, req models.JSONRPCRequest) (interface{}, error)
+
+// MethodMiddleware wraps the handling of every request, for concerns such
+// as logging, metrics and access checks that apply to all methods
+type MethodMiddleware func(next MethodHandler) MethodHandler
+
+// ToolCallHandler runs a tool with the arguments of a tools/call request
+// and returns its result
+type ToolCallHandler func(ctx context.Context, name string, args map[string]interface{}) (interface{}, error)
+
+// ToolMiddleware wraps every tool call, after the caller has been
+// authorized to make it
+type ToolMiddleware func(next ToolCallHandler) ToolCallHandler
+
+// Timeouts bounds how long requests may run. Methods and Tools are tried
+// in order and the first entry whose pattern matches the method or tool
+// name applies; methods matching none get Default. Zero means no limit.
+// Tool calls are bounded by both their method's and their tool's limit.
+type Timeouts struct {
+	Default time.Duration
+	Methods []Timeout
+	Tools   []Timeout
+}
+
+// Timeout limits the methods or tools whose names match a glob pattern
+type Timeout struct {
+	Pattern string
+	Limit   time.Duration
+}
+
+// limit returns the limit for name, which is def if no entry matches
+func limit(entries []Timeout, name string, def time.Duration) time.Duration {
+	for _, entry := range entries {
+		if glob.Match(entry.Pattern, name) {
+			return entry.Limit
+		}
+	}
+	return def
+}
+
+// rpcError is a handler error reported with its own JSON-RPC error code
+type rpcError struct {
+	code int
+	err  error
+}
+
+func (e *rpcError) Error() string { return e.err.Error() }
+func (e *rpcError) Unwrap() error { return e.err }
+
+// errMethodNotFound is returned for requests whose method does not exist
+var errMethodNotFound = errors.New("method not found")
+
+// errorCode returns the JSON-RPC error code reported for err. Errors the
+// caller did not cause are internal errors.
+func errorCode(err error) int {
+	var rpcErr *rpcError
+	switch {
+	case errors.As(err, &rpcErr):
+		return rpcErr.code
+	case errors.Is(err, context.DeadlineExceeded):
+		return models.ErrCodeTimeout
+	case errors.Is(err, errMethodNotFound), errors.Is(err, errUnknownTool):
+		return models.ErrCodeMethodNotFound
+	case errors.Is(err, filesearch.ErrNotPermitted):
+		return models.ErrCodeInvalidRequest
+	case errors.Is(err, filesearch.ErrInvalidArgument), errors.Is(err, filesearch.ErrInvalidPath),
+		errors.Is(err, filesearch.ErrUnknownRoot), errors.Is(err, fs.ErrNotExist), errors.Is(err, errRootExists):
+		return models.ErrCodeInvalidParams
+	default:
+		return models.ErrCodeInternalError
+	}
+}
+
+// invalidParams is reported for a request whose params are missing or
+// wrong
+func invalidParams(format string, args ...interface{}) error {
+	return &rpcError{code: models.ErrCodeInvalidParams, err: fmt.Errorf(format, args...)}
+}
+
+// invalidRequest is reported for a request the caller may not make in its
+// current state, such as before initialize
+func invalidRequest(format string, args ...interface{}) error {
+	return &rpcError{code: models.ErrCodeInvalidRequest, err: fmt.Errorf(format, args...)}
+}
+
+// UseMethodMiddleware adds middleware around the handling of every request.
+// Middleware runs in the order added, inside panic recovery and the
+// request's time limit.
+func (s *MCPServer) UseMethodMiddleware(mw ...MethodMiddleware) {
+	s.mu.Lock()
+	s.methodMiddleware = append(s.methodMiddleware, mw...)
+	s.mu.Unlock()
+}
+
+// UseToolMiddleware adds middleware around every tool call. Middleware runs
+// in the order added, inside panic recovery and the tool's time limit.
+func (s *MCPServer) UseToolMiddleware(mw ...ToolMiddleware) {
+	s.mu.Lock()
+	s.toolMiddleware = append(s.toolMiddleware, mw...)
+	s.mu.Unlock()
+}
+
+// SetTimeouts replaces the time limits of requests and tool calls
+func (s *MCPServer) SetTimeouts(timeouts Timeouts) {
+	s.mu.Lock()
+	s.timeouts = timeouts
+	s.mu.Unlock()
+}
+
+// methodChain returns the handler of requests: metrics and logging, time
+// limits and panic recovery around the added middleware and the method
+func (s *MCPServer) methodChain() MethodHandler {
+	s.mu.RLock()
+	middleware, timeouts := s.methodMiddleware, s.timeouts
+	s.mu.RUnlock()
+
+	handler := s.dispatch
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	handler = s.recoverMethod(handler)
+	handler = limitMethod(timeouts, handler)
+	return s.observeRequests(handler)
+}
+
+// toolChain returns the handler of tool calls: time limits and panic
+// recovery around the added middleware and the tool
+func (s *MCPServer) toolChain() ToolCallHandler {
+	s.mu.RLock()
+	middleware, timeouts := s.toolMiddleware, s.timeouts
+	s.mu.RUnlock()
+
+	handler := s.callTool
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	handler = s.recoverTool(handler)
+	return limitTool(timeouts, handler)
+}
+
+// dispatch runs the handler of a request's method
+func (s *MCPServer) dispatch(ctx context.Context, req models.JSONRPCRequest) (interface{}, error) {
+	method, ok := s.findMethod(req.Method)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errMethodNotFound, req.Method)
+	}
+	return method.handle(s, ctx, req.Params)
+}
+
+// observeRequests records the metrics of every request and logs it
+func (s *MCPServer) observeRequests(next MethodHandler) MethodHandler {
+	return func(ctx context.Context, req models.JSONRPCRequest) (interface{}, error) {
+		started := time.Now()
+		s.metrics.inFlight.Inc()
+		defer s.metrics.inFlight.Dec()
+
+		result, err := next(ctx, req)
+		if err != nil {
+			s.metrics.observeError(errorCode(err))
+		}
+		s.metrics.observeRequest(req.Method, started)
+		s.logRequest(ctx, req, started, err)
+		return result, err
+	}
+}
+
+// recoverMethod turns a panic while handling a request into an internal
+// error, so that one bad request cannot take down the process
+func (s *MCPServer) recoverMethod(next MethodHandler) MethodHandler {
+	return func(ctx context.Context, req models.JSONRPCRequest) (result interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				s.log().ErrorContext(ctx, "Panic handling request", "method", req.Method, "panic", p, "stack", string(debug.Stack()))
+				result, err = nil, internalError(req.Method)
+			}
+		}()
+		return next(ctx, req)
+	}
+}
+
+// recoverTool turns a panic in a tool into an internal error
+func (s *MCPServer) recoverTool(next ToolCallHandler) ToolCallHandler {
+	return func(ctx context.Context, name string, args map[string]interface{}) (result interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				s.log().ErrorContext(ctx, "Panic calling tool", "tool", name, "panic", p, "stack", string(debug.Stack()))
+				result, err = nil, internalError("tool "+name)
+			}
+		}()
+		return next(ctx, name, args)
+	}
+}
+
+// internalError is reported for a panic; its details stay in the log
+func internalError(what string) error {
+	return &rpcError{code: models.ErrCodeInternalError, err: fmt.Errorf("internal error in %s", what)}
+}
+
+// limitMethod answers requests that run past their method's time limit
+// with a timeout error and cancels them
+func limitMethod(timeouts Timeouts, next MethodHandler) MethodHandler {
+	return func(ctx context.Context, req models.JSONRPCRequest) (interface{}, error) {
+		d := limit(timeouts.Methods, req.Method, timeouts.Default)
+		if d <= 0 {
+			return next(ctx, req)
+		}
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+		result, err := runUntilDone(ctx, func() (interface{}, error) { return next(ctx, req) })
+		return result, timeoutError(ctx, err, req.Method, d)
+	}
+}
+
+// limitTool answers tool calls that run past their tool's time limit with
+// a timeout error and cancels them
+func limitTool(timeouts Timeouts, next ToolCallHandler) ToolCallHandler {
+	return func(ctx context.Context, name string, args map[string]interface{}) (interface{}, error) {
+		d := limit(timeouts.Tools, name, 0)
+		if d <= 0 {
+			return next(ctx, name, args)
+		}
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+		result, err := runUntilDone(ctx, func() (interface{}, error) { return next(ctx, name, args) })
+		return result, timeoutError(ctx, err, "tool "+name, d)
+	}
+}
+
+// runUntilDone returns what call returns, or ctx's error as soon as ctx is
+// done. A call still running then goes on until it notices the
+// cancellation, and its result is dropped.
+func runUntilDone(ctx context.Context, call func() (interface{}, error)) (interface{}, error) {
+	type outcome struct {
+		result interface{}
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := call()
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.result, out.err
+	case <-ctx.Done():
+		// A call that finished at the deadline keeps its result
+		select {
+		case out := <-done:
+			return out.result, out.err
+		default:
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// timeoutError reports err as a timeout if it came from ctx running out of
+// time
+func timeoutError(ctx context.Context, err error, what string, d time.Duration) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	var rpcErr *rpcError
+	if errors.As(err, &rpcErr) && rpcErr.code == models.ErrCodeTimeout {
+		return err
+	}
+	return &rpcError{
+		code: models.ErrCodeTimeout,
+		err:  fmt.Errorf("%s timed out after %s: %w", what, d, context.DeadlineExceeded),
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"unknown method", fmt.Errorf("%w: no/such", errMethodNotFound), models.ErrCodeMethodNotFound},
+		{"unknown tool", fmt.Errorf("%w: nope", errUnknownTool), models.ErrCodeMethodNotFound},
+		{"invalid params", invalidParams("text argument required"), models.ErrCodeInvalidParams},
+		{"invalid argument", fmt.Errorf("%w: query required", filesearch.ErrInvalidArgument), models.ErrCodeInvalidParams},
+		{"invalid path", fmt.Errorf("%w: a/../b", filesearch.ErrInvalidPath), models.ErrCodeInvalidParams},
+		{"missing file", fmt.Errorf("reading: %w", fs.ErrNotExist), models.ErrCodeInvalidParams},
+		{"not initialized", invalidRequest("server not initialized"), models.ErrCodeInvalidRequest},
+		{"not permitted", fmt.Errorf("tool x %w", filesearch.ErrNotPermitted), models.ErrCodeInvalidRequest},
+		{"deadline", fmt.Errorf("searching: %w", context.DeadlineExceeded), models.ErrCodeTimeout},
+		{"timeout", timeoutError(expired(t), errors.New("slow"), "tool x", 0), models.ErrCodeTimeout},
+		{"panic", internalError("tool x"), models.ErrCodeInternalError},
+		{"unexpected", errors.New("disk on fire"), models.ErrCodeInternalError},
+	}
+
+	for _, tt := range tests {
+		if got := errorCode(tt.err); got != tt.want {
+			t.Errorf("%s: errorCode(%v) = %d, want %d", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+// expired returns a context whose deadline has passed
+func expired(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	t.Cleanup(cancel)
+	<-ctx.Done()
+	return ctx
+}
+
+func TestTimeoutAnswersAtDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	// The handler ignores its context and only returns when released
+	stuck := func(ctx context.Context, req models.JSONRPCRequest) (interface{}, error) {
+		<-release
+		return "late", nil
+	}
+	handler := limitMethod(Timeouts{Default: 20 * time.Millisecond}, stuck)
+
+	started := time.Now()
+	result, err := handler(context.Background(), models.JSONRPCRequest{Method: "tools/call"})
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("answered after %v, want at the 20ms deadline", elapsed)
+	}
+	if result != nil || errorCode(err) != models.ErrCodeTimeout || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, %v, want a timeout error", result, err)
+	}
+}
+
+func TestTimeoutKeepsResultWithinLimit(t *testing.T) {
+	handler := limitTool(Timeouts{Tools: []Timeout{{Pattern: "*", Limit: time.Minute}}},
+		func(ctx context.Context, name string, args map[string]interface{}) (interface{}, error) {
+			return name, nil
+		})
+	if result, err := handler(context.Background(), "echo", nil); result != "echo" || err != nil {
+		t.Errorf("got %v, %v, want echo, nil", result, err)
+	}
+}
+
+func TestPanicInTimedToolIsRecovered(t *testing.T) {
+	s := NewEmptyMCPServer()
+	s.SetTimeouts(Timeouts{Tools: []Timeout{{Pattern: "*", Limit: time.Minute}}})
+	s.UseToolMiddleware(func(next ToolCallHandler) ToolCallHandler {
+		return func(ctx context.Context, name string, args map[string]interface{}) (interface{}, error) {
+			panic("boom")
+		}
+	})
+	if _, err := s.toolChain()(context.Background(), "echo", nil); errorCode(err) != models.ErrCodeInternalError {
+		t.Errorf("error = %v, want an internal error", err)
+	}
+}
@@ -0,0 +1,546 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/auth"
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+// OpenAPIPath serves the OpenAPI document of the HTTP endpoints
+const OpenAPIPath = "/openapi.json"
+
+// OpenAPIVersion is the version of the OpenAPI specification followed by
+// the document at OpenAPIPath
+const OpenAPIVersion = "3.1.0"
+
+// route is an HTTP endpoint and the operations it publishes in the OpenAPI
+// document. Routes without operations are served but not documented.
+type route struct {
+	pattern    string
+	handler    func(http.ResponseWriter, *http.Request)
+	operations []operation
+}
+
+// operation describes one method of an endpoint
+type operation struct {
+	method string
+	// path is the OpenAPI path template, if it differs from the pattern
+	path      string
+	tag       string
+	summary   string
+	params    []parameter
+	body      map[string]interface{}
+	responses []response
+	// admin operations require the admin scope and may respond 403
+	admin bool
+}
+
+// parameter is a query, path or header parameter of an operation
+type parameter struct {
+	name        string
+	in          string
+	description string
+	required    bool
+	schema      map[string]interface{}
+}
+
+// response is one response of an operation. Responses without a schema
+// have no body.
+type response struct {
+	status      int
+	description string
+	mediaType   string
+	schema      map[string]interface{}
+	headers     []parameter
+}
+
+// jsonResponse is a response with a JSON body
+func jsonResponse(status int, description string, schema map[string]interface{}) response {
+	return response{status: status, description: description, mediaType: "application/json", schema: schema}
+}
+
+// errorResponse is a response carrying an Error body
+func errorResponse(status int, description string) response {
+	return jsonResponse(status, description, schemaRef("Error"))
+}
+
+// schemaRef refers to a schema of the document's components
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// listOf is the schema of an object holding an array of the named schema
+// under key
+func listOf(key, name string) map[string]interface{} {
+	return objectSchema(map[string]interface{}{
+		key: map[string]interface{}{"type": "array", "items": schemaRef(name)},
+	})
+}
+
+// queryParam is an optional query parameter
+func queryParam(name, description string, schema map[string]interface{}) parameter {
+	return parameter{name: name, in: "query", description: description, schema: schema}
+}
+
+// pathParam is a path parameter
+func pathParam(name, description string) parameter {
+	return parameter{name: name, in: "path", description: description, required: true, schema: schemaOf("")}
+}
+
+// formatParam chooses the format of a REST response
+var formatParam = queryParam("format", "Response format; overrides the Accept header", map[string]interface{}{
+	"type": "string",
+	"enum": []string{formatJSON, formatNDJSON, formatText},
+})
+
+// restResponses are the responses every REST operation may give besides
+// its result
+var restResponses = []response{
+	errorResponse(http.StatusBadRequest, "Invalid request"),
+	errorResponse(http.StatusForbidden, "Denied by the caller's permissions or the access policy"),
+	errorResponse(http.StatusNotFound, "Unknown root or file"),
+	errorResponse(http.StatusNotAcceptable, "None of the accepted media types is supported"),
+	errorResponse(http.StatusTooManyRequests, "Rate limit or read quota exceeded"),
+}
+
+// rest returns the responses of a REST operation: its result, which is
+// also offered as NDJSON and text, and the common errors
+func rest(description string, ok map[string]interface{}) []response {
+	return append([]response{
+		jsonResponse(http.StatusOK, description, ok),
+	}, restResponses...)
+}
+
+// routes returns every HTTP endpoint. setupRoutes registers them and the
+// OpenAPI document is generated from them, so the two cannot drift apart.
+func (h *HTTPMCPServer) routes() []route {
+	sessionHeader := parameter{name: SessionIDHeader, in: "header", description: "Session to run in", schema: schemaOf("")}
+	checks := objectSchema(map[string]interface{}{
+		"status": schemaOf(""),
+		"ready":  schemaOf(false),
+		"checks": map[string]interface{}{"type": "object", "additionalProperties": schemaRef("CheckResult")},
+	})
+
+	return []route{
+		// Main MCP endpoint - handles all MCP protocol requests
+		{"/mcp", h.handleMCPRequest, []operation{
+			{
+				method:  "POST",
+				tag:     "mcp",
+				summary: "Send a JSON-RPC request or batch; rpc.discover describes the methods",
+				params:  []parameter{sessionHeader},
+				body: map[string]interface{}{"oneOf": []interface{}{
+					schemaRef("JSONRPCRequest"),
+					map[string]interface{}{"type": "array", "items": schemaRef("JSONRPCRequest")},
+				}},
+				responses: []response{
+					{
+						status:      http.StatusOK,
+						description: "JSON-RPC response or batch of responses",
+						mediaType:   "application/json",
+						schema: map[string]interface{}{"oneOf": []interface{}{
+							schemaRef("JSONRPCResponse"),
+							map[string]interface{}{"type": "array", "items": schemaRef("JSONRPCResponse")},
+						}},
+						headers: []parameter{{name: SessionIDHeader, description: "Session started by initialize", schema: schemaOf("")}},
+					},
+					{status: http.StatusRequestEntityTooLarge, description: "Request body too large"},
+				},
+			},
+			{
+				method:  "GET",
+				tag:     "mcp",
+				summary: "Open the session's stream of server notifications",
+				params:  []parameter{{name: SessionIDHeader, in: "header", required: true, schema: schemaOf("")}},
+				responses: []response{
+					{status: http.StatusOK, description: "Server-sent events, one JSON-RPC notification each", mediaType: "text/event-stream", schema: schemaOf("")},
+					errorResponse(http.StatusNotFound, "Unknown session"),
+				},
+			},
+			{
+				method:  "DELETE",
+				tag:     "mcp",
+				summary: "End the session",
+				params:  []parameter{{name: SessionIDHeader, in: "header", required: true, schema: schemaOf("")}},
+				responses: []response{
+					{status: http.StatusNoContent, description: "Session ended"},
+					errorResponse(http.StatusNotFound, "Unknown session"),
+				},
+			},
+		}},
+
+		// Health check endpoints: liveness, and readiness by subsystem
+		{"/health", h.HealthCheckHandler, []operation{
+			{method: "GET", tag: "health", summary: "Report that the server is up", responses: []response{
+				jsonResponse(http.StatusOK, "Healthy", objectSchema(map[string]interface{}{"status": schemaOf(""), "server": schemaOf("")})),
+			}},
+		}},
+		{"/healthz", h.HealthzHandler, []operation{
+			{method: "GET", tag: "health", summary: "Report that the process is alive", responses: []response{
+				jsonResponse(http.StatusOK, "Alive", objectSchema(map[string]interface{}{"status": schemaOf(""), "uptime": schemaOf("")})),
+			}},
+		}},
+		{"/readyz", h.ReadyzHandler, []operation{
+			{method: "GET", tag: "health", summary: "Check every subsystem", responses: []response{
+				jsonResponse(http.StatusOK, "Ready", checks),
+				jsonResponse(http.StatusServiceUnavailable, "A check failed", checks),
+			}},
+		}},
+
+		// Server information endpoint
+		{"/info", h.InfoHandler, []operation{
+			{method: "GET", tag: "info", summary: "Describe the server", responses: []response{
+				jsonResponse(http.StatusOK, "Server information", schemaOf(map[string]string{})),
+			}},
+		}},
+
+		// Description of these endpoints
+		{OpenAPIPath, h.OpenAPIHandler, []operation{
+			{method: "GET", tag: "info", summary: "This OpenAPI document", responses: []response{
+				jsonResponse(http.StatusOK, "OpenAPI document", schemaOf(map[string]interface{}{})),
+			}},
+		}},
+
+		// Prometheus metrics
+		{"/metrics", h.mcpServer.Metrics().ServeHTTP, []operation{
+			{method: "GET", tag: "observability", summary: "Prometheus metrics", responses: []response{
+				{status: http.StatusOK, description: "Metrics in the Prometheus text format", mediaType: "text/plain", schema: schemaOf("")},
+			}},
+		}},
+
+		// Administration
+		{"/admin/reload", h.AdminReloadHandler, []operation{
+			{method: "POST", tag: "admin", admin: true, summary: "Reload the configuration", responses: []response{
+				jsonResponse(http.StatusOK, "Reloaded", schemaOf(ReloadResult{})),
+				errorResponse(http.StatusNotFound, "Reload is not enabled"),
+				errorResponse(http.StatusUnprocessableEntity, "Invalid configuration; the running one is kept"),
+			}},
+		}},
+		{"/admin/index", h.AdminIndexHandler, []operation{
+			{method: "GET", tag: "admin", admin: true, summary: "Index statistics of every indexed root", responses: []response{
+				jsonResponse(http.StatusOK, "Index statistics", listOf("roots", "IndexStats")),
+				errorResponse(http.StatusNotFound, "Indexing is not enabled"),
+			}},
+		}},
+		{"/admin/reindex", h.AdminReindexHandler, []operation{
+			{
+				method: "POST", tag: "admin", admin: true, summary: "Rebuild the index of one root, or of every root",
+				params: []parameter{queryParam("root", "Root to rebuild; every root if omitted", schemaOf(""))},
+				responses: []response{
+					jsonResponse(http.StatusOK, "Statistics of the rebuilt roots", listOf("roots", "IndexStats")),
+					errorResponse(http.StatusNotFound, "Unknown root, or indexing is not enabled"),
+				},
+			},
+		}},
+		{"/admin/roots", h.AdminRootsHandler, []operation{
+			{method: "GET", tag: "admin", admin: true, summary: "List the search roots", responses: []response{
+				jsonResponse(http.StatusOK, "Search roots", listOf("roots", "Root")),
+			}},
+			{method: "POST", tag: "admin", admin: true, summary: "Add a search root until the next restart or reload", body: schemaRef("Root"), responses: []response{
+				jsonResponse(http.StatusCreated, "Root added, with its path made absolute", schemaRef("Root")),
+				errorResponse(http.StatusConflict, "A root with that name exists"),
+				errorResponse(http.StatusUnprocessableEntity, "Invalid root"),
+			}},
+		}},
+		{"/admin/roots/", h.AdminRootsHandler, []operation{
+			{
+				method: "DELETE", path: "/admin/roots/{name}", tag: "admin", admin: true, summary: "Remove a search root and its index",
+				params: []parameter{pathParam("name", "Root name")},
+				responses: []response{
+					{status: http.StatusNoContent, description: "Root removed"},
+					errorResponse(http.StatusNotFound, "Unknown root"),
+				},
+			},
+		}},
+		{"/admin/sessions", h.AdminSessionsHandler, []operation{
+			{method: "GET", tag: "admin", admin: true, summary: "List the live sessions", responses: []response{
+				jsonResponse(http.StatusOK, "Sessions, oldest first", listOf("sessions", "SessionInfo")),
+			}},
+		}},
+		{"/admin/sessions/", h.AdminSessionsHandler, []operation{
+			{
+				method: "DELETE", path: "/admin/sessions/{id}", tag: "admin", admin: true, summary: "End a session and cancel its calls",
+				params: []parameter{pathParam("id", "Session ID")},
+				responses: []response{
+					{status: http.StatusNoContent, description: "Session ended"},
+					errorResponse(http.StatusNotFound, "Unknown session"),
+				},
+			},
+		}},
+		{"/admin/calls", h.AdminCallsHandler, []operation{
+			{method: "GET", tag: "admin", admin: true, summary: "List the requests in flight", responses: []response{
+				jsonResponse(http.StatusOK, "Calls in flight", listOf("calls", "CallInfo")),
+			}},
+		}},
+		{"/admin/calls/", h.AdminCallsHandler, []operation{
+			{
+				method: "DELETE", path: "/admin/calls/{id}", tag: "admin", admin: true, summary: "Cancel a request in flight",
+				params: []parameter{pathParam("id", "Call ID")},
+				responses: []response{
+					{status: http.StatusNoContent, description: "Call cancelled"},
+					errorResponse(http.StatusNotFound, "Call not in flight"),
+				},
+			},
+		}},
+
+		// Recently recorded traces
+		{"/debug/traces", h.DebugTracesHandler, []operation{
+			{
+				method: "GET", tag: "observability", admin: true, summary: "Recently recorded traces as OTLP/JSON",
+				params: []parameter{
+					queryParam("traceId", "Only the spans of this trace", schemaOf("")),
+					queryParam("limit", "Most recent spans to return", schemaOf(0)),
+				},
+				responses: []response{
+					jsonResponse(http.StatusOK, "OTLP/JSON traces", schemaOf(map[string]interface{}{})),
+					errorResponse(http.StatusNotFound, "Tracing is not enabled"),
+				},
+			},
+		}},
+
+		// OAuth protected resource metadata
+		{auth.ProtectedResourceMetadataPath, h.ResourceMetadataHandler, []operation{
+			{method: "GET", tag: "info", summary: "OAuth protected resource metadata (RFC 9728)", responses: []response{
+				jsonResponse(http.StatusOK, "Metadata", schemaOf(auth.ProtectedResourceMetadata{})),
+				{status: http.StatusNotFound, description: "OAuth is not configured"},
+			}},
+		}},
+
+		// REST API over the file search tools and resources
+		{restSearchPath, h.RESTSearchHandler, []operation{
+			{
+				method: "GET", tag: "rest", summary: "Search file contents for q, or file names for the glob in name",
+				params: []parameter{
+					queryParam("q", "Text or regular expression to search file contents for", schemaOf("")),
+					queryParam("name", "Glob to match file names against, if q is not given", schemaOf("")),
+					queryParam("root", "Root to search; every root if omitted", schemaOf("")),
+					queryParam("ext", "File extensions to search, comma separated or repeated", schemaOf("")),
+					queryParam("include", "Glob restricting the content search to matching paths", schemaOf("")),
+					queryParam("regex", "Treat q as a regular expression", schemaOf(false)),
+					queryParam("case", "Match case", schemaOf(false)),
+					queryParam("context", "Lines of context around each match", schemaOf(0)),
+					queryParam("limit", "Most results to return", schemaOf(0)),
+					formatParam,
+				},
+				responses: rest("Matches, or files for a name search", map[string]interface{}{
+					"oneOf": []interface{}{schemaRef("GrepResult"), schemaRef("FindResult")},
+				}),
+			},
+		}},
+		{restFilesPath, h.RESTFilesHandler, []operation{
+			{
+				method: "GET", path: restFilesPath + "{root}/{path}", tag: "rest", summary: "Read a file; text/plain returns the raw content",
+				params: []parameter{
+					pathParam("root", "Root name"),
+					pathParam("path", "File path relative to the root, which may contain slashes"),
+					formatParam,
+				},
+				responses: rest("File metadata and content", schemaRef("FileContent")),
+			},
+		}},
+		{restTreePath, h.RESTTreeHandler, []operation{
+			{
+				method: "GET", tag: "rest", summary: "List the search roots",
+				params:    []parameter{formatParam},
+				responses: rest("Roots", objectSchema(map[string]interface{}{"roots": schemaOf([]restRoot{})})),
+			},
+			{
+				method: "GET", path: restTreePath + "{root}/{path}", tag: "rest", summary: "List a directory",
+				params: []parameter{
+					pathParam("root", "Root name"),
+					pathParam("path", "Directory path relative to the root, which may contain slashes; empty for the root itself"),
+					formatParam,
+				},
+				responses: rest("Directory entries", objectSchema(map[string]interface{}{
+					"root":    schemaOf(""),
+					"path":    schemaOf(""),
+					"entries": map[string]interface{}{"type": "array", "items": schemaRef("FileInfo")},
+				})),
+			},
+		}},
+
+		// Search UI assets
+		{uiPrefix, h.UIAssetHandler, nil},
+
+		// Root endpoint with the search UI for browsers and basic info otherwise
+		{"/", h.RootHandler, []operation{
+			{method: "GET", tag: "info", summary: "The search UI for browsers, or basic information about the server", responses: []response{
+				jsonResponse(http.StatusOK, "Server information", schemaOf(map[string]interface{}{})),
+			}},
+		}},
+	}
+}
+
+// openAPISchemas are the named schemas of the OpenAPI document
+func openAPISchemas() map[string]interface{} {
+	return map[string]interface{}{
+		"Error": objectSchema(map[string]interface{}{
+			"error":   schemaOf(""),
+			"message": schemaOf(""),
+		}),
+		"JSONRPCRequest":  schemaOf(models.JSONRPCRequest{}),
+		"JSONRPCResponse": schemaOf(models.JSONRPCResponse{}),
+		"CheckResult":     schemaOf(CheckResult{}),
+		"Root":            schemaOf(filesearch.Root{}),
+		"IndexStats":      schemaOf(filesearch.IndexStats{}),
+		"SessionInfo":     schemaOf(SessionInfo{}),
+		"CallInfo":        schemaOf(CallInfo{}),
+		"FileInfo":        schemaOf(filesearch.FileInfo{}),
+		"FindResult":      schemaOf(filesearch.FindResult{}),
+		"GrepResult":      schemaOf(filesearch.GrepResult{}),
+		"FileContent":     schemaOf(filesearch.FileContent{}),
+	}
+}
+
+// openAPIDocument builds the OpenAPI document of the routes, served from
+// the URL of r
+func (h *HTTPMCPServer) openAPIDocument(r *http.Request) map[string]interface{} {
+	paths := map[string]interface{}{}
+	for _, route := range h.routes() {
+		for _, op := range route.operations {
+			path := op.path
+			if path == "" {
+				path = route.pattern
+			}
+			item, ok := paths[path].(map[string]interface{})
+			if !ok {
+				item = map[string]interface{}{}
+				paths[path] = item
+			}
+			item[strings.ToLower(op.method)] = openAPIOperation(route.pattern, op)
+		}
+	}
+
+	return map[string]interface{}{
+		"openapi": OpenAPIVersion,
+		"info": map[string]interface{}{
+			"title":       models.ServerName,
+			"version":     models.ServerVersion,
+			"description": "HTTP endpoints of the MCP file search server. The JSON-RPC methods of /mcp are described by its rpc.discover method.",
+		},
+		"servers": []map[string]interface{}{{"url": baseURL(r)}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": openAPISchemas(),
+			"securitySchemes": map[string]interface{}{
+				"apiKey": map[string]interface{}{"type": "apiKey", "in": "header", "name": auth.APIKeyHeader},
+				"bearer": map[string]interface{}{"type": "http", "scheme": "bearer"},
+				"mutualTLS": map[string]interface{}{
+					"type":        "mutualTLS",
+					"description": "A verified client certificate identifies the caller without further credentials",
+				},
+			},
+		},
+		// Credentials are only checked if authentication is configured
+		"security": []map[string]interface{}{
+			{"apiKey": []string{}},
+			{"bearer": []string{}},
+			{"mutualTLS": []string{}},
+		},
+	}
+}
+
+// openAPIOperation converts an operation of the route with the given
+// pattern to its OpenAPI form
+func openAPIOperation(pattern string, op operation) map[string]interface{} {
+	responses := append([]response{}, op.responses...)
+	if !unauthenticatedPaths[pattern] {
+		responses = append(responses, errorResponse(http.StatusUnauthorized, "Missing or invalid credentials"))
+	}
+	if op.admin {
+		responses = append(responses, errorResponse(http.StatusForbidden, "Admin scope required"))
+	}
+	sort.SliceStable(responses, func(i, j int) bool { return responses[i].status < responses[j].status })
+
+	documented := map[string]interface{}{}
+	for _, resp := range responses {
+		status := strconv.Itoa(resp.status)
+		if _, seen := documented[status]; seen {
+			continue
+		}
+		entry := map[string]interface{}{"description": resp.description}
+		if resp.schema != nil {
+			content := map[string]interface{}{resp.mediaType: map[string]interface{}{"schema": resp.schema}}
+			if op.tag == "rest" && resp.status == http.StatusOK {
+				content["application/x-ndjson"] = map[string]interface{}{"schema": schemaOf("")}
+				content["text/plain"] = map[string]interface{}{"schema": schemaOf("")}
+			}
+			entry["content"] = content
+		}
+		if len(resp.headers) > 0 {
+			headers := map[string]interface{}{}
+			for _, header := range resp.headers {
+				headers[header.name] = map[string]interface{}{"description": header.description, "schema": header.schema}
+			}
+			entry["headers"] = headers
+		}
+		documented[status] = entry
+	}
+
+	result := map[string]interface{}{
+		"operationId": operationID(op.method, op.path, pattern),
+		"summary":     op.summary,
+		"tags":        []string{op.tag},
+		"responses":   documented,
+	}
+	if len(op.params) > 0 {
+		params := make([]map[string]interface{}, len(op.params))
+		for i, p := range op.params {
+			params[i] = map[string]interface{}{
+				"name":   p.name,
+				"in":     p.in,
+				"schema": p.schema,
+			}
+			if p.description != "" {
+				params[i]["description"] = p.description
+			}
+			if p.required {
+				params[i]["required"] = true
+			}
+		}
+		result["parameters"] = params
+	}
+	if op.body != nil {
+		result["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": op.body}},
+		}
+	}
+	if unauthenticatedPaths[pattern] {
+		result["security"] = []interface{}{}
+	}
+	return result
+}
+
+// operationID derives a unique operation ID such as getAdminRoots or
+// deleteAdminRootsName from the method and path
+func operationID(method, path, pattern string) string {
+	if path == "" {
+		path = pattern
+	}
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, word := range strings.FieldsFunc(path, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	}) {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	if path == "/" {
+		b.WriteString("Root")
+	}
+	return b.String()
+}
+
+// OpenAPIHandler serves the OpenAPI document of the HTTP endpoints
+func (h *HTTPMCPServer) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.openAPIDocument(r))
+}
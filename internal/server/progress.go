@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+// progressInterval is the least time between two progress notifications
+// for the same request
+const progressInterval = 100 * time.Millisecond
+
+// metaProgressToken returns the token a client passed in
+// params._meta.progressToken to ask for progress notifications
+func metaProgressToken(params map[string]interface{}) (interface{}, bool) {
+	meta, _ := params["_meta"].(map[string]interface{})
+	token, ok := meta["progressToken"]
+	if !ok {
+		return nil, false
+	}
+	switch token.(type) {
+	case string, float64:
+		return token, true
+	default:
+		return nil, false
+	}
+}
+
+// withProgress arranges for searches run with the returned context to send
+// notifications/progress to the caller, if it asked for them and has a
+// session to receive them. now paces the notifications.
+func withProgress(ctx context.Context, params map[string]interface{}, now func() time.Time) context.Context {
+	token, ok := metaProgressToken(params)
+	if !ok {
+		return ctx
+	}
+	client, ok := clientSessionFromContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	var last time.Time
+	return filesearch.WithProgress(ctx, func(files int, current string) {
+		if now().Sub(last) < progressInterval {
+			return
+		}
+		last = now()
+		client.send(models.JSONRPCNotification{
+			JSONRPC: models.JSONRPCVersion,
+			Method:  models.NotificationProgress,
+			Params: models.ProgressParams{
+				ProgressToken: token,
+				Progress:      float64(files),
+				Message:       fmt.Sprintf("%d files examined, at %s", files, current),
+			},
+		})
+	})
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/auth"
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/glob"
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+	"github.com/aawadall/go-mcp-filesearch/internal/ratelimit"
+)
+
+// RateLimitConfig sets per-client request budgets. Calls to tools matching
+// ExpensiveTools draw from the expensive budget; every other method draws
+// from the cheap budget. Rates are requests per second; a zero rate
+// disables that budget.
+type RateLimitConfig struct {
+	Rate           float64
+	Burst          int
+	ExpensiveRate  float64
+	ExpensiveBurst int
+	ExpensiveTools []string
+	DailyReadBytes int64
+}
+
+// DefaultExpensiveTools matches tools that scan file contents or build indexes
+var DefaultExpensiveTools = []string{"*search*", "*grep*", "*index*"}
+
+// rateLimiter applies a RateLimitConfig to incoming JSON-RPC requests
+type rateLimiter struct {
+	expensiveTools []string
+	cheap          *ratelimit.Limiter
+	expensive      *ratelimit.Limiter
+	quota          *ratelimit.Quota
+}
+
+// newRateLimiter creates the buckets and quota for the given configuration
+func newRateLimiter(cfg RateLimitConfig) *rateLimiter {
+	expensiveTools := cfg.ExpensiveTools
+	if expensiveTools == nil {
+		expensiveTools = DefaultExpensiveTools
+	}
+
+	return &rateLimiter{
+		expensiveTools: expensiveTools,
+		cheap:          ratelimit.NewLimiter(cfg.Rate, cfg.Burst),
+		expensive:      ratelimit.NewLimiter(cfg.ExpensiveRate, cfg.ExpensiveBurst),
+		quota:          ratelimit.NewQuota(cfg.DailyReadBytes),
+	}
+}
+
+// clientKey identifies the caller for rate limiting: the authenticated
+// principal if there is one, otherwise the remote IP address
+func clientKey(r *http.Request) string {
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		return "principal:" + principal.Name
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// isExpensive reports whether req calls a tool in the expensive class
+func (l *rateLimiter) isExpensive(req models.JSONRPCRequest) bool {
+	if req.Method != "tools/call" {
+		return false
+	}
+	params, ok := req.Params.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	name, _ := params["name"].(string)
+	return glob.MatchAny(l.expensiveTools, name)
+}
+
+// readsContent reports whether req returns file or resource contents that
+// count against the daily byte quota
+func readsContent(req models.JSONRPCRequest) bool {
+	return req.Method == "resources/read" || req.Method == "tools/call"
+}
+
+// admit checks the caller's budgets for req. If the request must be
+// rejected it returns false, the reason and how long the caller should wait.
+func (l *rateLimiter) admit(key string, req models.JSONRPCRequest) (bool, string, time.Duration) {
+	limiter, budget := l.cheap, "requests"
+	if l.isExpensive(req) {
+		limiter, budget = l.expensive, "expensive tool calls"
+	}
+
+	if ok, wait := limiter.Allow(key); !ok {
+		return false, "Rate limit exceeded for " + budget, wait
+	}
+
+	if readsContent(req) {
+		if ok, wait := l.quota.Check(key); !ok {
+			return false, "Daily read quota exhausted", wait
+		}
+	}
+
+	return true, "", 0
+}
+
+// record charges the content a successful request read to the caller's
+// daily quota
+func (l *rateLimiter) record(key string, req models.JSONRPCRequest, resp models.JSONRPCResponse) {
+	if !readsContent(req) || resp.Error != nil {
+		return
+	}
+	l.quota.Consume(key, bytesRead(req, resp.Result))
+}
+
+// bytesRead returns how much content a request read: the bytes a search
+// scanned, the size of the file read_file returned or the size of the
+// resource contents read. Other tools are charged the size of their result.
+func bytesRead(req models.JSONRPCRequest, result interface{}) int64 {
+	switch req.Method {
+	case "tools/call":
+		if n, ok := filesearch.BytesRead(result); ok {
+			return n
+		}
+	case "resources/read":
+		if n, ok := resourceBytes(result); ok {
+			return n
+		}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return 0
+	}
+	return int64(len(data))
+}
+
+// resourceBytes returns the size of the text and blobs in a resources/read
+// result. Directory listings read no file content.
+func resourceBytes(result interface{}) (int64, bool) {
+	var contents []map[string]interface{}
+	switch result := result.(type) {
+	case *directoryListing:
+		return 0, true
+	case map[string]interface{}:
+		contents, _ = result["contents"].([]map[string]interface{})
+	}
+	if contents == nil {
+		return 0, false
+	}
+
+	var n int64
+	for _, entry := range contents {
+		if text, ok := entry["text"].(string); ok {
+			n += int64(len(text))
+		}
+		if blob, ok := entry["blob"].(string); ok {
+			n += filesearch.BlobSize(blob)
+		}
+	}
+	return n, true
+}
+
+// rateLimitError builds the JSON-RPC error returned when a limit is hit
+func rateLimitError(message string, retryAfter int) *models.JSONRPCError {
+	return &models.JSONRPCError{
+		Code:    models.ErrCodeRateLimited,
+		Message: message,
+		Data: map[string]interface{}{
+			"retryAfter": retryAfter,
+		},
+	}
+}
+
+// retryAfterSeconds rounds a wait up to whole seconds, at least one
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
+
+// dispatch handles a single JSON-RPC request on behalf of the HTTP client,
+// applying rate limits and quotas when configured. It sets a Retry-After
+// header if the request is rejected.
+func (h *HTTPMCPServer) dispatch(w http.ResponseWriter, r *http.Request, req models.JSONRPCRequest) models.JSONRPCResponse {
+	if rejected := h.admit(w, r, req); rejected != nil {
+		return models.JSONRPCResponse{
+			JSONRPC: models.JSONRPCVersion,
+			ID:      req.ID,
+			Error:   rejected,
+		}
+	}
+
+	resp := h.mcpServer.handleRequest(r.Context(), req)
+	h.recordUsage(r, req, resp)
+	return resp
+}
+
+// notify runs a notification from the HTTP client, unless the rate limits
+// reject it
+func (h *HTTPMCPServer) notify(w http.ResponseWriter, r *http.Request, req models.JSONRPCRequest) {
+	if h.admit(w, r, req) == nil {
+		h.mcpServer.handleNotification(r.Context(), req)
+	}
+}
+
+// admit applies the rate limits and quotas, if configured, to a request
+// from the HTTP client. If the request is rejected it sets a Retry-After
+// header and returns the error to report.
+func (h *HTTPMCPServer) admit(w http.ResponseWriter, r *http.Request, req models.JSONRPCRequest) *models.JSONRPCError {
+	if h.limiter == nil {
+		return nil
+	}
+
+	ok, message, wait := h.limiter.admit(clientKey(r), req)
+	if ok {
+		return nil
+	}
+	retryAfter := retryAfterSeconds(wait)
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	h.mcpServer.metrics.observeError(models.ErrCodeRateLimited)
+	return rateLimitError(message, retryAfter)
+}
+
+// recordUsage charges the content a request read to the client's quota
+func (h *HTTPMCPServer) recordUsage(r *http.Request, req models.JSONRPCRequest, resp models.JSONRPCResponse) {
+	if h.limiter != nil {
+		h.limiter.record(clientKey(r), req, resp)
+	}
+}
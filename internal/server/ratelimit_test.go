@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/auth"
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+func TestClientKey(t *testing.T) {
+	request := func(remote, principal string) string {
+		r := httptest.NewRequest("POST", "/mcp", nil)
+		r.RemoteAddr = remote
+		if principal != "" {
+			r = r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{Name: principal}))
+		}
+		return clientKey(r)
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{"principal", request("10.0.0.1:1234", "alice"), "principal:alice"},
+		{"other principal from the same address", request("10.0.0.1:1234", "bob"), "principal:bob"},
+		{"anonymous", request("10.0.0.1:1234", ""), "ip:10.0.0.1"},
+		{"anonymous from another port", request("10.0.0.1:5678", ""), "ip:10.0.0.1"},
+		{"address without a port", request("10.0.0.2", ""), "ip:10.0.0.2"},
+	}
+	for _, tt := range tests {
+		if tt.key != tt.want {
+			t.Errorf("%s: key %q, want %q", tt.name, tt.key, tt.want)
+		}
+	}
+}
+
+func TestRateLimiterIsolatesIdentities(t *testing.T) {
+	// Slow enough that no token refills during the test
+	l := newRateLimiter(RateLimitConfig{Rate: 0.001, Burst: 2, ExpensiveRate: 0.001, ExpensiveBurst: 1})
+	list := models.JSONRPCRequest{Method: "tools/list"}
+	search := models.JSONRPCRequest{Method: "tools/call", Params: map[string]interface{}{"name": "search_content"}}
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := l.admit("principal:alice", list); !ok {
+			t.Fatalf("alice refused request %d within her burst", i+1)
+		}
+	}
+	if ok, _, _ := l.admit("principal:alice", list); ok {
+		t.Error("alice admitted beyond her burst")
+	}
+	if ok, _, _ := l.admit("principal:bob", list); !ok {
+		t.Error("bob refused after alice used her burst")
+	}
+
+	// Expensive calls have a budget of their own
+	if ok, _, _ := l.admit("principal:alice", search); !ok {
+		t.Error("alice's search refused after she used her cheap burst")
+	}
+	if ok, _, _ := l.admit("principal:alice", search); ok {
+		t.Error("alice's second search admitted beyond the expensive burst")
+	}
+	if ok, _, _ := l.admit("principal:bob", search); !ok {
+		t.Error("bob's search refused after alice used the expensive burst")
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+	"github.com/aawadall/go-mcp-filesearch/internal/policy"
+)
+
+// Notifier delivers a server notification to connected clients
+type Notifier func(models.JSONRPCNotification)
+
+// notifierSet holds the notifiers registered by the active transports
+type notifierSet struct {
+	mu   sync.Mutex
+	next int
+	byID map[int]Notifier
+}
+
+// AddNotifier registers a notifier for server notifications and returns a
+// function that removes it again
+func (s *MCPServer) AddNotifier(n Notifier) (remove func()) {
+	set := &s.notifiers
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	if set.byID == nil {
+		set.byID = make(map[int]Notifier)
+	}
+	id := set.next
+	set.next++
+	set.byID[id] = n
+
+	return func() {
+		set.mu.Lock()
+		delete(set.byID, id)
+		set.mu.Unlock()
+	}
+}
+
+// Notify sends a notification to every registered notifier. Notifiers pass
+// it on only to clients that have initialized their session.
+func (s *MCPServer) Notify(method string, params interface{}) {
+	notification := models.JSONRPCNotification{
+		JSONRPC: models.JSONRPCVersion,
+		Method:  method,
+		Params:  params,
+	}
+
+	s.notifiers.mu.Lock()
+	notifiers := make([]Notifier, 0, len(s.notifiers.byID))
+	for _, n := range s.notifiers.byID {
+		notifiers = append(notifiers, n)
+	}
+	s.notifiers.mu.Unlock()
+
+	for _, n := range notifiers {
+		n(notification)
+	}
+}
+
+// Runtime is the part of the server's configuration that can be replaced
+// while it is serving requests
+type Runtime struct {
+	Files        *filesearch.Handler
+	EnabledTools []string
+	Policy       *policy.Engine
+	Timeouts     Timeouts
+}
+
+// ReloadResult reports what changed on a reconfiguration. Changes and
+// RestartRequired are filled in by the caller that loaded the configuration.
+type ReloadResult struct {
+	ToolsChanged     bool     `json:"toolsChanged"`
+	ResourcesChanged bool     `json:"resourcesChanged"`
+	Changes          []string `json:"changes"`
+	RestartRequired  []string `json:"restartRequired,omitempty"`
+}
+
+// Reconfigure atomically replaces the file search handler, enabled tools,
+// policy and time limits, then notifies clients whose tool or resource lists may have
+// changed. Requests already in progress finish with the settings they
+// started with.
+func (s *MCPServer) Reconfigure(rt Runtime) ReloadResult {
+	oldTools, oldResources := s.listTools(), s.listResources()
+
+	s.mu.Lock()
+	oldPolicy := s.policy
+	s.files = rt.Files
+	s.enabledTools = rt.EnabledTools
+	s.policy = rt.Policy
+	s.timeouts = rt.Timeouts
+	s.mu.Unlock()
+
+	// A policy change can alter what each caller sees even if the lists
+	// themselves are unchanged
+	policyChanged := oldPolicy != rt.Policy
+	result := ReloadResult{
+		ToolsChanged:     policyChanged || !reflect.DeepEqual(oldTools, s.listTools()),
+		ResourcesChanged: policyChanged || !reflect.DeepEqual(oldResources, s.listResources()),
+	}
+
+	if result.ToolsChanged {
+		s.Notify(models.NotificationToolsListChanged, nil)
+	}
+	if result.ResourcesChanged {
+		s.Notify(models.NotificationResourcesListChanged, nil)
+	}
+	return result
+}
+
+// Policy returns the active policy engine, or nil if none is set
+func (s *MCPServer) Policy() *policy.Engine {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.policy
+}
+
+// FileSearch returns the active file search handler, or nil if none is set
+func (s *MCPServer) FileSearch() *filesearch.Handler {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.files
+}
@@ -0,0 +1,480 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+// Paths of the REST API, a plain HTTP view of the file search tools and
+// resources for clients that do not speak JSON-RPC
+const (
+	restSearchPath = "/api/search"
+	restFilesPath  = "/api/files/"
+	restTreePath   = "/api/tree/"
+)
+
+// REST response formats
+const (
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+	formatText   = "text"
+)
+
+// restMediaTypes maps the media types a client may accept to the response
+// format
+var restMediaTypes = map[string]string{
+	"application/json":     formatJSON,
+	"application/x-ndjson": formatNDJSON,
+	"application/ndjson":   formatNDJSON,
+	"application/jsonl":    formatNDJSON,
+	"text/plain":           formatText,
+	"text/*":               formatText,
+	"application/*":        formatJSON,
+	"*/*":                  formatJSON,
+}
+
+// TruncatedHeader is set on NDJSON and text search responses whose results
+// were cut off by the limit, since those formats have no room to say so
+const TruncatedHeader = "X-Result-Truncated"
+
+// withRESTRequest marks ctx as serving the REST API
+func withRESTRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, restRequestKey, true)
+}
+
+// isRESTRequest reports whether ctx is serving the REST API
+func isRESTRequest(ctx context.Context) bool {
+	rest, _ := ctx.Value(restRequestKey).(bool)
+	return rest
+}
+
+// restFormat chooses the response format from the format query parameter,
+// or else the first supported media type in the Accept header. It reports
+// false if the client accepts none of them.
+func restFormat(r *http.Request) (string, bool) {
+	switch format := r.URL.Query().Get("format"); format {
+	case formatJSON, formatNDJSON, formatText:
+		return format, true
+	case "":
+	default:
+		return "", false
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return formatJSON, true
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if format, ok := restMediaTypes[mediaType]; ok {
+			return format, true
+		}
+	}
+	return "", false
+}
+
+// restErrorStatus maps the error of a tool or resource handler to an HTTP
+// status
+func restErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, filesearch.ErrNotPermitted):
+		return http.StatusForbidden
+	case errors.Is(err, filesearch.ErrUnknownRoot), errors.Is(err, fs.ErrNotExist), errors.Is(err, errUnknownTool):
+		return http.StatusNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	case errorCode(err) == models.ErrCodeInternalError:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// restCall runs an MCP request on behalf of a REST client, with the same
+// authorization, rate limits, auditing and instrumentation as the MCP
+// endpoint. On failure it writes an error response and returns false.
+func (h *HTTPMCPServer) restCall(w http.ResponseWriter, r *http.Request, method string, params map[string]interface{}) (interface{}, bool) {
+	req := models.JSONRPCRequest{
+		JSONRPC: models.JSONRPCVersion,
+		Method:  method,
+		Params:  params,
+	}
+	if rejected := h.admit(w, r, req); rejected != nil {
+		writeJSONError(w, http.StatusTooManyRequests, rejected.Message)
+		return nil, false
+	}
+
+	result, err := h.mcpServer.execute(withRESTRequest(r.Context()), req)
+	if err != nil {
+		writeJSONError(w, restErrorStatus(err), err.Error())
+		return nil, false
+	}
+	h.recordUsage(r, req, models.JSONRPCResponse{Result: result})
+	return result, true
+}
+
+// restCallTool calls a tool for a REST client and returns its structured
+// result
+func (h *HTTPMCPServer) restCallTool(w http.ResponseWriter, r *http.Request, name string, args map[string]interface{}) (map[string]interface{}, bool) {
+	result, ok := h.restCall(w, r, "tools/call", map[string]interface{}{
+		"name":      name,
+		"arguments": args,
+	})
+	if !ok {
+		return nil, false
+	}
+	content, _ := result.(map[string]interface{})
+	return content, true
+}
+
+// restPreamble checks the method and chooses the response format, writing
+// an error response and returning false if the request cannot be served
+func restPreamble(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		writeJSONError(w, http.StatusMethodNotAllowed, "only GET is supported")
+		return "", false
+	}
+	w.Header().Add("Vary", "Accept")
+	format, ok := restFormat(r)
+	if !ok {
+		writeJSONError(w, http.StatusNotAcceptable, "supported formats are application/json, application/x-ndjson and text/plain")
+		return "", false
+	}
+	return format, true
+}
+
+// writeNDJSON sends n items, produced by item, as one line of JSON each
+func writeNDJSON(w http.ResponseWriter, n int, item func(i int) interface{}) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	for i := 0; i < n; i++ {
+		encoder.Encode(item(i))
+	}
+}
+
+// writeText sends a plain text response
+func writeText(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, text)
+}
+
+// RESTSearchHandler searches file contents for q, or file names for the
+// glob in name, with the other query parameters mapped onto the
+// search_content and find_files tool arguments
+func (h *HTTPMCPServer) RESTSearchHandler(w http.ResponseWriter, r *http.Request) {
+	format, ok := restPreamble(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	args := map[string]interface{}{}
+	if root := query.Get("root"); root != "" {
+		args["root"] = root
+	}
+	var extensions []interface{}
+	for _, value := range query["ext"] {
+		for _, ext := range strings.Split(value, ",") {
+			if ext = strings.TrimSpace(ext); ext != "" {
+				extensions = append(extensions, ext)
+			}
+		}
+	}
+	if len(extensions) > 0 {
+		args["extensions"] = extensions
+	}
+	for param, arg := range map[string]string{"limit": "limit", "context": "contextLines"} {
+		if value := query.Get(param); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				writeJSONError(w, http.StatusBadRequest, param+" must be a non-negative integer")
+				return
+			}
+			args[arg] = n
+		}
+	}
+
+	if name := query.Get("name"); name != "" && query.Get("q") == "" {
+		args["pattern"] = name
+		content, ok := h.restCallTool(w, r, filesearch.ToolFindFiles, args)
+		if !ok {
+			return
+		}
+		result, _ := content["structuredContent"].(*filesearch.FindResult)
+		if result == nil {
+			writeJSONError(w, http.StatusInternalServerError, "unexpected tool result")
+			return
+		}
+		writeFindResult(w, format, result)
+		return
+	}
+
+	q := query.Get("q")
+	if q == "" {
+		writeJSONError(w, http.StatusBadRequest, "q or name parameter required")
+		return
+	}
+	args["query"] = q
+	for param, arg := range map[string]string{"regex": "regex", "case": "caseSensitive"} {
+		if value := query.Get(param); value != "" {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, param+" must be true or false")
+				return
+			}
+			args[arg] = b
+		}
+	}
+	if include := query.Get("include"); include != "" {
+		args["include"] = include
+	}
+
+	content, ok := h.restCallTool(w, r, filesearch.ToolSearchContent, args)
+	if !ok {
+		return
+	}
+	result, _ := content["structuredContent"].(*filesearch.GrepResult)
+	if result == nil {
+		writeJSONError(w, http.StatusInternalServerError, "unexpected tool result")
+		return
+	}
+	writeGrepResult(w, format, result)
+}
+
+// writeFindResult sends a file name search result in the chosen format
+func writeFindResult(w http.ResponseWriter, format string, result *filesearch.FindResult) {
+	if format != formatJSON && result.Truncated {
+		w.Header().Set(TruncatedHeader, "true")
+	}
+	switch format {
+	case formatNDJSON:
+		writeNDJSON(w, len(result.Files), func(i int) interface{} { return result.Files[i] })
+	case formatText:
+		var b strings.Builder
+		for _, f := range result.Files {
+			fmt.Fprintf(&b, "%s/%s\n", f.Root, f.Path)
+		}
+		writeText(w, b.String())
+	default:
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// writeGrepResult sends a content search result in the chosen format. The
+// text format follows grep -n: matches as path:line:text and context lines
+// as path-line-text.
+func writeGrepResult(w http.ResponseWriter, format string, result *filesearch.GrepResult) {
+	if format != formatJSON && result.Truncated {
+		w.Header().Set(TruncatedHeader, "true")
+	}
+	switch format {
+	case formatNDJSON:
+		writeNDJSON(w, len(result.Matches), func(i int) interface{} { return result.Matches[i] })
+	case formatText:
+		var b strings.Builder
+		for _, m := range result.Matches {
+			for i, line := range m.Before {
+				fmt.Fprintf(&b, "%s/%s-%d-%s\n", m.Root, m.Path, m.Line-len(m.Before)+i, line)
+			}
+			fmt.Fprintf(&b, "%s/%s:%d:%s\n", m.Root, m.Path, m.Line, m.Text)
+			for i, line := range m.After {
+				fmt.Fprintf(&b, "%s/%s-%d-%s\n", m.Root, m.Path, m.Line+i+1, line)
+			}
+		}
+		writeText(w, b.String())
+	default:
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// restLocation splits the root and path out of a REST URL path
+func restLocation(urlPath, prefix string) (root, path string) {
+	root, path, _ = strings.Cut(strings.TrimPrefix(urlPath, prefix), "/")
+	return root, strings.TrimSuffix(path, "/")
+}
+
+// RESTFilesHandler serves /api/files/{root}/{path} through the read_file
+// tool: as JSON metadata and content, or the raw content for text/plain
+func (h *HTTPMCPServer) RESTFilesHandler(w http.ResponseWriter, r *http.Request) {
+	format, ok := restPreamble(w, r)
+	if !ok {
+		return
+	}
+
+	root, path := restLocation(r.URL.Path, restFilesPath)
+	if root == "" || path == "" {
+		writeJSONError(w, http.StatusBadRequest, "path must be "+restFilesPath+"{root}/{path}")
+		return
+	}
+
+	content, ok := h.restCallTool(w, r, filesearch.ToolReadFile, map[string]interface{}{
+		"root": root,
+		"path": path,
+	})
+	if !ok {
+		return
+	}
+	file, err := fileContent(content, root, path)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	switch format {
+	case formatNDJSON:
+		writeNDJSON(w, 1, func(int) interface{} { return file })
+	case formatText:
+		// The raw content, typed so that a browser neither renders nor runs
+		// it: a served HTML or SVG file could otherwise script this origin
+		contentType := "text/plain; charset=utf-8"
+		if file.IsBinary() {
+			contentType = "application/octet-stream"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Security-Policy", "sandbox")
+		w.Header().Set("Content-Length", strconv.FormatInt(file.Size, 10))
+		w.WriteHeader(http.StatusOK)
+		if file.IsBinary() {
+			w.Write(file.Data)
+		} else {
+			fmt.Fprint(w, file.Text)
+		}
+	default:
+		writeJSON(w, http.StatusOK, file)
+	}
+}
+
+// fileContent extracts the file from a read_file result. Text files come
+// back as structured content and binary files as embedded resources.
+func fileContent(content map[string]interface{}, root, path string) (*filesearch.FileContent, error) {
+	if file, ok := content["structuredContent"].(*filesearch.FileContent); ok {
+		return file, nil
+	}
+
+	items, _ := content["content"].([]map[string]interface{})
+	for _, item := range items {
+		resource, ok := item["resource"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		blob, _ := resource["blob"].(string)
+		data, err := base64.StdEncoding.DecodeString(blob)
+		if err != nil {
+			return nil, fmt.Errorf("decoding file content: %w", err)
+		}
+		mimeType, _ := resource["mimeType"].(string)
+		return &filesearch.FileContent{
+			Root:     root,
+			Path:     path,
+			Size:     int64(len(data)),
+			MimeType: mimeType,
+			Data:     data,
+		}, nil
+	}
+	return nil, errors.New("unexpected tool result")
+}
+
+// restRoot is a search root as listed by /api/tree/
+type restRoot struct {
+	Name string `json:"name"`
+	URI  string `json:"uri"`
+}
+
+// RESTTreeHandler lists the roots at /api/tree/ and the entries of a
+// directory at /api/tree/{root}/{path} through the root resources
+func (h *HTTPMCPServer) RESTTreeHandler(w http.ResponseWriter, r *http.Request) {
+	format, ok := restPreamble(w, r)
+	if !ok {
+		return
+	}
+
+	root, path := restLocation(r.URL.Path, restTreePath)
+	if root == "" {
+		h.restListRoots(w, r, format)
+		return
+	}
+
+	uri := filesearch.ResourceURI(root, path)
+	result, ok := h.restCall(w, r, "resources/read", map[string]interface{}{"uri": uri})
+	if !ok {
+		return
+	}
+	listing, ok := result.(*directoryListing)
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("%s is a file; use %s%s/%s", path, restFilesPath, root, path))
+		return
+	}
+
+	switch format {
+	case formatNDJSON:
+		writeNDJSON(w, len(listing.entries), func(i int) interface{} { return listing.entries[i] })
+	case formatText:
+		var b strings.Builder
+		for _, entry := range listing.entries {
+			if entry.IsDir {
+				fmt.Fprintf(&b, "%s/\n", entry.Path)
+			} else {
+				fmt.Fprintf(&b, "%s\n", entry.Path)
+			}
+		}
+		writeText(w, b.String())
+	default:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"root":    root,
+			"path":    path,
+			"entries": listing.entries,
+		})
+	}
+}
+
+// restListRoots lists the search roots the caller may see
+func (h *HTTPMCPServer) restListRoots(w http.ResponseWriter, r *http.Request, format string) {
+	result, ok := h.restCall(w, r, "resources/list", nil)
+	if !ok {
+		return
+	}
+	listed, _ := result.(map[string]interface{})
+	resources, _ := listed["resources"].([]models.Resource)
+
+	roots := []restRoot{}
+	prefix := filesearch.ResourceScheme + "://"
+	for _, resource := range resources {
+		name := strings.TrimSuffix(strings.TrimPrefix(resource.URI, prefix), "/")
+		if !strings.HasPrefix(resource.URI, prefix) || strings.Contains(name, "/") {
+			continue
+		}
+		roots = append(roots, restRoot{Name: name, URI: resource.URI})
+	}
+
+	switch format {
+	case formatNDJSON:
+		writeNDJSON(w, len(roots), func(i int) interface{} { return roots[i] })
+	case formatText:
+		var b strings.Builder
+		for _, root := range roots {
+			fmt.Fprintf(&b, "%s/\n", root.Name)
+		}
+		writeText(w, b.String())
+	default:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"roots": roots})
+	}
+}
@@ -5,58 +5,140 @@ package server
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/aawadall/go-mcp-filesearch/internal/audit"
+	"github.com/aawadall/go-mcp-filesearch/internal/clock"
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
 	"github.com/aawadall/go-mcp-filesearch/internal/models"
+	"github.com/aawadall/go-mcp-filesearch/internal/policy"
+	"github.com/aawadall/go-mcp-filesearch/internal/trace"
 )
 
 // MCPServer represents an MCP server instance that handles client requests
 // and manages resources and tools.
 type MCPServer struct {
-	initialized bool
-	resources   []models.Resource
-	tools       []models.Tool
+	methods []rpcMethod
+	// initialized is set by an initialize request made outside a session.
+	// Sessions track their own handshake; sessionless HTTP requests carry
+	// no state and are accepted once any such client has initialized.
+	initialized atomic.Bool
+
+	// mu guards the settings below, which may be replaced by a
+	// configuration reload while requests are being served
+	mu           sync.RWMutex
+	resources    []registeredResource
+	tools        []registeredTool
+	logger       *slog.Logger
+	policy       *policy.Engine
+	files        *filesearch.Handler
+	watcher      *filesearch.Watcher
+	enabledTools []string
+	timeouts     Timeouts
+	clock        clock.Clock
+
+	methodMiddleware []MethodMiddleware
+	toolMiddleware   []ToolMiddleware
+
+	notifiers notifierSet
+	metrics   *serverMetrics
+	auditLog  *audit.Logger
+	calls     *callRegistry
+	tracer    *trace.Tracer
+	traces    *trace.RingBuffer
+
+	// minClientLogLevel is the lowest log level any client asked for
+	minClientLogLevel atomic.Int64
 }
 
-// NewMCPServer creates and returns a new MCPServer instance with default
-// resources and tools configured.
+// NewMCPServer creates and returns a new MCPServer instance with the echo
+// tool and the test resource configured.
 func NewMCPServer() *MCPServer {
-	server := &MCPServer{
-		resources: []models.Resource{
-			{
-				URI:         "example://test",
-				Name:        "Test Resource",
-				Description: "A simple test resource",
-				MimeType:    "text/plain",
-			},
-		},
-		tools: []models.Tool{
-			{
-				Name:        "echo",
-				Description: "Echo back the input text",
-				InputSchema: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"text": map[string]interface{}{
-							"type":        "string",
-							"description": "Text to echo back",
-						},
-					},
-					"required": []string{"text"},
-				},
-			},
-		},
-	}
+	server := NewEmptyMCPServer()
+	server.AddTool(echoTool, echo)
+	server.AddResource(testResource, readTestResource)
 	return server
 }
 
+// NewEmptyMCPServer creates an MCPServer without tools or resources, for
+// embedders that register their own
+func NewEmptyMCPServer() *MCPServer {
+	server := &MCPServer{}
+	server.methods = rpcMethods()
+	server.metrics = newServerMetrics(server)
+	server.calls = newCallRegistry()
+	server.minClientLogLevel.Store(noClientLogLevel)
+	return server
+}
+
+// SetClock replaces the clock the server reads the time from, for the
+// start times of calls, the pacing of progress notifications and the age
+// of the index
+func (s *MCPServer) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	s.clock = c
+	s.mu.Unlock()
+}
+
+// now returns the current time by the server's clock
+func (s *MCPServer) now() time.Time {
+	s.mu.RLock()
+	c := s.clock
+	s.mu.RUnlock()
+	if c == nil {
+		c = clock.System
+	}
+	return c.Now()
+}
+
+// SetPolicy restricts tool calls and resource reads to what the given
+// policy engine allows for the calling principal
+func (s *MCPServer) SetPolicy(engine *policy.Engine) {
+	s.mu.Lock()
+	s.policy = engine
+	s.mu.Unlock()
+}
+
+// errUnknownTool is returned for calls to tools that do not exist or are not
+// enabled
+var errUnknownTool = errors.New("unknown tool")
+
+// checkInitialized returns an error until the calling session has sent
+// initialize, or for requests outside a session, until a sessionless client
+// has. Requests from the REST API have no handshake and are always
+// accepted.
+func (s *MCPServer) checkInitialized(ctx context.Context) error {
+	if isRESTRequest(ctx) {
+		return nil
+	}
+	initialized := &s.initialized
+	if client, ok := clientSessionFromContext(ctx); ok {
+		initialized = &client.initialized
+	}
+	if !initialized.Load() {
+		return invalidRequest("server not initialized")
+	}
+	return nil
+}
+
 // handleInitialize processes the initialize method request and returns
 // server capabilities and information.
-func (s *MCPServer) handleInitialize(params interface{}) (interface{}, error) {
-	s.initialized = true
+func (s *MCPServer) handleInitialize(ctx context.Context, params interface{}) (interface{}, error) {
+	if client, ok := clientSessionFromContext(ctx); ok {
+		client.initialized.Store(true)
+	} else {
+		s.initialized.Store(true)
+	}
 
 	return models.InitializeResult{
 		ProtocolVersion: models.MCPProtocolVersion,
@@ -68,6 +150,7 @@ func (s *MCPServer) handleInitialize(params interface{}) (interface{}, error) {
 			Tools: map[string]interface{}{
 				"listChanged": true,
 			},
+			Logging: &struct{}{},
 		},
 		ServerInfo: models.ServerInfo{
 			Name:    models.ServerName,
@@ -77,139 +160,300 @@ func (s *MCPServer) handleInitialize(params interface{}) (interface{}, error) {
 }
 
 // handleListResources returns the list of available resources.
-func (s *MCPServer) handleListResources(params interface{}) (interface{}, error) {
-	if !s.initialized {
-		return nil, fmt.Errorf("server not initialized")
+func (s *MCPServer) handleListResources(ctx context.Context, params interface{}) (interface{}, error) {
+	if err := s.checkInitialized(ctx); err != nil {
+		return nil, err
 	}
 
 	return map[string]interface{}{
-		"resources": s.resources,
+		"resources": s.visibleResources(ctx),
 	}, nil
 }
 
 // handleListTools returns the list of available tools.
-func (s *MCPServer) handleListTools(params interface{}) (interface{}, error) {
-	if !s.initialized {
-		return nil, fmt.Errorf("server not initialized")
+func (s *MCPServer) handleListTools(ctx context.Context, params interface{}) (interface{}, error) {
+	if err := s.checkInitialized(ctx); err != nil {
+		return nil, err
 	}
 
 	return map[string]interface{}{
-		"tools": s.tools,
+		"tools": s.visibleTools(ctx),
 	}, nil
 }
 
 // handleReadResource reads and returns the contents of a specified resource.
-func (s *MCPServer) handleReadResource(params interface{}) (interface{}, error) {
-	if !s.initialized {
-		return nil, fmt.Errorf("server not initialized")
+func (s *MCPServer) handleReadResource(ctx context.Context, params interface{}) (interface{}, error) {
+	if err := s.checkInitialized(ctx); err != nil {
+		return nil, err
 	}
 
-	return map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"uri":      "example://test",
-				"mimeType": "text/plain",
-				"text":     "This is a test content from the MCP server",
-			},
-		},
-	}, nil
+	paramsMap, ok := params.(map[string]interface{})
+	if !ok {
+		return nil, invalidParams("invalid params")
+	}
+
+	uri, ok := paramsMap["uri"].(string)
+	if !ok {
+		return nil, invalidParams("resource uri required")
+	}
+
+	if err := s.checkResourceAccess(ctx, uri); err != nil {
+		s.auditResourceRead(ctx, uri, nil, nil, err, true)
+		return nil, err
+	}
+
+	if isFileResource(uri) {
+		result, paths, err := s.readFileResource(ctx, uri)
+		s.auditResourceRead(ctx, uri, paths, result, err, false)
+		return result, err
+	}
+
+	read, ok := s.resourceHandler(uri)
+	if !ok {
+		err := invalidParams("resource not found: %s", uri)
+		s.auditResourceRead(ctx, uri, nil, nil, err, false)
+		return nil, err
+	}
+
+	result, err := read(ctx, uri)
+	s.auditResourceRead(ctx, uri, nil, result, err, false)
+	return result, err
+}
+
+// findResource looks up a registered resource by URI
+func (s *MCPServer) findResource(uri string) (models.Resource, bool) {
+	for _, resource := range s.listResources() {
+		if resource.URI == uri {
+			return resource, true
+		}
+	}
+	return models.Resource{}, false
+}
+
+// findTool looks up a registered tool by name
+func (s *MCPServer) findTool(name string) (models.Tool, bool) {
+	for _, tool := range s.listTools() {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return models.Tool{}, false
 }
 
 // handleCallTool executes a specific tool with the provided arguments.
-func (s *MCPServer) handleCallTool(params interface{}) (interface{}, error) {
-	if !s.initialized {
-		return nil, fmt.Errorf("server not initialized")
+func (s *MCPServer) handleCallTool(ctx context.Context, params interface{}) (interface{}, error) {
+	if err := s.checkInitialized(ctx); err != nil {
+		return nil, err
 	}
 
 	paramsMap, ok := params.(map[string]interface{})
 	if !ok {
-		return nil, fmt.Errorf("invalid params")
+		return nil, invalidParams("invalid params")
 	}
 
 	name, ok := paramsMap["name"].(string)
 	if !ok {
-		return nil, fmt.Errorf("tool name required")
+		return nil, invalidParams("tool name required")
 	}
 
-	switch name {
-	case "echo":
-		args, ok := paramsMap["arguments"].(map[string]interface{})
-		if !ok {
-			return nil, fmt.Errorf("arguments required")
-		}
+	tool, ok := s.findTool(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errUnknownTool, name)
+	}
 
-		text, ok := args["text"].(string)
-		if !ok {
-			return nil, fmt.Errorf("text argument required")
-		}
+	if err := s.checkToolAccess(ctx, tool); err != nil {
+		s.auditToolCall(ctx, name, paramsMap, nil, err, true)
+		return nil, err
+	}
 
-		return map[string]interface{}{
-			"content": []map[string]interface{}{
-				{
-					"type": "text",
-					"text": fmt.Sprintf("Echo: %s", text),
-				},
-			},
-		}, nil
-	default:
-		return nil, fmt.Errorf("unknown tool: %s", name)
+	ctx, span := trace.Start(ctx, "tool "+name)
+	span.SetAttr("mcp.tool.name", name)
+	started := time.Now()
+	args, _ := paramsMap["arguments"].(map[string]interface{})
+	result, err := s.toolChain()(withProgress(ctx, paramsMap, s.now), name, args)
+	span.RecordError(err)
+	span.End()
+	s.metrics.observeToolCall(name, started, result, err)
+	s.auditToolCall(ctx, name, paramsMap, result, err, false)
+	return result, err
+}
+
+// callTool executes a tool the caller has been authorized to call
+func (s *MCPServer) callTool(ctx context.Context, name string, args map[string]interface{}) (interface{}, error) {
+	if handle, ok := s.toolHandler(name); ok {
+		return handle(ctx, args)
+	}
+
+	files := s.FileSearch()
+	if files == nil {
+		return nil, fmt.Errorf("%w: %s", errUnknownTool, name)
 	}
+
+	result, err := files.Call(ctx, name, args, s.pathFilter(ctx))
+	if err == nil && name == filesearch.ToolRebuildIndex {
+		s.notifyRootsUpdated(rebuiltRoots(result))
+	}
+	return result, err
+}
+
+// rebuiltRoots returns the statistics in a rebuild_index result
+func rebuiltRoots(result interface{}) []filesearch.IndexStats {
+	content, _ := result.(map[string]interface{})
+	structured, _ := content["structuredContent"].(map[string]interface{})
+	stats, _ := structured["roots"].([]filesearch.IndexStats)
+	return stats
 }
 
-// handleBatchRequest processes a batch of JSON-RPC requests and returns an array of responses.
-func (s *MCPServer) handleBatchRequest(requests []models.JSONRPCRequest) []models.JSONRPCResponse {
-	responses := make([]models.JSONRPCResponse, len(requests))
+// handleBatchRequest processes a batch of JSON-RPC messages and returns the
+// responses to its requests. Notifications in the batch get no response.
+func (s *MCPServer) handleBatchRequest(ctx context.Context, requests []models.JSONRPCRequest) []models.JSONRPCResponse {
+	responses := make([]models.JSONRPCResponse, 0, len(requests))
 
-	for i, req := range requests {
-		responses[i] = s.handleRequest(req)
+	for _, req := range requests {
+		if isNotification(req) {
+			s.handleNotification(ctx, req)
+			continue
+		}
+		responses = append(responses, s.handleRequest(ctx, req))
 	}
 
 	return responses
 }
 
+// emptyBatchResponse answers an empty batch, which JSON-RPC rejects with a
+// single error rather than an array
+func emptyBatchResponse() models.JSONRPCResponse {
+	return models.JSONRPCResponse{
+		JSONRPC: models.JSONRPCVersion,
+		ID:      nil,
+		Error: &models.JSONRPCError{
+			Code:    models.ErrCodeInvalidRequest,
+			Message: "Invalid Request: empty batch",
+		},
+	}
+}
+
+// hasInitialize reports whether a batch holds an initialize request
+func hasInitialize(requests []models.JSONRPCRequest) bool {
+	for _, req := range requests {
+		if req.Method == "initialize" {
+			return true
+		}
+	}
+	return false
+}
+
+// isNotification reports whether a message is a notification: a message
+// with a method but no ID, which gets no response
+func isNotification(req models.JSONRPCRequest) bool {
+	return req.ID == nil && req.Method != ""
+}
+
+// handleNotification runs a notification from the client. Nothing is sent
+// back, so notifications the server has no method for, such as
+// notifications/initialized, are ignored.
+func (s *MCPServer) handleNotification(ctx context.Context, req models.JSONRPCRequest) {
+	if req.Method == models.NotificationCancelled {
+		s.handleCancelled(ctx, req.Params)
+		return
+	}
+	if _, ok := s.findMethod(req.Method); ok {
+		s.execute(ctx, req)
+	}
+}
+
 // handleRequest routes incoming JSON-RPC requests to the appropriate handler method.
-func (s *MCPServer) handleRequest(req models.JSONRPCRequest) models.JSONRPCResponse {
-	var result interface{}
-	var err error
-
-	switch req.Method {
-	case "initialize":
-		result, err = s.handleInitialize(req.Params)
-	case "resources/list":
-		result, err = s.handleListResources(req.Params)
-	case "resources/read":
-		result, err = s.handleReadResource(req.Params)
-	case "tools/list":
-		result, err = s.handleListTools(req.Params)
-	case "tools/call":
-		result, err = s.handleCallTool(req.Params)
-	default:
-		err = fmt.Errorf("method not found: %s", req.Method)
+func (s *MCPServer) handleRequest(ctx context.Context, req models.JSONRPCRequest) models.JSONRPCResponse {
+	if req.Method == "" {
+		s.metrics.observeError(models.ErrCodeInvalidRequest)
+		return models.JSONRPCResponse{
+			JSONRPC: models.JSONRPCVersion,
+			ID:      req.ID,
+			Error: &models.JSONRPCError{
+				Code:    models.ErrCodeInvalidRequest,
+				Message: "Invalid Request: method required",
+			},
+		}
 	}
 
+	result, err := s.execute(ctx, req)
+
 	response := models.JSONRPCResponse{
 		JSONRPC: models.JSONRPCVersion,
 		ID:      req.ID,
 	}
-
 	if err != nil {
 		response.Error = &models.JSONRPCError{
-			Code:    models.ErrCodeMethodNotFound, // method not found
+			Code:    errorCode(err),
 			Message: err.Error(),
 		}
 	} else {
 		response.Result = result
 	}
-
 	return response
 }
 
+// execute runs a request and returns its result, or the error of the
+// handler so that transports other than JSON-RPC can classify it
+func (s *MCPServer) execute(ctx context.Context, req models.JSONRPCRequest) (interface{}, error) {
+	ctx = withRequestID(ctx)
+	ctx, span := s.startRequestSpan(ctx, req)
+	defer span.End()
+	ctx, finish := s.calls.start(ctx, req, s.now())
+	defer finish()
+
+	result, err := s.methodChain()(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return result, err
+}
+
 // Run starts the MCP server and begins listening for JSON-RPC requests on stdin.
 // The server processes requests and supports both single-line and multiline JSON-RPC messages.
 func (s *MCPServer) Run() {
-	scanner := bufio.NewScanner(os.Stdin)
+	s.Serve(context.Background(), os.Stdin, os.Stdout)
+}
+
+// Serve reads JSON-RPC requests from in and writes the responses and
+// notifications to out as a single client session, until in is exhausted
+// and every request has been answered. Requests are handled concurrently
+// with contexts derived from ctx, so responses may arrive out of order.
+func (s *MCPServer) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
 	var buffer strings.Builder
 
+	// Responses and notifications share out, one message per line
+	var outMu sync.Mutex
+	write := func(message interface{}) {
+		if respBytes, err := json.Marshal(message); err == nil {
+			outMu.Lock()
+			fmt.Fprintln(out, string(respBytes))
+			outMu.Unlock()
+		}
+	}
+	// The stdio transport serves a single client session
+	client := newClientSession("stdio", func(n models.JSONRPCNotification) { write(n) })
+	ctx = withClientSession(ctx, client)
+	defer s.AddNotifier(func(n models.JSONRPCNotification) {
+		if client.wants(n) {
+			write(n)
+		}
+	})()
+
+	// Requests are handled concurrently, so that a slow request holds up
+	// neither the others nor a notifications/cancelled asking to stop it.
+	// initialize is answered before reading on, since the requests after
+	// it rely on the session being initialized.
+	var handling sync.WaitGroup
+	dispatch := func(handle func()) {
+		handling.Add(1)
+		go func() {
+			defer handling.Done()
+			handle()
+		}()
+	}
+
 	for scanner.Scan() {
 		line := scanner.Text()
 
@@ -228,10 +472,14 @@ func (s *MCPServer) Run() {
 		// First, try to parse as a single JSON-RPC request
 		var req models.JSONRPCRequest
 		if err := json.Unmarshal([]byte(content), &req); err == nil {
-			// Single request - process it
-			response := s.handleRequest(req)
-			if respBytes, err := json.Marshal(response); err == nil {
-				fmt.Println(string(respBytes))
+			// Single message - process it, answering only requests
+			switch {
+			case isNotification(req):
+				s.handleNotification(ctx, req)
+			case req.Method == "initialize":
+				write(s.handleRequest(ctx, req))
+			default:
+				dispatch(func() { write(s.handleRequest(ctx, req)) })
 			}
 			buffer.Reset()
 			continue
@@ -240,13 +488,21 @@ func (s *MCPServer) Run() {
 		// If single request parsing failed, try parsing as a batch request
 		var requests []models.JSONRPCRequest
 		if err := json.Unmarshal([]byte(content), &requests); err == nil {
-			// Batch request - process all requests
-			responses := s.handleBatchRequest(requests)
-			for _, response := range responses {
-				if respBytes, err := json.Marshal(response); err == nil {
-					fmt.Println(string(respBytes))
+			// Batch request - the responses go back as one array, unless
+			// the batch held only notifications
+			answer := func() {
+				if responses := s.handleBatchRequest(ctx, requests); len(responses) > 0 {
+					write(responses)
 				}
 			}
+			switch {
+			case len(requests) == 0:
+				write(emptyBatchResponse())
+			case hasInitialize(requests):
+				answer()
+			default:
+				dispatch(answer)
+			}
 			buffer.Reset()
 			continue
 		}
@@ -255,6 +511,9 @@ func (s *MCPServer) Run() {
 		// This allows for multiline JSON input
 	}
 
+	// Answer the requests still being handled before returning
+	handling.Wait()
+
 	// Handle any remaining content in buffer (incomplete JSON)
 	if remaining := strings.TrimSpace(buffer.String()); remaining != "" {
 		errResp := models.JSONRPCResponse{
@@ -265,8 +524,7 @@ func (s *MCPServer) Run() {
 				Message: "Incomplete JSON-RPC request",
 			},
 		}
-		if respBytes, err := json.Marshal(errResp); err == nil {
-			fmt.Println(string(respBytes))
-		}
+		write(errResp)
 	}
+	return scanner.Err()
 }
@@ -0,0 +1,388 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+// sseKeepAlive is how often an idle event stream receives a comment so that
+// proxies do not close it
+const sseKeepAlive = 30 * time.Second
+
+// sessionQueueSize is how many notifications may wait for a slow stream
+// before further ones are dropped
+const sessionQueueSize = 64
+
+// Default session limits
+const (
+	DefaultSessionIdleTimeout = 30 * time.Minute
+	DefaultMaxSessions        = 1000
+)
+
+// sessionPruneInterval is how often idle sessions are looked for
+const sessionPruneInterval = time.Minute
+
+// errTooManySessions is returned when a session would exceed the limit
+var errTooManySessions = errors.New("too many sessions")
+
+// session is an MCP session created by initialize over HTTP. Clients echo
+// its ID in the Mcp-Session-Id header and may open an event stream on it to
+// receive server notifications.
+type session struct {
+	id        string
+	principal string
+	created   time.Time
+	client    *clientSession
+
+	mu      sync.Mutex
+	streams map[chan []byte]struct{}
+	closed  chan struct{}
+	// lastUsed is when a request last named the session or its last event
+	// stream closed
+	lastUsed time.Time
+	now      func() time.Time
+}
+
+// sessionStore tracks the live sessions of an HTTP server
+type sessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*session
+
+	// idleTimeout ends sessions unused for longer, unless they have an
+	// event stream open; zero keeps sessions until they are deleted
+	idleTimeout time.Duration
+	// max bounds the live sessions; zero is unlimited
+	max int
+	now func() time.Time
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{
+		sessions:    make(map[string]*session),
+		idleTimeout: DefaultSessionIdleTimeout,
+		max:         DefaultMaxSessions,
+		now:         time.Now,
+	}
+}
+
+// setLimits replaces the idle timeout and the session limit
+func (st *sessionStore) setLimits(idleTimeout time.Duration, max int) {
+	st.mu.Lock()
+	st.idleTimeout, st.max = idleTimeout, max
+	st.mu.Unlock()
+}
+
+// full reports whether a new session would exceed the limit
+func (st *sessionStore) full() bool {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.max > 0 && len(st.sessions) >= st.max
+}
+
+// create starts a new session owned by principal, unless the store is full
+func (st *sessionStore) create(principal string) (*session, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate session ID: %w", err)
+	}
+
+	now := st.now()
+	sess := &session{
+		id:        hex.EncodeToString(buf),
+		principal: principal,
+		created:   now,
+		streams:   make(map[chan []byte]struct{}),
+		closed:    make(chan struct{}),
+		lastUsed:  now,
+		now:       st.now,
+	}
+	// Sessions are created by a successful initialize
+	sess.client = newClientSession(sess.id, sess.send)
+	sess.client.initialized.Store(true)
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.max > 0 && len(st.sessions) >= st.max {
+		return nil, errTooManySessions
+	}
+	st.sessions[sess.id] = sess
+	return sess, nil
+}
+
+// get returns the session with the given ID if it belongs to principal,
+// marking it as used
+func (st *sessionStore) get(id, principal string) (*session, bool) {
+	st.mu.RLock()
+	sess, ok := st.sessions[id]
+	st.mu.RUnlock()
+	if !ok || sess.principal != principal {
+		return nil, false
+	}
+
+	sess.mu.Lock()
+	sess.lastUsed = st.now()
+	sess.mu.Unlock()
+	return sess, true
+}
+
+// prune ends the sessions that have been idle for longer than the idle
+// timeout and returns their IDs
+func (st *sessionStore) prune() []string {
+	st.mu.Lock()
+	if st.idleTimeout <= 0 {
+		st.mu.Unlock()
+		return nil
+	}
+	cutoff := st.now().Add(-st.idleTimeout)
+	var expired []*session
+	for id, sess := range st.sessions {
+		sess.mu.Lock()
+		idle := len(sess.streams) == 0 && sess.lastUsed.Before(cutoff)
+		sess.mu.Unlock()
+		if idle {
+			expired = append(expired, sess)
+			delete(st.sessions, id)
+		}
+	}
+	st.mu.Unlock()
+
+	ids := make([]string, len(expired))
+	for i, sess := range expired {
+		close(sess.closed)
+		ids[i] = sess.id
+	}
+	return ids
+}
+
+// remove ends a session and closes its event streams. It reports whether
+// the session existed.
+func (st *sessionStore) remove(id string) bool {
+	st.mu.Lock()
+	sess, ok := st.sessions[id]
+	delete(st.sessions, id)
+	st.mu.Unlock()
+
+	if ok {
+		close(sess.closed)
+	}
+	return ok
+}
+
+// SessionInfo describes a live session
+type SessionInfo struct {
+	ID        string    `json:"id"`
+	Principal string    `json:"principal,omitempty"`
+	Created   time.Time `json:"created"`
+	Streams   int       `json:"streams"`
+}
+
+// list returns the live sessions, oldest first
+func (st *sessionStore) list() []SessionInfo {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	sessions := make([]SessionInfo, 0, len(st.sessions))
+	for _, sess := range st.sessions {
+		sess.mu.Lock()
+		streams := len(sess.streams)
+		sess.mu.Unlock()
+
+		sessions = append(sessions, SessionInfo{
+			ID:        sess.id,
+			Principal: sess.principal,
+			Created:   sess.created,
+			Streams:   streams,
+		})
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Created.Before(sessions[j].Created) })
+	return sessions
+}
+
+// count returns the number of live sessions
+func (st *sessionStore) count() int {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return len(st.sessions)
+}
+
+// broadcast queues a notification on the event streams of every session
+// that wants it
+func (st *sessionStore) broadcast(n models.JSONRPCNotification) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	for _, sess := range st.sessions {
+		if sess.client.wants(n) {
+			sess.send(n)
+		}
+	}
+}
+
+// send queues a notification on the session's open event streams. Streams
+// that are not keeping up miss the notification rather than blocking the
+// server.
+func (sess *session) send(n models.JSONRPCNotification) {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return
+	}
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	for stream := range sess.streams {
+		select {
+		case stream <- data:
+		default:
+		}
+	}
+}
+
+// subscribe opens a notification stream on the session
+func (sess *session) subscribe() (chan []byte, func()) {
+	stream := make(chan []byte, sessionQueueSize)
+	sess.mu.Lock()
+	sess.streams[stream] = struct{}{}
+	sess.mu.Unlock()
+
+	return stream, func() {
+		sess.mu.Lock()
+		delete(sess.streams, stream)
+		// The session was in use until its last stream closed
+		sess.lastUsed = sess.now()
+		sess.mu.Unlock()
+	}
+}
+
+// SetSessionLimits ends sessions left idle for longer than idleTimeout and
+// refuses to start sessions beyond max. Sessions with an open event stream
+// are not idle. Zero disables either limit.
+func (h *HTTPMCPServer) SetSessionLimits(idleTimeout time.Duration, max int) {
+	h.sessions.setLimits(idleTimeout, max)
+}
+
+// pruneSessions ends idle sessions and cancels their requests periodically
+// until the server shuts down
+func (h *HTTPMCPServer) pruneSessions(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-h.done:
+			return
+		}
+		h.endIdleSessions()
+	}
+}
+
+// endIdleSessions ends idle sessions and cancels their requests
+func (h *HTTPMCPServer) endIdleSessions() {
+	for _, id := range h.sessions.prune() {
+		h.mcpServer.calls.cancelSession(id)
+		h.mcpServer.log().Debug("Ended idle session", "session_id", id)
+	}
+}
+
+// roomForSession reports whether a session can be started, ending idle
+// sessions to make room if needed
+func (h *HTTPMCPServer) roomForSession() bool {
+	if !h.sessions.full() {
+		return true
+	}
+	h.endIdleSessions()
+	return !h.sessions.full()
+}
+
+// startSession creates a session after a successful initialize and returns
+// its ID in the response headers
+func (h *HTTPMCPServer) startSession(w http.ResponseWriter, r *http.Request) {
+	sess, err := h.sessions.create(principalName(r.Context()))
+	if err != nil {
+		h.mcpServer.log().WarnContext(r.Context(), "Failed to start session", "error", err)
+		return
+	}
+	w.Header().Set(SessionIDHeader, sess.id)
+}
+
+// lookupSession finds the session named in the request headers, writing an
+// error response if it is missing or unknown
+func (h *HTTPMCPServer) lookupSession(w http.ResponseWriter, r *http.Request) (*session, bool) {
+	id := r.Header.Get(SessionIDHeader)
+	if id == "" {
+		http.Error(w, "Missing "+SessionIDHeader+" header", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Sessions are only visible to the principal that created them
+	sess, ok := h.sessions.get(id, principalName(r.Context()))
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return nil, false
+	}
+	return sess, true
+}
+
+// handleEventStream streams server notifications for a session as
+// server-sent events until the client disconnects, the session ends or the
+// server shuts down
+func (h *HTTPMCPServer) handleEventStream(w http.ResponseWriter, r *http.Request) {
+	sess, ok := h.lookupSession(w, r)
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Streams outlive the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	stream, unsubscribe := sess.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case data := <-stream:
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		case <-sess.closed:
+			return
+		case <-h.done:
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// handleDeleteSession ends the session named in the request headers
+func (h *HTTPMCPServer) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	sess, ok := h.lookupSession(w, r)
+	if !ok {
+		return
+	}
+	h.sessions.remove(sess.id)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClock is a settable time source
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time          { return c.now }
+func (c *testClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// newTestSessionStore returns a session store reading the time from a test clock
+func newTestSessionStore(idleTimeout time.Duration, max int) (*sessionStore, *testClock) {
+	clock := &testClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	st := newSessionStore()
+	st.now = clock.Now
+	st.setLimits(idleTimeout, max)
+	return st, clock
+}
+
+// mustCreate starts a session or fails t
+func mustCreate(t *testing.T, st *sessionStore, principal string) *session {
+	t.Helper()
+	sess, err := st.create(principal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sess
+}
+
+// isClosed reports whether a session has ended
+func isClosed(sess *session) bool {
+	select {
+	case <-sess.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestSessionStorePrunesIdleSessions(t *testing.T) {
+	st, clock := newTestSessionStore(30*time.Minute, 0)
+	idle := mustCreate(t, st, "alice")
+	used := mustCreate(t, st, "alice")
+	streaming := mustCreate(t, st, "bob")
+	_, unsubscribe := streaming.subscribe()
+
+	clock.Advance(20 * time.Minute)
+	if _, ok := st.get(used.id, "alice"); !ok {
+		t.Fatal("session not found")
+	}
+	clock.Advance(15 * time.Minute)
+
+	if ended := st.prune(); !reflect.DeepEqual(ended, []string{idle.id}) {
+		t.Errorf("pruned %v, want only the idle session %s", ended, idle.id)
+	}
+	if !isClosed(idle) || isClosed(used) || isClosed(streaming) {
+		t.Error("only the idle session should be closed")
+	}
+	if _, ok := st.get(idle.id, "alice"); ok {
+		t.Error("pruned session still found")
+	}
+
+	// A session is not idle while its event stream is open, and its idle
+	// time starts when the stream closes
+	clock.Advance(time.Hour)
+	unsubscribe()
+	st.prune()
+	if isClosed(streaming) {
+		t.Error("session pruned as soon as its stream closed")
+	}
+	clock.Advance(31 * time.Minute)
+	if ended := st.prune(); !reflect.DeepEqual(ended, []string{streaming.id}) {
+		t.Errorf("pruned %v, want %s", ended, streaming.id)
+	}
+
+	if st.count() != 0 {
+		t.Errorf("%d sessions left, want 0", st.count())
+	}
+}
+
+func TestSessionStoreWithoutIdleTimeout(t *testing.T) {
+	st, clock := newTestSessionStore(0, 0)
+	sess := mustCreate(t, st, "")
+	clock.Advance(24 * time.Hour)
+	if ended := st.prune(); len(ended) != 0 || isClosed(sess) {
+		t.Errorf("pruned %v without an idle timeout", ended)
+	}
+}
+
+func TestSessionStoreLimit(t *testing.T) {
+	st, clock := newTestSessionStore(time.Minute, 2)
+	first := mustCreate(t, st, "alice")
+	mustCreate(t, st, "bob")
+
+	if !st.full() {
+		t.Error("store at its limit not full")
+	}
+	if _, err := st.create("carol"); !errors.Is(err, errTooManySessions) {
+		t.Fatalf("create beyond the limit: error = %v, want %v", err, errTooManySessions)
+	}
+
+	st.remove(first.id)
+	mustCreate(t, st, "carol")
+
+	clock.Advance(2 * time.Minute)
+	st.prune()
+	if st.full() {
+		t.Error("store full after its sessions went idle")
+	}
+	mustCreate(t, st, "dave")
+}
+
+func TestInitializeRefusedWhenSessionsFull(t *testing.T) {
+	h := NewHTTPMCPServer(NewMCPServer())
+	defer close(h.done)
+	clock := &testClock{now: time.Now()}
+	h.sessions.now = clock.Now
+	h.SetSessionLimits(time.Minute, 1)
+
+	initialize := func() *httptest.ResponseRecorder {
+		body := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`
+		r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Accept", "application/json, text/event-stream")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := initialize(); w.Code != http.StatusOK || w.Header().Get(SessionIDHeader) == "" {
+		t.Fatalf("first initialize: status %d, session %q", w.Code, w.Header().Get(SessionIDHeader))
+	}
+	if w := initialize(); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("initialize beyond the limit: status %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+
+	// Idle sessions make room for new ones
+	clock.Advance(2 * time.Minute)
+	if w := initialize(); w.Code != http.StatusOK || w.Header().Get(SessionIDHeader) == "" {
+		t.Errorf("initialize after the session went idle: status %d, session %q", w.Code, w.Header().Get(SessionIDHeader))
+	}
+}
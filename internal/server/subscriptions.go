@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+// watch subscribes the client to updates of a resource
+func (c *clientSession) watch(uri string) {
+	c.mu.Lock()
+	if c.watched == nil {
+		c.watched = make(map[string]bool)
+	}
+	c.watched[uri] = true
+	c.mu.Unlock()
+}
+
+// unwatch cancels the client's subscription to a resource
+func (c *clientSession) unwatch(uri string) {
+	c.mu.Lock()
+	delete(c.watched, uri)
+	c.mu.Unlock()
+}
+
+// watching reports whether the client subscribed to a resource
+func (c *clientSession) watching(uri string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.watched[uri]
+}
+
+// subscriptionParams returns the session and resource URI of a
+// resources/subscribe or resources/unsubscribe request
+func (s *MCPServer) subscriptionParams(ctx context.Context, params interface{}) (*clientSession, string, error) {
+	if err := s.checkInitialized(ctx); err != nil {
+		return nil, "", err
+	}
+
+	paramsMap, ok := params.(map[string]interface{})
+	if !ok {
+		return nil, "", invalidParams("invalid params")
+	}
+	uri, ok := paramsMap["uri"].(string)
+	if !ok {
+		return nil, "", invalidParams("uri required")
+	}
+
+	client, ok := clientSessionFromContext(ctx)
+	if !ok {
+		return nil, "", invalidRequest("subscriptions require a session")
+	}
+	return client, uri, nil
+}
+
+// handleSubscribe subscribes the caller to updates of a resource. A search
+// root is updated whenever its index is rebuilt or the file watcher finds a
+// changed file in it.
+func (s *MCPServer) handleSubscribe(ctx context.Context, params interface{}) (interface{}, error) {
+	client, uri, err := s.subscriptionParams(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.findResource(uri); !ok && !isFileResource(uri) {
+		return nil, invalidParams("resource not found: %s", uri)
+	}
+	if err := s.checkResourceAccess(ctx, uri); err != nil {
+		return nil, err
+	}
+
+	client.watch(uri)
+	return map[string]interface{}{}, nil
+}
+
+// handleUnsubscribe cancels the caller's subscription to a resource
+func (s *MCPServer) handleUnsubscribe(ctx context.Context, params interface{}) (interface{}, error) {
+	client, uri, err := s.subscriptionParams(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	client.unwatch(uri)
+	return map[string]interface{}{}, nil
+}
+
+// notifyRootsUpdated tells subscribers of the given roots' resources that
+// their indexes were rebuilt
+func (s *MCPServer) notifyRootsUpdated(stats []filesearch.IndexStats) {
+	for _, stat := range stats {
+		s.Notify(models.NotificationResourceUpdated, models.ResourceUpdatedParams{
+			URI: filesearch.ResourceURI(stat.Root, ""),
+		})
+	}
+}
@@ -0,0 +1,266 @@
+// Package server implements TLS support for the HTTP transport.
+// It provides certificate loading with automatic reload, optional client
+// certificate verification and mapping of client certificates to principals.
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/auth"
+)
+
+// Client certificate verification modes
+const (
+	ClientAuthNone     = "none"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+// DefaultCertReloadInterval is how often certificate files are checked for changes
+const DefaultCertReloadInterval = 30 * time.Second
+
+// TLSConfig describes how the HTTP server terminates TLS
+type TLSConfig struct {
+	CertFile       string
+	KeyFile        string
+	ClientCAFile   string
+	ClientAuth     string
+	ReloadInterval time.Duration
+	// Done stops the certificate reloader when closed, typically
+	// HTTPMCPServer.Done; nil keeps it running for the life of the process
+	Done <-chan struct{}
+}
+
+// ClientIdentity is the principal a client certificate subject maps to
+type ClientIdentity struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// UnmarshalJSON accepts a bare name as well as an object with scopes
+func (c *ClientIdentity) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*c = ClientIdentity{Name: name}
+		return nil
+	}
+	type plain ClientIdentity
+	return json.Unmarshal(data, (*plain)(c))
+}
+
+// LoadIdentityMap reads a JSON object mapping certificate subjects to identities
+// from the given file. Keys may be a full subject DN or a bare common name;
+// values are a name or a {"name", "scopes"} object.
+func LoadIdentityMap(path string) (map[string]ClientIdentity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity map: %w", err)
+	}
+
+	var identities map[string]ClientIdentity
+	if err := json.Unmarshal(data, &identities); err != nil {
+		return nil, fmt.Errorf("failed to parse identity map %s: %w", path, err)
+	}
+
+	return identities, nil
+}
+
+// certReloader serves the current certificate and reloads it when the
+// certificate or key file changes on disk.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// newCertReloader loads the initial certificate and returns a reloader for it
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// latestModTime returns the most recent modification time of the cert and key files
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// reload reads the certificate and key pair from disk
+func (r *certReloader) reload() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return fmt.Errorf("failed to stat certificate files: %w", err)
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.mu.Unlock()
+
+	return nil
+}
+
+// watch polls the certificate files and reloads them when they change,
+// until done is closed. A failed reload keeps the previous certificate in
+// service.
+func (r *certReloader) watch(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+		r.check()
+	}
+}
+
+// check reloads the certificate if its files changed since it was loaded
+func (r *certReloader) check() {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		slog.Warn("TLS certificate check failed", "error", err)
+		return
+	}
+
+	r.mu.RLock()
+	changed := modTime.After(r.modTime)
+	r.mu.RUnlock()
+
+	if !changed {
+		return
+	}
+
+	if err := r.reload(); err != nil {
+		slog.Warn("TLS certificate reload failed, keeping previous certificate", "error", err)
+		return
+	}
+	slog.Info("Reloaded TLS certificate", "cert_file", r.certFile)
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// NewTLSConfig builds a tls.Config from the given configuration. The returned
+// config reloads the server certificate in the background when it changes,
+// until cfg.Done is closed.
+func NewTLSConfig(cfg TLSConfig) (*tls.Config, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, fmt.Errorf("both certificate and key files are required for TLS")
+	}
+
+	reloader, err := newCertReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := cfg.ReloadInterval
+	if interval <= 0 {
+		interval = DefaultCertReloadInterval
+	}
+	go reloader.watch(interval, cfg.Done)
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	clientAuth := cfg.ClientAuth
+	if clientAuth == "" {
+		clientAuth = ClientAuthNone
+		if cfg.ClientCAFile != "" {
+			clientAuth = ClientAuthRequire
+		}
+	}
+
+	switch clientAuth {
+	case ClientAuthNone:
+		return tlsConfig, nil
+	case ClientAuthOptional:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client auth mode: %s", clientAuth)
+	}
+
+	if cfg.ClientCAFile == "" {
+		return nil, fmt.Errorf("client CA file is required for client auth mode %s", clientAuth)
+	}
+
+	caBundle, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA bundle: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBundle) {
+		return nil, fmt.Errorf("no certificates found in client CA bundle %s", cfg.ClientCAFile)
+	}
+	tlsConfig.ClientCAs = pool
+
+	return tlsConfig, nil
+}
+
+// ClientCertPrincipal maps the verified client certificate on r to a principal.
+// The identity map is consulted first by full subject DN and then by common
+// name; unmapped certificates are identified by their common name and hold
+// no scopes.
+func ClientCertPrincipal(r *http.Request, identities map[string]ClientIdentity) (*auth.Principal, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+
+	subject := r.TLS.VerifiedChains[0][0].Subject
+
+	identity, ok := identities[subject.String()]
+	if !ok {
+		identity, ok = identities[subject.CommonName]
+	}
+	if !ok {
+		identity = ClientIdentity{Name: subject.CommonName}
+	}
+	if identity.Name == "" {
+		return nil, false
+	}
+
+	return &auth.Principal{
+		Name:   identity.Name,
+		Method: auth.MethodClientCert,
+		Scopes: identity.Scopes,
+	}, true
+}
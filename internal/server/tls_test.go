@@ -0,0 +1,228 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/auth"
+)
+
+// newTestCertificate returns a self-signed certificate for the given subject
+func newTestCertificate(t *testing.T, subject pkix.Name) (*x509.Certificate, []byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeCertificate writes a certificate for cn and its key to dir, dated mod
+func writeCertificate(t *testing.T, dir, cn string, mod time.Time) (string, string) {
+	t.Helper()
+	_, certPEM, keyPEM := newTestCertificate(t, pkix.Name{CommonName: cn})
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	for path, data := range map[string][]byte{certFile: certPEM, keyFile: keyPEM} {
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return certFile, keyFile
+}
+
+// servedCommonName returns the common name of the certificate r serves
+func servedCommonName(t *testing.T, r *certReloader) string {
+	t.Helper()
+	cert, err := r.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestLoadIdentityMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "identities.json")
+	data := `{
+		"CN=alice,O=Acme": {"name": "alice", "scopes": ["admin"]},
+		"bob": "robert"
+	}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	identities, err := LoadIdentityMap(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]ClientIdentity{
+		"CN=alice,O=Acme": {Name: "alice", Scopes: []string{"admin"}},
+		"bob":             {Name: "robert"},
+	}
+	if !reflect.DeepEqual(identities, want) {
+		t.Errorf("identities = %+v, want %+v", identities, want)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"bob": 42}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadIdentityMap(path); err == nil {
+		t.Error("identity map with a numeric identity loaded")
+	}
+	if _, err := LoadIdentityMap(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("missing identity map loaded")
+	}
+}
+
+func TestClientCertPrincipal(t *testing.T) {
+	identities := map[string]ClientIdentity{
+		"CN=alice,O=Acme": {Name: "alice@acme", Scopes: []string{"admin"}},
+		"alice":           {Name: "alice by name"},
+		"bob":             {Name: "robert", Scopes: []string{"read"}},
+	}
+
+	tests := []struct {
+		name    string
+		subject *pkix.Name
+		want    *auth.Principal
+	}{
+		{
+			name:    "full subject wins over common name",
+			subject: &pkix.Name{CommonName: "alice", Organization: []string{"Acme"}},
+			want:    &auth.Principal{Name: "alice@acme", Method: auth.MethodClientCert, Scopes: []string{"admin"}},
+		},
+		{
+			name:    "common name",
+			subject: &pkix.Name{CommonName: "bob", Organization: []string{"Other"}},
+			want:    &auth.Principal{Name: "robert", Method: auth.MethodClientCert, Scopes: []string{"read"}},
+		},
+		{
+			name:    "unmapped subject holds no scopes",
+			subject: &pkix.Name{CommonName: "carol"},
+			want:    &auth.Principal{Name: "carol", Method: auth.MethodClientCert},
+		},
+		{
+			name:    "unmapped subject without a common name",
+			subject: &pkix.Name{Organization: []string{"Acme"}},
+		},
+		{
+			name: "no client certificate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "https://mcp.example.com/mcp", nil)
+			if tt.subject != nil {
+				cert, _, _ := newTestCertificate(t, *tt.subject)
+				r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+			}
+
+			principal, ok := ClientCertPrincipal(r, identities)
+			if ok != (tt.want != nil) {
+				t.Fatalf("ok = %v, want %v", ok, tt.want != nil)
+			}
+			if !reflect.DeepEqual(principal, tt.want) {
+				t.Errorf("principal = %+v, want %+v", principal, tt.want)
+			}
+		})
+	}
+}
+
+func TestCertReloaderReloadsChangedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	certFile, keyFile := writeCertificate(t, dir, "first", start)
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cn := servedCommonName(t, reloader); cn != "first" {
+		t.Fatalf("serving %q, want first", cn)
+	}
+
+	reloader.check()
+	if cn := servedCommonName(t, reloader); cn != "first" {
+		t.Errorf("unchanged files reloaded as %q", cn)
+	}
+
+	writeCertificate(t, dir, "second", start.Add(time.Minute))
+	reloader.check()
+	if cn := servedCommonName(t, reloader); cn != "second" {
+		t.Errorf("after the files changed serving %q, want second", cn)
+	}
+
+	// A broken certificate keeps the previous one in service
+	broken := start.Add(2 * time.Minute)
+	if err := os.WriteFile(certFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(certFile, broken, broken); err != nil {
+		t.Fatal(err)
+	}
+	reloader.check()
+	if cn := servedCommonName(t, reloader); cn != "second" {
+		t.Errorf("after a failed reload serving %q, want second", cn)
+	}
+}
+
+func TestCertReloaderStopsWhenDone(t *testing.T) {
+	certFile, keyFile := writeCertificate(t, t.TempDir(), "server", time.Now())
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		reloader.watch(time.Millisecond, done)
+		close(stopped)
+	}()
+
+	close(done)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reloader kept watching after done was closed")
+	}
+}
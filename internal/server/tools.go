@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+// ToolHandler runs a registered tool with the arguments of a tools/call
+// request and returns its result, usually a map with "content"
+type ToolHandler func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+
+// ResourceHandler reads a registered resource and returns its result,
+// usually a map with "contents"
+type ResourceHandler func(ctx context.Context, uri string) (interface{}, error)
+
+// registeredTool is a tool added with AddTool
+type registeredTool struct {
+	tool   models.Tool
+	handle ToolHandler
+}
+
+// registeredResource is a resource added with AddResource
+type registeredResource struct {
+	resource models.Resource
+	read     ResourceHandler
+}
+
+// AddTool offers a tool whose calls are run by handle, replacing any
+// registered tool of the same name, and tells clients their tool lists
+// changed. Registered tools take precedence over file search tools.
+func (s *MCPServer) AddTool(tool models.Tool, handle ToolHandler) {
+	s.mu.Lock()
+	s.tools = append(removeTool(s.tools, tool.Name), registeredTool{tool: tool, handle: handle})
+	s.mu.Unlock()
+	s.Notify(models.NotificationToolsListChanged, nil)
+}
+
+// RemoveTool withdraws a tool added with AddTool, reporting whether it
+// existed
+func (s *MCPServer) RemoveTool(name string) bool {
+	s.mu.Lock()
+	before := len(s.tools)
+	s.tools = removeTool(s.tools, name)
+	removed := len(s.tools) < before
+	s.mu.Unlock()
+	if removed {
+		s.Notify(models.NotificationToolsListChanged, nil)
+	}
+	return removed
+}
+
+// removeTool returns tools without the one named name
+func removeTool(tools []registeredTool, name string) []registeredTool {
+	kept := make([]registeredTool, 0, len(tools)+1)
+	for _, t := range tools {
+		if t.tool.Name != name {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// toolHandler returns the handler of a registered tool
+func (s *MCPServer) toolHandler(name string) (ToolHandler, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, t := range s.tools {
+		if t.tool.Name == name {
+			return t.handle, true
+		}
+	}
+	return nil, false
+}
+
+// AddResource offers a resource read by read, replacing any registered
+// resource with the same URI, and tells clients their resource lists
+// changed
+func (s *MCPServer) AddResource(resource models.Resource, read ResourceHandler) {
+	s.mu.Lock()
+	s.resources = append(removeResource(s.resources, resource.URI), registeredResource{resource: resource, read: read})
+	s.mu.Unlock()
+	s.Notify(models.NotificationResourcesListChanged, nil)
+}
+
+// RemoveResource withdraws a resource added with AddResource, reporting
+// whether it existed
+func (s *MCPServer) RemoveResource(uri string) bool {
+	s.mu.Lock()
+	before := len(s.resources)
+	s.resources = removeResource(s.resources, uri)
+	removed := len(s.resources) < before
+	s.mu.Unlock()
+	if removed {
+		s.Notify(models.NotificationResourcesListChanged, nil)
+	}
+	return removed
+}
+
+// removeResource returns resources without the one at uri
+func removeResource(resources []registeredResource, uri string) []registeredResource {
+	kept := make([]registeredResource, 0, len(resources)+1)
+	for _, r := range resources {
+		if r.resource.URI != uri {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// resourceHandler returns the handler of a registered resource
+func (s *MCPServer) resourceHandler(uri string) (ResourceHandler, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, r := range s.resources {
+		if r.resource.URI == uri {
+			return r.read, true
+		}
+	}
+	return nil, false
+}
+
+// echoTool echoes its text argument back
+var echoTool = models.Tool{
+	Name:        "echo",
+	Description: "Echo back the input text",
+	InputSchema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"text": map[string]interface{}{
+				"type":        "string",
+				"description": "Text to echo back",
+			},
+		},
+		"required": []string{"text"},
+	},
+	Annotations: &models.ToolAnnotations{
+		ReadOnlyHint: true,
+	},
+}
+
+// echo runs the echo tool
+func echo(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	if args == nil {
+		return nil, invalidParams("arguments required")
+	}
+
+	text, ok := args["text"].(string)
+	if !ok {
+		return nil, invalidParams("text argument required")
+	}
+
+	return map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": fmt.Sprintf("Echo: %s", text),
+			},
+		},
+	}, nil
+}
+
+// testResource is a static resource with fixed text
+var testResource = models.Resource{
+	URI:         "example://test",
+	Name:        "Test Resource",
+	Description: "A simple test resource",
+	MimeType:    "text/plain",
+}
+
+// readTestResource reads the test resource
+func readTestResource(ctx context.Context, uri string) (interface{}, error) {
+	return map[string]interface{}{
+		"contents": []map[string]interface{}{
+			{
+				"uri":      testResource.URI,
+				"mimeType": testResource.MimeType,
+				"text":     "This is a test content from the MCP server",
+			},
+		},
+	}, nil
+}
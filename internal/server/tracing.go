@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+	"github.com/aawadall/go-mcp-filesearch/internal/trace"
+)
+
+// TraceparentHeader carries W3C trace context on HTTP requests
+const TraceparentHeader = "traceparent"
+
+// SetTracer records a trace of every request with tracer. If traces is not
+// nil the HTTP transport serves it at /debug/traces. It must be called
+// before the server starts serving.
+func (s *MCPServer) SetTracer(tracer *trace.Tracer, traces *trace.RingBuffer) {
+	s.tracer = tracer
+	s.traces = traces
+}
+
+// metaTraceparent returns the trace context a client passed in
+// params._meta.traceparent, or a zero context if there is none
+func metaTraceparent(params interface{}) trace.SpanContext {
+	paramsMap, _ := params.(map[string]interface{})
+	meta, _ := paramsMap["_meta"].(map[string]interface{})
+	value, _ := meta["traceparent"].(string)
+	if value == "" {
+		return trace.SpanContext{}
+	}
+	sc, err := trace.ParseTraceparent(value)
+	if err != nil {
+		return trace.SpanContext{}
+	}
+	return sc
+}
+
+// startRequestSpan begins the span of a JSON-RPC request. Trace context in
+// _meta takes precedence over the transport's span.
+func (s *MCPServer) startRequestSpan(ctx context.Context, req models.JSONRPCRequest) (context.Context, *trace.Span) {
+	ctx, span := s.tracer.Start(ctx, req.Method, metaTraceparent(req.Params), trace.KindServer)
+	span.SetAttr("rpc.system", "jsonrpc")
+	span.SetAttr("rpc.method", req.Method)
+	if req.ID != nil {
+		span.SetAttr("rpc.jsonrpc.request_id", fmt.Sprint(req.ID))
+	}
+	if id, ok := RequestIDFromContext(ctx); ok {
+		span.SetAttr("mcp.request_id", id)
+	}
+	if client, ok := clientSessionFromContext(ctx); ok {
+		span.SetAttr("mcp.session_id", client.id)
+	}
+	return ctx, span
+}
+
+// startHTTPSpan begins the span of an HTTP request, continuing the trace in
+// its traceparent header if there is one
+func (h *HTTPMCPServer) startHTTPSpan(r *http.Request) (*http.Request, *trace.Span) {
+	var remote trace.SpanContext
+	if value := r.Header.Get(TraceparentHeader); value != "" {
+		remote, _ = trace.ParseTraceparent(value)
+	}
+	ctx, span := h.mcpServer.tracer.Start(r.Context(), r.Method+" "+r.URL.Path, remote, trace.KindServer)
+	span.SetAttr("http.request.method", r.Method)
+	span.SetAttr("url.path", r.URL.Path)
+	return r.WithContext(ctx), span
+}
+
+// DebugTracesHandler serves recently recorded traces as OTLP/JSON
+func (h *HTTPMCPServer) DebugTracesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.isAdmin(r) {
+		writeJSONError(w, http.StatusForbidden, "admin scope required")
+		return
+	}
+	if h.mcpServer.traces == nil {
+		writeJSONError(w, http.StatusNotFound, "tracing is not enabled")
+		return
+	}
+	h.mcpServer.traces.ServeHTTP(w, r)
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// webFiles holds the search UI: a single page that drives the MCP endpoint
+// from the browser
+//
+//go:embed web
+var webFiles embed.FS
+
+// uiPrefix is the path under which the UI's assets are served
+const uiPrefix = "/ui/"
+
+// uiContentSecurityPolicy keeps the UI from loading anything but its own
+// assets
+const uiContentSecurityPolicy = "default-src 'self'; img-src 'self' data:; frame-ancestors 'none'"
+
+// SetUI enables or disables the search UI served at /. It is enabled by
+// default.
+func (h *HTTPMCPServer) SetUI(enabled bool) {
+	h.ui = enabled
+}
+
+// wantsHTML reports whether the request is a browser asking for a page
+// rather than an API client asking for JSON
+func wantsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// isUIPath reports whether the request is for the UI's page or assets,
+// which hold no data and are served without credentials. The page asks
+// for a token when the MCP endpoint requires one.
+func (h *HTTPMCPServer) isUIPath(r *http.Request) bool {
+	if !h.ui {
+		return false
+	}
+	return strings.HasPrefix(r.URL.Path, uiPrefix) || (r.URL.Path == "/" && wantsHTML(r))
+}
+
+// serveUIPage serves the UI's page
+func (h *HTTPMCPServer) serveUIPage(w http.ResponseWriter, r *http.Request) {
+	page, err := webFiles.ReadFile("web/index.html")
+	if err != nil {
+		http.Error(w, "UI not available", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", uiContentSecurityPolicy)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(page)
+}
+
+// UIAssetHandler serves the scripts and styles of the UI
+func (h *HTTPMCPServer) UIAssetHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.ui {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	assets, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		http.Error(w, "UI not available", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Security-Policy", uiContentSecurityPolicy)
+	w.Header().Set("Cache-Control", "no-cache")
+	http.StripPrefix(uiPrefix, http.FileServer(http.FS(assets))).ServeHTTP(w, r)
+}
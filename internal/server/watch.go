@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+// WatchFiles polls the search roots for changed files until ctx is done,
+// keeping the index up to date and telling subscribers of the changed files
+// and of their roots. A zero interval uses filesearch.DefaultWatchInterval.
+func (s *MCPServer) WatchFiles(ctx context.Context, interval time.Duration) {
+	watcher := filesearch.NewWatcher(func() *filesearch.Engine {
+		files := s.FileSearch()
+		if files == nil {
+			return nil
+		}
+		return files.Engine()
+	}, filesearch.WatchConfig{
+		Interval: interval,
+		OnChange: s.notifyFilesChanged,
+		OnError: func(err error) {
+			s.log().Warn("Failed to save index after a file change", "error", err)
+		},
+	})
+
+	s.mu.Lock()
+	s.watcher = watcher
+	s.mu.Unlock()
+
+	watcher.Run(ctx)
+}
+
+// fileWatcher returns the watcher started by WatchFiles, if any
+func (s *MCPServer) fileWatcher() *filesearch.Watcher {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.watcher
+}
+
+// notifyFilesChanged tells subscribers of each changed file, and once of
+// each root holding one, that the resource was updated
+func (s *MCPServer) notifyFilesChanged(changes []filesearch.Change) {
+	roots := make(map[string]bool)
+	for _, change := range changes {
+		s.Notify(models.NotificationResourceUpdated, models.ResourceUpdatedParams{
+			URI: filesearch.ResourceURI(change.Root, change.Path),
+		})
+		if !roots[change.Root] {
+			roots[change.Root] = true
+			s.Notify(models.NotificationResourceUpdated, models.ResourceUpdatedParams{
+				URI: filesearch.ResourceURI(change.Root, ""),
+			})
+		}
+	}
+}
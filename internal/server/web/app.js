@@ -0,0 +1,599 @@
+// Search UI for the file search MCP server. Every action is an MCP request
+// to /mcp, so the page sees exactly what MCP clients see: the same tools,
+// the same access policy and the same limits.
+"use strict";
+
+const PROTOCOL_VERSION = "2024-11-05";
+const ROOT_SCHEME = "filesearch://";
+
+const $ = (id) => document.getElementById(id);
+
+// ---- MCP client ----------------------------------------------------------
+
+const mcp = {
+  nextID: 1,
+  session: null,
+  progress: new Map(),
+
+  headers() {
+    const headers = { "Content-Type": "application/json", Accept: "application/json" };
+    const token = sessionStorage.getItem("token");
+    if (token) {
+      if (sessionStorage.getItem("tokenKind") === "apikey") {
+        headers["X-API-Key"] = token;
+      } else {
+        headers.Authorization = "Bearer " + token;
+      }
+    }
+    if (this.session) {
+      headers["Mcp-Session-Id"] = this.session;
+    }
+    return headers;
+  },
+
+  // request sends a JSON-RPC request and resolves to its result. Aborting
+  // signal also tells the server to stop working on the request.
+  async request(method, params, signal) {
+    const id = this.nextID++;
+    const cancel = () => this.notify("notifications/cancelled", { requestId: id, reason: "Stopped by the user" });
+    signal?.addEventListener("abort", cancel, { once: true });
+    try {
+      return await this.send(id, method, params, signal);
+    } finally {
+      signal?.removeEventListener("abort", cancel);
+    }
+  },
+
+  // notify sends a JSON-RPC notification, ignoring failures
+  notify(method, params) {
+    fetch("mcp", {
+      method: "POST",
+      headers: this.headers(),
+      body: JSON.stringify({ jsonrpc: "2.0", method, params }),
+      keepalive: true,
+    }).catch(() => {});
+  },
+
+  // send posts one request and resolves to its result
+  async send(id, method, params, signal) {
+    const response = await fetch("mcp", {
+      method: "POST",
+      headers: this.headers(),
+      body: JSON.stringify({ jsonrpc: "2.0", id, method, params }),
+      signal,
+    });
+    if (response.status === 401) {
+      throw new AuthError();
+    }
+    if (response.status === 404 && this.session) {
+      // The session ended, for example because an operator closed it
+      this.session = null;
+      throw new Error("Session ended; reload the page to reconnect");
+    }
+    if (!response.ok) {
+      throw new Error(`${response.status} ${response.statusText}`);
+    }
+
+    const session = response.headers.get("Mcp-Session-Id");
+    if (session) {
+      this.session = session;
+    }
+
+    const message = await response.json();
+    if (message.error) {
+      throw new Error(message.error.message);
+    }
+    return message.result;
+  },
+
+  // callTool calls a tool, passing progress notifications to onProgress
+  async callTool(name, args, signal, onProgress) {
+    const token = "ui-" + this.nextID;
+    this.progress.set(token, onProgress);
+    try {
+      const result = await this.request("tools/call", {
+        name,
+        arguments: args,
+        _meta: { progressToken: token },
+      }, signal);
+      if (result.isError) {
+        throw new Error(result.content.map((c) => c.text).join("\n"));
+      }
+      return result;
+    } finally {
+      this.progress.delete(token);
+    }
+  },
+
+  // listen reads the session's event stream until it closes, handing each
+  // notification to onNotification
+  async listen(onNotification) {
+    const headers = this.headers();
+    headers.Accept = "text/event-stream";
+    const response = await fetch("mcp", { headers });
+    if (!response.ok || !response.body) {
+      return;
+    }
+
+    const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+    let buffer = "";
+    for (;;) {
+      const { value, done } = await reader.read();
+      if (done) {
+        return;
+      }
+      buffer += value;
+      let end;
+      while ((end = buffer.indexOf("\n\n")) >= 0) {
+        const event = buffer.slice(0, end);
+        buffer = buffer.slice(end + 2);
+        const data = event.split("\n")
+          .filter((line) => line.startsWith("data:"))
+          .map((line) => line.slice(5).trim())
+          .join("\n");
+        if (data) {
+          onNotification(JSON.parse(data));
+        }
+      }
+    }
+  },
+};
+
+class AuthError extends Error {
+  constructor() {
+    super("Credentials required");
+  }
+}
+
+function onNotification(message) {
+  switch (message.method) {
+    case "notifications/progress": {
+      const onProgress = mcp.progress.get(message.params.progressToken);
+      if (onProgress) {
+        onProgress(message.params);
+      }
+      break;
+    }
+    case "notifications/resources/list_changed":
+      loadRoots().catch(() => {});
+      break;
+  }
+}
+
+// ---- Connection ----------------------------------------------------------
+
+async function connect() {
+  try {
+    const result = await mcp.request("initialize", {
+      protocolVersion: PROTOCOL_VERSION,
+      capabilities: {},
+      clientInfo: { name: "filesearch-web-ui", version: "1.0.0" },
+    });
+    $("token-form").hidden = true;
+    $("server").textContent = `${result.serverInfo.name} ${result.serverInfo.version}`;
+    mcp.listen(onNotification).catch(() => {});
+    await loadRoots();
+    setStatus("");
+  } catch (err) {
+    if (err instanceof AuthError) {
+      $("token-form").hidden = false;
+      $("token").focus();
+    }
+    setStatus(err.message, true);
+  }
+}
+
+async function loadRoots() {
+  const { resources } = await mcp.request("resources/list", {});
+  const select = $("root");
+  const selected = select.value;
+  select.replaceChildren(new Option("All roots", ""));
+  for (const resource of resources) {
+    if (resource.mimeType === "inode/directory" && resource.uri.startsWith(ROOT_SCHEME)) {
+      select.add(new Option(resource.name, resource.name));
+    }
+  }
+  select.value = selected;
+  if (select.value !== selected) {
+    select.value = "";
+  }
+}
+
+// ---- Search --------------------------------------------------------------
+
+let running = null;
+
+async function search(event) {
+  event.preventDefault();
+  if (running) {
+    running.abort();
+  }
+  running = new AbortController();
+  const signal = running.signal;
+
+  const mode = $("mode").value;
+  const args = searchArgs(mode);
+  const started = performance.now();
+
+  $("go").hidden = true;
+  $("stop").hidden = false;
+  setStatus("Searching…", false, true);
+
+  try {
+    const result = await mcp.callTool(mode, args, signal, (progress) => {
+      setStatus(progress.message || `${progress.progress} files examined`, false, true);
+    });
+    const elapsed = ((performance.now() - started) / 1000).toFixed(2);
+    if (mode === "find_files") {
+      renderFiles(result.structuredContent, elapsed);
+    } else {
+      renderMatches(result.structuredContent, args, elapsed);
+    }
+  } catch (err) {
+    if (err.name === "AbortError") {
+      setStatus("Stopped");
+    } else {
+      setStatus(err.message, true);
+    }
+  } finally {
+    if (running && running.signal === signal) {
+      running = null;
+      $("go").hidden = false;
+      $("stop").hidden = true;
+    }
+  }
+}
+
+function searchArgs(mode) {
+  const args = {};
+  const query = $("query").value;
+  const root = $("root").value;
+  const extensions = $("extensions").value.split(",").map((e) => e.trim()).filter(Boolean);
+  const limit = parseInt($("limit").value, 10);
+
+  if (root) args.root = root;
+  if (extensions.length) args.extensions = extensions;
+  if (limit > 0) args.limit = limit;
+
+  if (mode === "find_files") {
+    args.pattern = query;
+    return args;
+  }
+
+  args.query = query;
+  args.regex = $("regex").checked;
+  args.caseSensitive = $("case").checked;
+  args.contextLines = parseInt($("context").value, 10) || 0;
+  if ($("include").value) args.include = $("include").value;
+  return args;
+}
+
+function renderFiles(result, elapsed) {
+  const files = result.files || [];
+  const list = el("ul", "file-list");
+  for (const file of files) {
+    const item = el("li");
+    item.append(fileLink(file.root, file.path), el("span", "muted", formatSize(file.size)));
+    list.append(item);
+  }
+  $("results").replaceChildren(list);
+  setStatus(`${files.length} files${result.truncated ? " (truncated)" : ""} in ${elapsed}s`);
+}
+
+function renderMatches(result, args, elapsed) {
+  const highlight = matcher(args);
+  const matches = result.matches || [];
+  const container = document.createDocumentFragment();
+
+  // Group consecutive matches by file
+  let current = null;
+  let lines = null;
+  let lastLine = 0;
+  for (const m of matches) {
+    const key = m.root + "/" + m.path;
+    if (key !== current) {
+      current = key;
+      const file = el("div", "file");
+      const header = el("div", "file-header");
+      header.append(fileLink(m.root, m.path));
+      lines = el("div", "lines");
+      file.append(header, lines);
+      container.append(file);
+      lastLine = 0;
+    }
+
+    const first = m.line - (m.before || []).length;
+    if (lastLine && first > lastLine + 1) {
+      lines.append(el("div", "gap", "…"));
+    }
+    (m.before || []).forEach((text, i) => {
+      const n = first + i;
+      if (n > lastLine) lines.append(resultLine(m, n, text, null, true));
+    });
+    if (m.line > lastLine) {
+      lines.append(resultLine(m, m.line, m.text, highlight, false));
+    }
+    (m.after || []).forEach((text, i) => {
+      lines.append(resultLine(m, m.line + i + 1, text, null, true));
+    });
+    lastLine = m.line + (m.after || []).length;
+  }
+
+  $("results").replaceChildren(container);
+  const files = new Set(matches.map((m) => m.root + "/" + m.path)).size;
+  setStatus(`${matches.length} matches in ${files} files` +
+    `${result.truncated ? " (truncated)" : ""}; ${result.filesScanned} files, ` +
+    `${formatSize(result.bytesScanned)} scanned in ${elapsed}s`);
+}
+
+function resultLine(m, n, text, highlight, isContext) {
+  const line = el("div", isContext ? "line context" : "line");
+  const body = el("span", "text");
+  if (highlight) {
+    appendHighlighted(body, text, highlight);
+  } else {
+    body.textContent = text;
+  }
+  line.append(el("span", "lineno", String(n)), body);
+  line.addEventListener("click", () => openFile(m.root, m.path, n));
+  return line;
+}
+
+// matcher returns a global regular expression that finds the query in a
+// line, or null if it cannot be expressed in JavaScript
+function matcher(args) {
+  const flags = args.caseSensitive ? "g" : "gi";
+  const source = args.regex ? args.query : args.query.replace(/[.*+?^${}()|[\]\\]/g, "\\$&");
+  try {
+    return new RegExp(source, flags);
+  } catch {
+    return null;
+  }
+}
+
+function appendHighlighted(parent, text, re) {
+  re.lastIndex = 0;
+  let last = 0;
+  let match;
+  while ((match = re.exec(text)) !== null) {
+    if (match[0] === "") {
+      re.lastIndex++;
+      continue;
+    }
+    parent.append(text.slice(last, match.index), el("mark", null, match[0]));
+    last = match.index + match[0].length;
+  }
+  parent.append(text.slice(last));
+}
+
+// ---- Preview -------------------------------------------------------------
+
+async function openFile(root, path, line) {
+  $("preview").hidden = false;
+  $("preview-path").textContent = `${root}/${path}`;
+  const body = $("preview-body");
+  body.replaceChildren(el("div", "muted", "Loading…"));
+
+  try {
+    const result = await mcp.callTool("read_file", { root, path });
+    const content = result.structuredContent;
+    if (!content || typeof content.text !== "string") {
+      body.replaceChildren(el("div", "muted", "Binary file; no preview"));
+      return;
+    }
+    renderPreview(body, content.text, path, line);
+  } catch (err) {
+    body.replaceChildren(el("div", "error", err.message));
+  }
+}
+
+function renderPreview(body, text, path, target) {
+  const lines = text.split("\n");
+  if (lines.length > 1 && lines[lines.length - 1] === "") {
+    lines.pop();
+  }
+  const highlighter = new Highlighter(path);
+  const fragment = document.createDocumentFragment();
+  let targetLine = null;
+  lines.forEach((text, i) => {
+    const line = el("div", "line");
+    const content = el("span", "text");
+    highlighter.line(content, text);
+    line.append(el("span", "lineno", String(i + 1)), content);
+    if (i + 1 === target) {
+      line.classList.add("target");
+      targetLine = line;
+    }
+    fragment.append(line);
+  });
+  body.replaceChildren(fragment);
+  if (targetLine) {
+    targetLine.scrollIntoView({ block: "center" });
+  } else {
+    body.scrollTop = 0;
+  }
+}
+
+// Highlighter colours keywords, strings, numbers and comments. It knows
+// the comment syntax of common languages and tracks block comments across
+// lines; anything it does not recognise is shown as plain text.
+const COMMENTS = {
+  slash: { line: "//", block: ["/*", "*/"], quotes: "\"'`" },
+  hash: { line: "#", quotes: "\"'" },
+  dash: { line: "--", quotes: "\"'" },
+  markup: { block: ["<!--", "-->"], quotes: "\"" },
+};
+
+const LANGUAGES = {
+  go: "slash", js: "slash", mjs: "slash", ts: "slash", tsx: "slash", jsx: "slash",
+  c: "slash", h: "slash", cc: "slash", cpp: "slash", hpp: "slash", cs: "slash",
+  java: "slash", kt: "slash", rs: "slash", swift: "slash", scala: "slash",
+  css: "slash", scss: "slash", proto: "slash", php: "slash", dart: "slash",
+  py: "hash", sh: "hash", bash: "hash", zsh: "hash", rb: "hash", pl: "hash",
+  yaml: "hash", yml: "hash", toml: "hash", conf: "hash", ini: "hash",
+  r: "hash", mk: "hash", dockerfile: "hash", makefile: "hash",
+  sql: "dash", lua: "dash", hs: "dash",
+  html: "markup", htm: "markup", xml: "markup", svg: "markup",
+};
+
+const KEYWORDS = new Set((
+  "break case catch class const continue def default defer delete do elif else " +
+  "enum export extends false finally fn for from func function go if impl import " +
+  "in interface let match mod nil none null package pass pub raise return select " +
+  "self static struct super switch this throw true try type typeof use var void " +
+  "while with yield async await chan map range fallthrough goto lambda not and or " +
+  "is as new public private protected final abstract mut where loop trait"
+).split(" "));
+
+class Highlighter {
+  constructor(path) {
+    const base = path.split("/").pop().toLowerCase();
+    const ext = base.includes(".") ? base.split(".").pop() : base;
+    this.comments = COMMENTS[LANGUAGES[ext]] || null;
+    this.inBlock = false;
+  }
+
+  line(parent, text) {
+    const comments = this.comments;
+    if (!comments) {
+      parent.textContent = text;
+      return;
+    }
+
+    let i = 0;
+    let plain = "";
+    const flush = () => {
+      if (plain) {
+        appendWords(parent, plain);
+        plain = "";
+      }
+    };
+    const emit = (cls, s) => {
+      flush();
+      parent.append(el("span", cls, s));
+    };
+
+    // A block comment started on an earlier line continues from the start
+    // of this one
+    let blockStart = this.inBlock ? 0 : -1;
+    while (i < text.length) {
+      if (blockStart < 0 && comments.block && text.startsWith(comments.block[0], i)) {
+        blockStart = i;
+        i += comments.block[0].length;
+      }
+      if (blockStart >= 0) {
+        const end = text.indexOf(comments.block[1], i);
+        if (end < 0) {
+          emit("tok-comment", text.slice(blockStart));
+          this.inBlock = true;
+          return;
+        }
+        i = end + comments.block[1].length;
+        emit("tok-comment", text.slice(blockStart, i));
+        blockStart = -1;
+        this.inBlock = false;
+        continue;
+      }
+      if (comments.line && text.startsWith(comments.line, i)) {
+        emit("tok-comment", text.slice(i));
+        return;
+      }
+
+      const c = text[i];
+      if (comments.quotes.includes(c)) {
+        let j = i + 1;
+        while (j < text.length && text[j] !== c) {
+          j += text[j] === "\\" ? 2 : 1;
+        }
+        emit("tok-string", text.slice(i, j + 1));
+        i = j + 1;
+        continue;
+      }
+
+      plain += c;
+      i++;
+    }
+    flush();
+  }
+}
+
+// appendWords appends text, colouring keywords and numbers
+function appendWords(parent, text) {
+  const re = /\b(?:[A-Za-z_]\w*|\d[\d_]*(?:\.\d+)?(?:[eE][+-]?\d+)?|0[xX][\da-fA-F]+)\b/g;
+  let last = 0;
+  let match;
+  while ((match = re.exec(text)) !== null) {
+    const word = match[0];
+    let cls = null;
+    if (/^\d/.test(word)) {
+      cls = "tok-number";
+    } else if (KEYWORDS.has(word)) {
+      cls = "tok-keyword";
+    }
+    if (cls) {
+      parent.append(text.slice(last, match.index), el("span", cls, word));
+      last = match.index + word.length;
+    }
+  }
+  parent.append(text.slice(last));
+}
+
+// ---- Helpers -------------------------------------------------------------
+
+function el(tag, className, text) {
+  const node = document.createElement(tag);
+  if (className) node.className = className;
+  if (text !== undefined) node.textContent = text;
+  return node;
+}
+
+function fileLink(root, path, line) {
+  const link = el("a", null, `${root}/${path}`);
+  link.href = "#";
+  link.addEventListener("click", (event) => {
+    event.preventDefault();
+    openFile(root, path, line);
+  });
+  return link;
+}
+
+function formatSize(bytes) {
+  if (bytes === undefined) return "";
+  const units = ["B", "KB", "MB", "GB"];
+  let n = bytes;
+  let unit = 0;
+  while (n >= 1024 && unit < units.length - 1) {
+    n /= 1024;
+    unit++;
+  }
+  return `${unit ? n.toFixed(1) : n} ${units[unit]}`;
+}
+
+function setStatus(message, isError = false, busy = false) {
+  const status = $("status");
+  status.textContent = message;
+  status.classList.toggle("error", isError);
+  status.classList.toggle("busy", busy);
+}
+
+// ---- Wiring --------------------------------------------------------------
+
+document.addEventListener("DOMContentLoaded", () => {
+  $("search").addEventListener("submit", search);
+  $("stop").addEventListener("click", () => running && running.abort());
+  $("mode").addEventListener("change", () => {
+    document.body.classList.toggle("files-mode", $("mode").value === "find_files");
+  });
+  $("preview-close").addEventListener("click", () => {
+    $("preview").hidden = true;
+  });
+  $("token-form").addEventListener("submit", (event) => {
+    event.preventDefault();
+    sessionStorage.setItem("token", $("token").value);
+    sessionStorage.setItem("tokenKind", $("token-kind").value);
+    connect();
+  });
+
+  $("token-kind").value = sessionStorage.getItem("tokenKind") || "bearer";
+  connect();
+});
@@ -0,0 +1,133 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// DefaultBufferSize is the number of traces kept by a RingBuffer
+const DefaultBufferSize = 100
+
+// FileExporter appends each trace to a file as one OTLP/JSON export request
+// per line
+type FileExporter struct {
+	service string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileExporter opens path for appending, creating it if necessary
+func NewFileExporter(path, service string) (*FileExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening trace file: %w", err)
+	}
+	return &FileExporter{service: service, file: file}, nil
+}
+
+// Export implements Exporter
+func (e *FileExporter) Export(spans []SpanData) {
+	data, err := json.Marshal(Encode(e.service, spans))
+	if err != nil {
+		slog.Warn("Failed to encode trace", "error", err)
+		return
+	}
+	data = append(data, '\n')
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.file.Write(data); err != nil {
+		slog.Warn("Failed to write trace file", "error", err)
+	}
+}
+
+// Close closes the file
+func (e *FileExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.file.Close()
+}
+
+// RingBuffer keeps the most recent traces in memory and serves them over
+// HTTP. It is safe for concurrent use.
+type RingBuffer struct {
+	service string
+
+	mu     sync.Mutex
+	traces [][]SpanData
+	next   int
+	full   bool
+}
+
+// NewRingBuffer creates a buffer holding up to size traces
+func NewRingBuffer(size int, service string) *RingBuffer {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	return &RingBuffer{service: service, traces: make([][]SpanData, size)}
+}
+
+// Export implements Exporter
+func (b *RingBuffer) Export(spans []SpanData) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.traces[b.next] = spans
+	b.next = (b.next + 1) % len(b.traces)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Traces returns the buffered traces, most recent first
+func (b *RingBuffer) Traces() [][]SpanData {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count := b.next
+	if b.full {
+		count = len(b.traces)
+	}
+	traces := make([][]SpanData, 0, count)
+	for i := 1; i <= count; i++ {
+		traces = append(traces, b.traces[(b.next-i+len(b.traces))%len(b.traces)])
+	}
+	return traces
+}
+
+// ServeHTTP serves the buffered traces, most recent first, as one OTLP/JSON
+// export request. The traceId query parameter selects a single trace and
+// limit caps the number of traces returned.
+func (b *RingBuffer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	traceID := r.URL.Query().Get("traceId")
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	var spans []SpanData
+	count := 0
+	for _, trace := range b.Traces() {
+		if len(trace) == 0 || (traceID != "" && trace[0].TraceID.String() != traceID) {
+			continue
+		}
+		if limit > 0 && count == limit {
+			break
+		}
+		spans = append(spans, trace...)
+		count++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Encode(b.service, spans))
+}
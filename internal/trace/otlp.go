@@ -0,0 +1,147 @@
+package trace
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// The types below mirror the OTLP/JSON encoding of an
+// ExportTraceServiceRequest, so that exported files can be loaded by
+// OpenTelemetry tooling
+
+// ExportRequest is a batch of spans grouped by resource and scope
+type ExportRequest struct {
+	ResourceSpans []ResourceSpans `json:"resourceSpans"`
+}
+
+// ResourceSpans are the spans produced by one resource
+type ResourceSpans struct {
+	Resource   Resource     `json:"resource"`
+	ScopeSpans []ScopeSpans `json:"scopeSpans"`
+}
+
+// Resource describes the process that produced spans
+type Resource struct {
+	Attributes []KeyValue `json:"attributes"`
+}
+
+// ScopeSpans are the spans produced by one instrumentation scope
+type ScopeSpans struct {
+	Scope Scope      `json:"scope"`
+	Spans []OTLPSpan `json:"spans"`
+}
+
+// Scope names the instrumentation that produced spans
+type Scope struct {
+	Name string `json:"name"`
+}
+
+// OTLPSpan is a span in OTLP/JSON form
+type OTLPSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	Status            Status     `json:"status"`
+}
+
+// Status codes, numbered as in OTLP
+const (
+	StatusUnset = 0
+	StatusOK    = 1
+	StatusError = 2
+)
+
+// Status is the outcome of a span
+type Status struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// KeyValue is an OTLP attribute
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue holds exactly one attribute value. OTLP/JSON encodes 64-bit
+// integers as strings.
+type AnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// scopeName identifies this package as the instrumentation scope
+const scopeName = "github.com/aawadall/go-mcp-filesearch/internal/trace"
+
+// Encode converts the spans of a trace to an OTLP export request from the
+// named service
+func Encode(service string, spans []SpanData) ExportRequest {
+	otlpSpans := make([]OTLPSpan, 0, len(spans))
+	for _, span := range spans {
+		otlpSpans = append(otlpSpans, encodeSpan(span))
+	}
+
+	return ExportRequest{
+		ResourceSpans: []ResourceSpans{
+			{
+				Resource: Resource{
+					Attributes: []KeyValue{keyValue("service.name", service)},
+				},
+				ScopeSpans: []ScopeSpans{
+					{Scope: Scope{Name: scopeName}, Spans: otlpSpans},
+				},
+			},
+		},
+	}
+}
+
+func encodeSpan(span SpanData) OTLPSpan {
+	out := OTLPSpan{
+		TraceID:           span.TraceID.String(),
+		SpanID:            span.SpanID.String(),
+		Name:              span.Name,
+		Kind:              int(span.Kind),
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+	}
+	if span.ParentSpanID.IsValid() {
+		out.ParentSpanID = span.ParentSpanID.String()
+	}
+	for _, a := range span.Attributes {
+		out.Attributes = append(out.Attributes, keyValue(a.Key, a.Value))
+	}
+	if span.Error {
+		out.Status = Status{Code: StatusError, Message: span.StatusMessage}
+	}
+	return out
+}
+
+// keyValue converts an attribute value to its OTLP form
+func keyValue(key string, value interface{}) KeyValue {
+	var v AnyValue
+	switch value := value.(type) {
+	case string:
+		v.StringValue = &value
+	case bool:
+		v.BoolValue = &value
+	case int:
+		s := strconv.Itoa(value)
+		v.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(value, 10)
+		v.IntValue = &s
+	case float64:
+		v.DoubleValue = &value
+	default:
+		s := fmt.Sprint(value)
+		v.StringValue = &s
+	}
+	return KeyValue{Key: key, Value: v}
+}
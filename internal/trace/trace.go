@@ -0,0 +1,335 @@
+// Package trace records spans for requests as they pass through the
+// server, propagates W3C trace context and exports finished traces in the
+// OTLP JSON format. Spans travel in the context; starting a span from a
+// context without one does nothing, so instrumented code costs little when
+// tracing is off.
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxSpans is the most spans recorded for one trace on this server;
+// later spans are counted but dropped
+const DefaultMaxSpans = 512
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id SpanID) String() string  { return hex.EncodeToString(id[:]) }
+
+// IsValid reports whether the ID is not all zeros
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+// IsValid reports whether the ID is not all zeros
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+// SpanContext is the part of a span that is propagated between processes
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether both IDs are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent formats the span context as a W3C traceparent header value
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent parses a W3C traceparent header value
+func ParseTraceparent(value string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q", value)
+	}
+
+	var sc SpanContext
+	var flags [1]byte
+	if !decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) || !decodeHex(flags[:], parts[3]) {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q", value)
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q: zero trace or span ID", value)
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+// decodeHex decodes lowercase hex into dst, which it must fill exactly
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != 2*len(dst) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// Kind is the OTLP span kind
+type Kind int
+
+// Span kinds, numbered as in OTLP
+const (
+	KindInternal Kind = 1
+	KindServer   Kind = 2
+	KindClient   Kind = 3
+)
+
+// Attr is a span attribute
+type Attr struct {
+	Key   string
+	Value interface{}
+}
+
+// SpanData is a finished span
+type SpanData struct {
+	Name          string
+	Kind          Kind
+	TraceID       TraceID
+	SpanID        SpanID
+	ParentSpanID  SpanID
+	Start         time.Time
+	End           time.Time
+	Attributes    []Attr
+	Error         bool
+	StatusMessage string
+}
+
+// Exporter receives the spans of a trace when its local root span ends
+type Exporter interface {
+	Export(spans []SpanData)
+}
+
+// Tracer starts root spans and hands finished traces to its exporters
+type Tracer struct {
+	exporters []Exporter
+	maxSpans  int
+}
+
+// NewTracer creates a tracer that exports to the given exporters
+func NewTracer(exporters ...Exporter) *Tracer {
+	return &Tracer{exporters: exporters, maxSpans: DefaultMaxSpans}
+}
+
+// recording collects the spans of one trace started by this process
+type recording struct {
+	tracer  *Tracer
+	mu      sync.Mutex
+	spans   []SpanData
+	started int
+	dropped int
+	// exported is set once the local root has ended; spans ending later
+	// are discarded
+	exported bool
+}
+
+// Span is an operation being timed. A nil *Span is valid and records
+// nothing, as is a span of an unsampled trace.
+type Span struct {
+	ctx SpanContext
+	rec *recording
+
+	mu   sync.Mutex
+	data SpanData
+	root bool
+	done bool
+}
+
+type spanKey struct{}
+
+// FromContext returns the span in ctx, or nil
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// SpanContextFromContext returns the propagated context of the span in ctx
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	span := FromContext(ctx)
+	if span == nil {
+		return SpanContext{}, false
+	}
+	return span.ctx, true
+}
+
+// Start begins a span named name on a nil-safe tracer. If remote is valid
+// the span continues that trace as a local root; otherwise it is a child of
+// the span in ctx, or the root of a new trace.
+func (t *Tracer) Start(ctx context.Context, name string, remote SpanContext, kind Kind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	if !remote.IsValid() {
+		if parent := FromContext(ctx); parent != nil {
+			return startChild(ctx, parent, name, kind)
+		}
+		remote = SpanContext{TraceID: newTraceID(), Sampled: true}
+	}
+
+	span := &Span{
+		ctx:  SpanContext{TraceID: remote.TraceID, SpanID: newSpanID(), Sampled: remote.Sampled},
+		root: true,
+	}
+	if remote.Sampled {
+		span.rec = &recording{tracer: t, started: 1}
+		span.data = SpanData{
+			Name:         name,
+			Kind:         kind,
+			TraceID:      span.ctx.TraceID,
+			SpanID:       span.ctx.SpanID,
+			ParentSpanID: remote.SpanID,
+			Start:        time.Now(),
+		}
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Start begins a child of the span in ctx. Without one, or when the trace is
+// not sampled, it returns ctx unchanged and a nil span.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return startChild(ctx, parent, name, KindInternal)
+}
+
+// startChild begins a span under parent, unless the trace is unsampled or
+// already has the maximum number of spans
+func startChild(ctx context.Context, parent *Span, name string, kind Kind) (context.Context, *Span) {
+	rec := parent.rec
+	if rec == nil {
+		return ctx, nil
+	}
+
+	rec.mu.Lock()
+	if rec.started >= rec.tracer.maxSpans {
+		rec.dropped++
+		rec.mu.Unlock()
+		return ctx, nil
+	}
+	rec.started++
+	rec.mu.Unlock()
+
+	span := &Span{
+		ctx: SpanContext{TraceID: parent.ctx.TraceID, SpanID: newSpanID(), Sampled: true},
+		rec: rec,
+		data: SpanData{
+			Name:         name,
+			Kind:         kind,
+			TraceID:      parent.ctx.TraceID,
+			ParentSpanID: parent.ctx.SpanID,
+			Start:        time.Now(),
+		},
+	}
+	span.data.SpanID = span.ctx.SpanID
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SetAttr sets an attribute of the span
+func (s *Span) SetAttr(key string, value interface{}) {
+	if s == nil || s.rec == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, a := range s.data.Attributes {
+		if a.Key == key {
+			s.data.Attributes[i].Value = value
+			return
+		}
+	}
+	s.data.Attributes = append(s.data.Attributes, Attr{Key: key, Value: value})
+}
+
+// RecordError marks the span as failed with err's message. A nil error is
+// ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || s.rec == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Error = true
+	s.data.StatusMessage = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span. Ending the local root of a trace exports every
+// span of the trace that has ended.
+func (s *Span) End() {
+	if s == nil || s.rec == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return
+	}
+	s.done = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	rec := s.rec
+	rec.mu.Lock()
+	if rec.exported {
+		rec.mu.Unlock()
+		return
+	}
+	if s.root && rec.dropped > 0 {
+		data.Attributes = append(data.Attributes, Attr{Key: "trace.dropped_spans", Value: rec.dropped})
+	}
+	rec.spans = append(rec.spans, data)
+	spans := rec.spans
+	if s.root {
+		rec.exported = true
+		rec.spans = nil
+	}
+	rec.mu.Unlock()
+
+	if s.root {
+		for _, exporter := range rec.tracer.exporters {
+			exporter.Export(spans)
+		}
+	}
+}
+
+// TraceID returns the ID of the span's trace, or a zero ID for a nil span
+func (s *Span) TraceID() TraceID {
+	if s == nil {
+		return TraceID{}
+	}
+	return s.ctx.TraceID
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	rand.Read(id[:])
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	rand.Read(id[:])
+	return id
+}
@@ -0,0 +1,540 @@
+// Package client calls MCP servers over stdio, plain HTTP and streamable
+// HTTP. A Client performs the initialize handshake, offers typed methods for
+// the MCP requests, delivers server notifications to callbacks and, if the
+// connection is lost, reconnects and restores the session's log level and
+// subscriptions.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+// Errors returned by the client and its transports
+var (
+	ErrClosed       = errors.New("client closed")
+	ErrDisconnected = errors.New("disconnected from server")
+	// ErrSessionExpired is returned when a streamable HTTP server no longer
+	// knows the session. It is a kind of ErrDisconnected.
+	ErrSessionExpired = fmt.Errorf("%w: session expired", ErrDisconnected)
+
+	errNotConnected = errors.New("client not connected")
+)
+
+// Transport carries JSON-RPC messages between a Client and a server
+type Transport interface {
+	// Connect opens a connection, replacing any earlier one. Messages from
+	// the server are passed to receive, one at a time. lost is called if
+	// the connection fails after Connect returns.
+	Connect(ctx context.Context, receive func(message []byte), lost func(err error)) error
+	// Send delivers a message to the server. Responses may be passed to
+	// receive before Send returns. Send returns an error wrapping
+	// ErrDisconnected only if the message was not delivered.
+	Send(ctx context.Context, message []byte) error
+	// Close closes the connection for good
+	Close() error
+}
+
+// ReconnectPolicy controls how a Client reconnects after losing its
+// connection. The delay between attempts doubles from MinDelay up to
+// MaxDelay. MaxAttempts of zero keeps trying until the client is closed.
+type ReconnectPolicy struct {
+	MaxAttempts int
+	MinDelay    time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultReconnectPolicy is used by clients unless SetReconnect replaces it
+var DefaultReconnectPolicy = ReconnectPolicy{
+	MinDelay: 100 * time.Millisecond,
+	MaxDelay: 30 * time.Second,
+}
+
+// connectTimeout bounds each reconnection attempt
+const connectTimeout = 30 * time.Second
+
+// notificationQueueSize is the number of notifications buffered for the
+// callbacks before reading from the server blocks
+const notificationQueueSize = 256
+
+// Client is a connection to an MCP server. Its methods are safe for
+// concurrent use; the Set and On methods must be called before Connect.
+type Client struct {
+	transport Transport
+	info      Implementation
+	reconnect *ReconnectPolicy
+
+	nextID        atomic.Int64
+	notifications chan Notification
+	dispatching   sync.Once
+	ctx           context.Context
+	cancel        context.CancelFunc
+
+	mu            sync.Mutex
+	handlers      map[string][]func(Notification)
+	pending       map[int64]chan reply
+	progress      map[string]func(Progress)
+	subscriptions map[string]bool
+	logLevel      string
+	server        *InitializeResult
+	// ready is closed while the client is connected
+	ready     chan struct{}
+	failed    error
+	redialing bool
+	closed    bool
+}
+
+// reply is a response to a request, or the error that prevented one
+type reply struct {
+	msg *message
+	err error
+}
+
+// message is any JSON-RPC message received from the server
+type message struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *Error          `json:"error,omitempty"`
+}
+
+// New creates a client that talks to a server over transport. Call Connect
+// before making requests.
+func New(transport Transport) *Client {
+	ctx, cancel := context.WithCancel(context.Background())
+	reconnect := DefaultReconnectPolicy
+	return &Client{
+		transport:     transport,
+		info:          Implementation{Name: "go-mcp-filesearch-client", Version: models.ServerVersion},
+		reconnect:     &reconnect,
+		notifications: make(chan Notification, notificationQueueSize),
+		ctx:           ctx,
+		cancel:        cancel,
+		handlers:      make(map[string][]func(Notification)),
+		pending:       make(map[int64]chan reply),
+		progress:      make(map[string]func(Progress)),
+		subscriptions: make(map[string]bool),
+		ready:         make(chan struct{}),
+		failed:        errNotConnected,
+	}
+}
+
+// SetClientInfo sets the name and version the client introduces itself with
+func (c *Client) SetClientInfo(name, version string) {
+	c.info = Implementation{Name: name, Version: version}
+}
+
+// SetReconnect replaces the reconnection policy; nil disables reconnection
+func (c *Client) SetReconnect(policy *ReconnectPolicy) {
+	c.reconnect = policy
+}
+
+// OnNotification calls fn for every notification with the given method, or
+// for every notification if method is empty. Callbacks run one at a time in
+// the order the notifications arrived.
+func (c *Client) OnNotification(method string, fn func(Notification)) {
+	c.mu.Lock()
+	c.handlers[method] = append(c.handlers[method], fn)
+	c.mu.Unlock()
+}
+
+// OnLog calls fn for every log message the server sends after SetLogLevel
+func (c *Client) OnLog(fn func(LogMessage)) {
+	c.OnNotification(models.NotificationMessage, func(n Notification) {
+		var msg LogMessage
+		if json.Unmarshal(n.Params, &msg) == nil {
+			fn(msg)
+		}
+	})
+}
+
+// OnResourceUpdated calls fn with the URI of every subscribed resource the
+// server reports as updated
+func (c *Client) OnResourceUpdated(fn func(uri string)) {
+	c.OnNotification(models.NotificationResourceUpdated, func(n Notification) {
+		var params struct {
+			URI string `json:"uri"`
+		}
+		if json.Unmarshal(n.Params, &params) == nil {
+			fn(params.URI)
+		}
+	})
+}
+
+// Connect connects to the server and performs the initialize handshake
+func (c *Client) Connect(ctx context.Context) (*InitializeResult, error) {
+	c.dispatching.Do(func() { go c.dispatch() })
+	if err := c.connect(ctx); err != nil {
+		return nil, err
+	}
+	return c.ServerInfo(), nil
+}
+
+// connect opens the transport and performs the handshake, restoring the log
+// level and subscriptions of an earlier connection
+func (c *Client) connect(ctx context.Context) error {
+	if err := c.transport.Connect(ctx, c.receive, c.lost); err != nil {
+		return err
+	}
+
+	var result InitializeResult
+	_, err := c.roundTrip(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": models.MCPProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      c.info,
+	}, &result)
+	if err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	if err := c.sendNotification(ctx, models.NotificationInitialized, nil); err != nil {
+		return fmt.Errorf("initialized: %w", err)
+	}
+
+	c.mu.Lock()
+	c.server = &result
+	level := c.logLevel
+	uris := make([]string, 0, len(c.subscriptions))
+	for uri := range c.subscriptions {
+		uris = append(uris, uri)
+	}
+	c.mu.Unlock()
+
+	if level != "" {
+		if _, err := c.roundTrip(ctx, "logging/setLevel", map[string]interface{}{"level": level}, nil); err != nil {
+			return fmt.Errorf("restoring log level: %w", err)
+		}
+	}
+	for _, uri := range uris {
+		if _, err := c.roundTrip(ctx, "resources/subscribe", map[string]interface{}{"uri": uri}, nil); err != nil {
+			return fmt.Errorf("restoring subscription to %s: %w", uri, err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	c.failed = nil
+	c.redialing = false
+	select {
+	case <-c.ready:
+	default:
+		close(c.ready)
+	}
+	return nil
+}
+
+// lost fails the requests awaiting responses on a broken connection and,
+// unless reconnection is disabled, starts reconnecting
+func (c *Client) lost(err error) {
+	if !errors.Is(err, ErrDisconnected) {
+		err = fmt.Errorf("%w: %v", ErrDisconnected, err)
+	}
+
+	c.mu.Lock()
+	select {
+	case <-c.ready:
+		c.ready = make(chan struct{})
+	default:
+	}
+	pending := c.pending
+	c.pending = make(map[int64]chan reply)
+	redial := !c.closed && c.reconnect != nil && !c.redialing
+	if redial {
+		c.redialing = true
+	} else if c.reconnect == nil {
+		c.failed = err
+	}
+	c.mu.Unlock()
+
+	for _, ch := range pending {
+		ch <- reply{err: err}
+	}
+	if redial {
+		go c.redial()
+	}
+}
+
+// redial reconnects with backoff until it succeeds, the policy gives up or
+// the client is closed
+func (c *Client) redial() {
+	policy := *c.reconnect
+	delay := policy.MinDelay
+	var err error
+	for attempt := 1; policy.MaxAttempts == 0 || attempt <= policy.MaxAttempts; attempt++ {
+		select {
+		case <-time.After(delay):
+		case <-c.ctx.Done():
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.ctx, connectTimeout)
+		err = c.connect(ctx)
+		cancel()
+		if err == nil {
+			return
+		}
+		if delay *= 2; delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+	}
+
+	c.mu.Lock()
+	c.redialing = false
+	c.failed = fmt.Errorf("%w: reconnecting failed: %v", ErrDisconnected, err)
+	c.mu.Unlock()
+}
+
+// awaitReady waits until the client is connected
+func (c *Client) awaitReady(ctx context.Context) error {
+	c.mu.Lock()
+	ready, failed, closed := c.ready, c.failed, c.closed
+	c.mu.Unlock()
+	switch {
+	case closed:
+		return ErrClosed
+	case failed != nil:
+		return failed
+	}
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.ctx.Done():
+		return ErrClosed
+	}
+}
+
+// Call sends a request and unmarshals its result into result, which may be
+// nil to discard it. A request that could not be delivered because the
+// connection was lost is sent again once the client has reconnected.
+// Cancelling ctx abandons the request and sends notifications/cancelled so
+// that the server stops working on it.
+func (c *Client) Call(ctx context.Context, method string, params, result interface{}) error {
+	const maxSends = 3
+	var err error
+	for i := 0; i < maxSends; i++ {
+		if err := c.awaitReady(ctx); err != nil {
+			return err
+		}
+		var sent bool
+		sent, err = c.roundTrip(ctx, method, params, result)
+		if sent || c.reconnect == nil {
+			return err
+		}
+	}
+	return err
+}
+
+// roundTrip sends a request and waits for its response. It reports whether
+// the request reached the transport's connection.
+func (c *Client) roundTrip(ctx context.Context, method string, params, result interface{}) (bool, error) {
+	id := c.nextID.Add(1)
+	request := map[string]interface{}{
+		"jsonrpc": models.JSONRPCVersion,
+		"id":      id,
+		"method":  method,
+	}
+	if params != nil {
+		request["params"] = params
+	}
+	data, err := json.Marshal(request)
+	if err != nil {
+		return false, fmt.Errorf("encoding %s request: %w", method, err)
+	}
+
+	ch := make(chan reply, 1)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return false, ErrClosed
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.transport.Send(ctx, data); err != nil {
+		if errors.Is(err, ErrDisconnected) {
+			c.lost(err)
+			return false, err
+		}
+		return true, err
+	}
+
+	select {
+	case r := <-ch:
+		switch {
+		case r.err != nil:
+			return true, r.err
+		case r.msg.Error != nil:
+			return true, r.msg.Error
+		case result != nil:
+			if err := json.Unmarshal(r.msg.Result, result); err != nil {
+				return true, fmt.Errorf("decoding %s result: %w", method, err)
+			}
+		}
+		return true, nil
+	case <-ctx.Done():
+		go c.cancelRequest(id, ctx.Err())
+		return true, ctx.Err()
+	case <-c.ctx.Done():
+		return true, ErrClosed
+	}
+}
+
+// cancelRequest tells the server that the client no longer wants the
+// response to a request, so that it stops working on it
+func (c *Client) cancelRequest(id int64, reason error) {
+	c.sendNotification(c.ctx, models.NotificationCancelled, models.CancelledParams{RequestID: id, Reason: reason.Error()})
+}
+
+// sendNotification sends a notification to the server; params may be nil
+func (c *Client) sendNotification(ctx context.Context, method string, params interface{}) error {
+	message := map[string]interface{}{"jsonrpc": models.JSONRPCVersion, "method": method}
+	if params != nil {
+		message["params"] = params
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return c.transport.Send(ctx, data)
+}
+
+// receive handles a message, or batch of messages, from the server
+func (c *Client) receive(data []byte) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var batch []json.RawMessage
+		if json.Unmarshal(data, &batch) == nil {
+			for _, item := range batch {
+				c.receive(item)
+			}
+		}
+		return
+	}
+
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return
+	}
+
+	switch {
+	case msg.Method != "" && len(msg.ID) == 0:
+		select {
+		case c.notifications <- Notification{Method: msg.Method, Params: msg.Params}:
+		case <-c.ctx.Done():
+		}
+
+	case msg.Method != "":
+		// This client offers no methods to the server
+		go c.refuse(msg.ID, msg.Method)
+
+	default:
+		id, err := strconv.ParseInt(string(msg.ID), 10, 64)
+		if err != nil {
+			return
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[id]
+		delete(c.pending, id)
+		c.mu.Unlock()
+		if ok {
+			ch <- reply{msg: &msg}
+		}
+	}
+}
+
+// refuse answers a request from the server with method not found
+func (c *Client) refuse(id json.RawMessage, method string) {
+	data, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": models.JSONRPCVersion,
+		"id":      id,
+		"error": Error{
+			Code:    models.ErrCodeMethodNotFound,
+			Message: "method not found: " + method,
+		},
+	})
+	if err == nil {
+		c.transport.Send(c.ctx, data)
+	}
+}
+
+// dispatch delivers notifications to the callbacks until the client is
+// closed
+func (c *Client) dispatch() {
+	for {
+		select {
+		case n := <-c.notifications:
+			c.notify(n)
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// notify passes a notification to its callbacks, and progress to the
+// request that asked for it
+func (c *Client) notify(n Notification) {
+	c.mu.Lock()
+	handlers := append(append([]func(Notification){}, c.handlers[n.Method]...), c.handlers[""]...)
+	c.mu.Unlock()
+
+	if n.Method == models.NotificationProgress {
+		var params struct {
+			Progress
+			ProgressToken interface{} `json:"progressToken"`
+		}
+		if json.Unmarshal(n.Params, &params) == nil {
+			c.mu.Lock()
+			fn := c.progress[fmt.Sprint(params.ProgressToken)]
+			c.mu.Unlock()
+			if fn != nil {
+				fn(params.Progress)
+			}
+		}
+	}
+
+	for _, fn := range handlers {
+		fn(n)
+	}
+}
+
+// ServerInfo returns the server's answer to the latest initialize
+func (c *Client) ServerInfo() *InitializeResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.server
+}
+
+// Close disconnects from the server. Requests in flight fail with
+// ErrClosed.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+
+	c.cancel()
+	return c.transport.Close()
+}
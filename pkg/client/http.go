@@ -0,0 +1,370 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionHeader carries the session ID of a streamable HTTP connection
+const sessionHeader = "Mcp-Session-Id"
+
+// Delays between attempts to reopen a streamable HTTP event stream
+const (
+	streamRetryMin = 250 * time.Millisecond
+	streamRetryMax = 30 * time.Second
+)
+
+// closeSessionTimeout bounds the request ending a session on Close
+const closeSessionTimeout = 5 * time.Second
+
+// HTTPError is returned for HTTP responses that carry no JSON-RPC message,
+// such as 401 for missing credentials or 429 when rate limited
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+// HTTPTransport posts each message to the server's MCP endpoint and passes
+// the response to the client. It keeps no session, so the server cannot
+// send it notifications; use StreamableHTTPTransport for those.
+type HTTPTransport struct {
+	// URL is the MCP endpoint, such as http://localhost:8080/mcp
+	URL string
+	// Header is added to every request, for example to carry credentials
+	Header http.Header
+	// Client sends the requests; nil uses http.DefaultClient
+	Client *http.Client
+
+	mu      sync.Mutex
+	receive func([]byte)
+}
+
+// NewHTTPTransport returns a transport posting to the MCP endpoint at url
+func NewHTTPTransport(url string) *HTTPTransport {
+	return &HTTPTransport{URL: url, Header: make(http.Header)}
+}
+
+// Connect prepares the transport; no connection is held between requests
+func (t *HTTPTransport) Connect(ctx context.Context, receive func([]byte), lost func(error)) error {
+	t.mu.Lock()
+	t.receive = receive
+	t.mu.Unlock()
+	return nil
+}
+
+// Send posts a message and passes the response to the client
+func (t *HTTPTransport) Send(ctx context.Context, message []byte) error {
+	t.mu.Lock()
+	receive := t.receive
+	t.mu.Unlock()
+	_, err := post(ctx, t.Client, t.URL, t.Header, "", message, receive)
+	return err
+}
+
+// Close does nothing; there is no connection to close
+func (t *HTTPTransport) Close() error {
+	return nil
+}
+
+// StreamableHTTPTransport posts messages to the server's MCP endpoint within
+// the session started by initialize, and keeps an event stream open for the
+// server's notifications. A dropped stream is reopened; an expired session
+// makes the client reconnect and start a new one.
+type StreamableHTTPTransport struct {
+	// URL is the MCP endpoint, such as http://localhost:8080/mcp
+	URL string
+	// Header is added to every request, for example to carry credentials
+	Header http.Header
+	// Client sends the requests; nil uses http.DefaultClient. Its Timeout
+	// must be zero, or the event stream is cut off after it.
+	Client *http.Client
+
+	mu      sync.Mutex
+	receive func([]byte)
+	lost    func(error)
+	session string
+	// stopStream closes the session's event stream
+	stopStream context.CancelFunc
+}
+
+// NewStreamableHTTPTransport returns a transport for the MCP endpoint at url
+func NewStreamableHTTPTransport(url string) *StreamableHTTPTransport {
+	return &StreamableHTTPTransport{URL: url, Header: make(http.Header)}
+}
+
+// Connect ends any earlier session; the next initialize starts a new one
+func (t *StreamableHTTPTransport) Connect(ctx context.Context, receive func([]byte), lost func(error)) error {
+	t.endSession(ctx)
+	t.mu.Lock()
+	t.receive, t.lost = receive, lost
+	t.mu.Unlock()
+	return nil
+}
+
+// Send posts a message within the session. When a response starts a
+// session, Send opens its event stream before returning, so that
+// notifications for the next request are not missed.
+func (t *StreamableHTTPTransport) Send(ctx context.Context, message []byte) error {
+	t.mu.Lock()
+	receive, session := t.receive, t.session
+	t.mu.Unlock()
+
+	started, err := post(ctx, t.Client, t.URL, t.Header, session, message, receive)
+	if err != nil {
+		if errors.Is(err, ErrSessionExpired) {
+			t.expire(session)
+		}
+		return err
+	}
+	if started == "" || started == session {
+		return nil
+	}
+
+	t.mu.Lock()
+	t.session = started
+	if t.stopStream != nil {
+		t.stopStream()
+	}
+	streamCtx, stop := context.WithCancel(context.Background())
+	t.stopStream = stop
+	t.mu.Unlock()
+
+	body, err := t.openStream(streamCtx, started)
+	if errors.Is(err, errNoStream) {
+		return nil
+	}
+	go t.listen(streamCtx, started, body)
+	return nil
+}
+
+// errNoStream reports that the server offers no event stream
+var errNoStream = errors.New("server offers no event stream")
+
+// openStream opens the event stream of a session
+func (t *StreamableHTTPTransport) openStream(ctx context.Context, session string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", t.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	setHeaders(req, t.Header)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(sessionHeader, session)
+
+	resp, err := httpClient(t.Client).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrSessionExpired
+	case http.StatusMethodNotAllowed:
+		resp.Body.Close()
+		return nil, errNoStream
+	default:
+		return nil, statusError(resp)
+	}
+}
+
+// listen passes the notifications on a session's event stream to the
+// client, reopening the stream with backoff if it drops, until ctx is
+// cancelled or the session expires
+func (t *StreamableHTTPTransport) listen(ctx context.Context, session string, body io.ReadCloser) {
+	delay := streamRetryMin
+	for {
+		if body != nil {
+			t.mu.Lock()
+			receive := t.receive
+			t.mu.Unlock()
+			readEvents(body, receive)
+			body.Close()
+			delay = streamRetryMin
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+
+		var err error
+		body, err = t.openStream(ctx, session)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case errors.Is(err, ErrSessionExpired):
+			if t.expire(session) {
+				t.mu.Lock()
+				lost := t.lost
+				t.mu.Unlock()
+				lost(err)
+			}
+			return
+		case errors.Is(err, errNoStream):
+			return
+		case err != nil:
+			body = nil
+			if delay *= 2; delay > streamRetryMax {
+				delay = streamRetryMax
+			}
+		}
+	}
+}
+
+// expire forgets a session the server no longer knows, reporting whether
+// it was the current one
+func (t *StreamableHTTPTransport) expire(session string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.session != session {
+		return false
+	}
+	t.session = ""
+	if t.stopStream != nil {
+		t.stopStream()
+		t.stopStream = nil
+	}
+	return true
+}
+
+// endSession closes the event stream and asks the server to end the
+// session
+func (t *StreamableHTTPTransport) endSession(ctx context.Context) {
+	t.mu.Lock()
+	session := t.session
+	t.mu.Unlock()
+	if session == "" || !t.expire(session) {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", t.URL, nil)
+	if err != nil {
+		return
+	}
+	setHeaders(req, t.Header)
+	req.Header.Set(sessionHeader, session)
+	if resp, err := httpClient(t.Client).Do(req); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// Close ends the session
+func (t *StreamableHTTPTransport) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), closeSessionTimeout)
+	defer cancel()
+	t.endSession(ctx)
+	return nil
+}
+
+// httpClient returns client, or the default client if it is nil
+func httpClient(client *http.Client) *http.Client {
+	if client == nil {
+		return http.DefaultClient
+	}
+	return client
+}
+
+// setHeaders adds the configured headers to a request
+func setHeaders(req *http.Request, header http.Header) {
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+}
+
+// statusError reads an unsuccessful response into an HTTPError
+func statusError(resp *http.Response) error {
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return &HTTPError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+}
+
+// post sends a message to an MCP endpoint, within session if it is not
+// empty, and passes the messages in the response, JSON or an event stream,
+// to receive. It returns the session ID set by the response.
+func post(ctx context.Context, client *http.Client, url string, header http.Header, session string, message []byte, receive func([]byte)) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(message))
+	if err != nil {
+		return "", err
+	}
+	setHeaders(req, header)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if session != "" {
+		req.Header.Set(sessionHeader, session)
+	}
+
+	resp, err := httpClient(client).Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("%w: %v", ErrDisconnected, err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound && session != "":
+		resp.Body.Close()
+		return "", ErrSessionExpired
+	case resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusNoContent:
+		resp.Body.Close()
+		return resp.Header.Get(sessionHeader), nil
+	case resp.StatusCode != http.StatusOK:
+		return "", statusError(resp)
+	}
+	defer resp.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		readEvents(resp.Body, receive)
+	} else {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", fmt.Errorf("reading response: %w", err)
+		}
+		receive(body)
+	}
+	return resp.Header.Get(sessionHeader), nil
+}
+
+// readEvents passes the data of each server-sent event to receive until
+// the stream ends
+func readEvents(r io.Reader, receive func([]byte)) {
+	reader := bufio.NewReader(r)
+	var data bytes.Buffer
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				receive(bytes.Clone(data.Bytes()))
+				data.Reset()
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+)
+
+// progressKey is the context key of a progress callback
+type progressKey struct{}
+
+// progressTokens numbers the progress tokens of all clients
+var progressTokens atomic.Int64
+
+// WithProgress returns a context that makes CallTool ask the server for
+// progress notifications and pass them to fn. Like the notification
+// callbacks, fn runs on the client's notification goroutine.
+func WithProgress(ctx context.Context, fn func(Progress)) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ListTools returns the tools the server offers the client
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var result struct {
+		Tools []Tool `json:"tools"`
+	}
+	if err := c.Call(ctx, "tools/list", map[string]interface{}{}, &result); err != nil {
+		return nil, err
+	}
+	return result.Tools, nil
+}
+
+// CallTool calls a tool with the given arguments. A tool that fails returns
+// a result with IsError set, not an error.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (*ToolResult, error) {
+	params := map[string]interface{}{
+		"name":      name,
+		"arguments": args,
+	}
+	if fn, ok := ctx.Value(progressKey{}).(func(Progress)); ok && fn != nil {
+		token := "progress-" + strconv.FormatInt(progressTokens.Add(1), 10)
+		params["_meta"] = map[string]interface{}{"progressToken": token}
+		c.mu.Lock()
+		c.progress[token] = fn
+		c.mu.Unlock()
+		defer func() {
+			c.mu.Lock()
+			delete(c.progress, token)
+			c.mu.Unlock()
+		}()
+	}
+
+	var result ToolResult
+	if err := c.Call(ctx, "tools/call", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListResources returns the resources the server offers the client
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	var result struct {
+		Resources []Resource `json:"resources"`
+	}
+	if err := c.Call(ctx, "resources/list", map[string]interface{}{}, &result); err != nil {
+		return nil, err
+	}
+	return result.Resources, nil
+}
+
+// ReadResource reads a resource
+func (c *Client) ReadResource(ctx context.Context, uri string) ([]ResourceContents, error) {
+	var result struct {
+		Contents []ResourceContents `json:"contents"`
+	}
+	if err := c.Call(ctx, "resources/read", map[string]interface{}{"uri": uri}, &result); err != nil {
+		return nil, err
+	}
+	return result.Contents, nil
+}
+
+// Subscribe asks the server to report updates of a resource, which are
+// passed to the OnResourceUpdated callbacks. Subscriptions are restored
+// after reconnecting.
+func (c *Client) Subscribe(ctx context.Context, uri string) error {
+	if err := c.Call(ctx, "resources/subscribe", map[string]interface{}{"uri": uri}, nil); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.subscriptions[uri] = true
+	c.mu.Unlock()
+	return nil
+}
+
+// Unsubscribe stops updates of a resource
+func (c *Client) Unsubscribe(ctx context.Context, uri string) error {
+	c.mu.Lock()
+	delete(c.subscriptions, uri)
+	c.mu.Unlock()
+	return c.Call(ctx, "resources/unsubscribe", map[string]interface{}{"uri": uri}, nil)
+}
+
+// SetLogLevel asks the server to send log messages at level and above,
+// which are passed to the OnLog callbacks. The level is restored after
+// reconnecting.
+func (c *Client) SetLogLevel(ctx context.Context, level string) error {
+	if err := c.Call(ctx, "logging/setLevel", map[string]interface{}{"level": level}, nil); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.logLevel = level
+	c.mu.Unlock()
+	return nil
+}
+
+// Discover returns the server's OpenRPC description of its methods and
+// tools
+func (c *Client) Discover(ctx context.Context) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	if err := c.Call(ctx, "rpc.discover", nil, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
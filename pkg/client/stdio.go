@@ -0,0 +1,136 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// stdioStopTimeout is how long a server gets to exit after its stdin is
+// closed before it is killed
+const stdioStopTimeout = 5 * time.Second
+
+// StdioTransport runs a server as a subprocess and exchanges newline
+// delimited messages over its stdin and stdout. Reconnecting starts a new
+// process.
+type StdioTransport struct {
+	Command string
+	Args    []string
+	// Env and Dir are passed to exec.Cmd; nil inherits this process's
+	// environment and working directory
+	Env []string
+	Dir string
+	// Stderr receives the server's standard error; nil discards it
+	Stderr io.Writer
+
+	mu   sync.Mutex
+	proc *stdioProcess
+}
+
+// stdioProcess is a running server
+type stdioProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	exited chan struct{}
+}
+
+// NewStdioTransport returns a transport running command with args
+func NewStdioTransport(command string, args ...string) *StdioTransport {
+	return &StdioTransport{Command: command, Args: args}
+}
+
+// Connect starts the server process, stopping any earlier one
+func (t *StdioTransport) Connect(ctx context.Context, receive func([]byte), lost func(error)) error {
+	t.stop()
+
+	cmd := exec.Command(t.Command, t.Args...)
+	cmd.Env = t.Env
+	cmd.Dir = t.Dir
+	cmd.Stderr = t.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s: %w", t.Command, err)
+	}
+
+	proc := &stdioProcess{cmd: cmd, stdin: stdin, exited: make(chan struct{})}
+	t.mu.Lock()
+	t.proc = proc
+	t.mu.Unlock()
+
+	go func() {
+		reader := bufio.NewReader(stdout)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				receive(line)
+			}
+			if err != nil {
+				break
+			}
+		}
+		err := cmd.Wait()
+		close(proc.exited)
+
+		// Only report the loss of the current process
+		t.mu.Lock()
+		current := t.proc == proc
+		if current {
+			t.proc = nil
+		}
+		t.mu.Unlock()
+		if current {
+			lost(fmt.Errorf("%w: server exited: %v", ErrDisconnected, err))
+		}
+	}()
+	return nil
+}
+
+// Send writes a message to the server's stdin
+func (t *StdioTransport) Send(ctx context.Context, message []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.proc == nil {
+		return fmt.Errorf("%w: server not running", ErrDisconnected)
+	}
+	if _, err := t.proc.stdin.Write(append(message, '\n')); err != nil {
+		return fmt.Errorf("%w: %v", ErrDisconnected, err)
+	}
+	return nil
+}
+
+// Close stops the server process
+func (t *StdioTransport) Close() error {
+	t.stop()
+	return nil
+}
+
+// stop closes the server's stdin so that it exits, and kills it if it has
+// not exited in time
+func (t *StdioTransport) stop() {
+	t.mu.Lock()
+	proc := t.proc
+	t.proc = nil
+	t.mu.Unlock()
+	if proc == nil {
+		return
+	}
+
+	proc.stdin.Close()
+	select {
+	case <-proc.exited:
+	case <-time.After(stdioStopTimeout):
+		proc.cmd.Process.Kill()
+		<-proc.exited
+	}
+}
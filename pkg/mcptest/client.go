@@ -0,0 +1,144 @@
+package mcptest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/pkg/client"
+)
+
+// DefaultTimeout bounds each request and wait of a Client unless its
+// Timeout is changed
+const DefaultTimeout = 5 * time.Second
+
+// Client is a client connected in memory to a server under test. Its
+// methods fail the test instead of returning errors, and it records the
+// notifications the server sends. A notification sent while a request is
+// handled is recorded by the time the request returns, so tests can assert
+// on it without waiting.
+type Client struct {
+	*client.Client
+	Transport *Transport
+	// Timeout bounds each request made by the Must methods and each wait
+	// for a notification
+	Timeout time.Duration
+
+	t testing.TB
+}
+
+// NewClient connects a client to srv and performs the initialize
+// handshake, failing t if it cannot. The client is closed, ending its
+// session, when the test finishes.
+func NewClient(t testing.TB, srv Server) *Client {
+	t.Helper()
+	transport := NewTransport(srv)
+	c := &Client{Client: client.New(transport), Transport: transport, Timeout: DefaultTimeout, t: t}
+	c.SetClientInfo("mcptest", "1.0.0")
+	c.SetReconnect(nil)
+
+	ctx, cancel := c.context()
+	defer cancel()
+	if _, err := c.Connect(ctx); err != nil {
+		c.Close()
+		t.Fatalf("mcptest: connecting: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// context returns a context bounded by the client's Timeout
+func (c *Client) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.Timeout)
+}
+
+// MustCall sends a request and unmarshals its result into result, which
+// may be nil, failing the test on error
+func (c *Client) MustCall(method string, params, result interface{}) {
+	c.t.Helper()
+	ctx, cancel := c.context()
+	defer cancel()
+	if err := c.Call(ctx, method, params, result); err != nil {
+		c.t.Fatalf("mcptest: %s: %v", method, err)
+	}
+}
+
+// MustCallTool calls a tool, failing the test if the call fails or the tool
+// reports an error
+func (c *Client) MustCallTool(name string, args map[string]interface{}) *client.ToolResult {
+	c.t.Helper()
+	ctx, cancel := c.context()
+	defer cancel()
+	result, err := c.CallTool(ctx, name, args)
+	if err != nil {
+		c.t.Fatalf("mcptest: calling %s: %v", name, err)
+	}
+	if result.IsError {
+		c.t.Fatalf("mcptest: %s failed: %s", name, result.Text())
+	}
+	return result
+}
+
+// Notifications returns the notifications received with the given method,
+// or all of them if method is empty, oldest first
+func (c *Client) Notifications(method string) []client.Notification {
+	return c.Transport.Recorder().Notifications(method)
+}
+
+// ResetNotifications forgets the notifications received so far, so that
+// later assertions only see new ones
+func (c *Client) ResetNotifications() {
+	c.Transport.Recorder().Reset()
+}
+
+// AssertNotified fails the test unless a notification with the given
+// method has been received, and returns the first one
+func (c *Client) AssertNotified(method string) client.Notification {
+	c.t.Helper()
+	found := c.Notifications(method)
+	if len(found) == 0 {
+		c.t.Fatalf("mcptest: no %s notification received", method)
+		return client.Notification{}
+	}
+	return found[0]
+}
+
+// AssertNotNotified fails the test if a notification with the given method
+// has been received
+func (c *Client) AssertNotNotified(method string) {
+	c.t.Helper()
+	if found := c.Notifications(method); len(found) > 0 {
+		c.t.Errorf("mcptest: unexpected %s notification: %s", method, found[0].Params)
+	}
+}
+
+// WaitNotification returns the first notification with the given method,
+// waiting up to Timeout for one to arrive, for notifications the server
+// sends outside of requests
+func (c *Client) WaitNotification(method string) client.Notification {
+	c.t.Helper()
+	return c.WaitNotificationMatching(method, nil)
+}
+
+// WaitNotificationMatching returns the first notification with the given
+// method for which match returns true, waiting up to Timeout for one
+func (c *Client) WaitNotificationMatching(method string, match func(client.Notification) bool) client.Notification {
+	c.t.Helper()
+	ctx, cancel := c.context()
+	defer cancel()
+	n, err := c.Transport.Recorder().Wait(ctx, method, match)
+	if err != nil {
+		c.t.Fatalf("mcptest: %v", err)
+	}
+	return n
+}
+
+// DecodeParams unmarshals the params of a notification into v, failing the
+// test if they do not fit
+func (c *Client) DecodeParams(n client.Notification, v interface{}) {
+	c.t.Helper()
+	if err := json.Unmarshal(n.Params, v); err != nil {
+		c.t.Fatalf("mcptest: decoding %s params: %v", n.Method, err)
+	}
+}
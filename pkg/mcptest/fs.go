@@ -0,0 +1,158 @@
+package mcptest
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"testing/fstest"
+	"time"
+)
+
+// Epoch is the time NewClock starts at by default and the modification
+// time of files an FS is created with
+var Epoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// FS is an in-memory filesystem that a test can change while a server
+// searches it, given to the server with pkg/server's WithRootFS. As in
+// testing/fstest.MapFS, directories are implied by the files in them. It is
+// safe for concurrent use.
+type FS struct {
+	mu     sync.RWMutex
+	files  fstest.MapFS
+	errors map[string]error
+	clock  *Clock
+}
+
+// NewFS returns a filesystem holding files, which maps slash-separated
+// paths such as "docs/readme.md" to their contents. The files are modified
+// at Epoch.
+func NewFS(files map[string]string) *FS {
+	f := &FS{files: fstest.MapFS{}, errors: map[string]error{}}
+	for name, content := range files {
+		f.files[name] = &fstest.MapFile{Data: []byte(content), Mode: 0o644, ModTime: Epoch}
+	}
+	return f
+}
+
+// SetClock makes later writes take their modification time from clock
+// rather than Epoch
+func (f *FS) SetClock(clock *Clock) {
+	f.mu.Lock()
+	f.clock = clock
+	f.mu.Unlock()
+}
+
+// now returns the modification time of a write
+func (f *FS) now() time.Time {
+	if f.clock != nil {
+		return f.clock.Now()
+	}
+	return Epoch
+}
+
+// Open opens the named file, failing with the error set by SetError
+func (f *FS) Open(name string) (fs.File, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if err, ok := f.errors[name]; ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	// Files are replaced rather than changed, so an open file keeps the
+	// contents it was opened with
+	return f.files.Open(name)
+}
+
+// WriteFile creates or replaces the named file
+func (f *FS) WriteFile(name, content string) {
+	f.mu.Lock()
+	f.files[name] = &fstest.MapFile{Data: []byte(content), Mode: 0o644, ModTime: f.now()}
+	f.mu.Unlock()
+}
+
+// Mkdir creates an empty directory, which would otherwise not exist
+func (f *FS) Mkdir(name string) {
+	f.mu.Lock()
+	f.files[name] = &fstest.MapFile{Mode: fs.ModeDir | 0o755, ModTime: f.now()}
+	f.mu.Unlock()
+}
+
+// Remove removes the named file, or directory and everything in it. It
+// reports whether anything was removed.
+func (f *FS) Remove(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	removed := false
+	for file := range f.files {
+		if file == name || strings.HasPrefix(file, name+"/") {
+			delete(f.files, file)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// SetModTime changes the modification time of the named file, reporting
+// whether it exists
+func (f *FS) SetModTime(name string, t time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, ok := f.files[name]
+	if !ok {
+		return false
+	}
+	changed := *file
+	changed.ModTime = t
+	f.files[name] = &changed
+	return true
+}
+
+// SetError makes opening the named file or directory fail with err, such
+// as fs.ErrPermission; a nil err makes it succeed again
+func (f *FS) SetError(name string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	name = path.Clean(name)
+	if err == nil {
+		delete(f.errors, name)
+	} else {
+		f.errors[name] = err
+	}
+}
+
+// Clock is a clock that stands still until a test moves it, given to the
+// server with pkg/server's WithClock. It is safe for concurrent use.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a clock reading start, or Epoch if start is zero
+func NewClock(start time.Time) *Clock {
+	if start.IsZero() {
+		start = Epoch
+	}
+	return &Clock{now: start}
+}
+
+// Now returns the clock's time
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d and returns the new time
+func (c *Clock) Advance(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+// Set sets the clock's time
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
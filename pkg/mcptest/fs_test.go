@@ -0,0 +1,101 @@
+package mcptest_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/pkg/mcptest"
+)
+
+func TestFSSetError(t *testing.T) {
+	files := mcptest.NewFS(map[string]string{"docs/a.md": "# A", "docs/b.md": "# B"})
+
+	files.SetError("docs/./a.md", fs.ErrPermission)
+	if _, err := files.Open("docs/a.md"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Open of failing file: error = %v, want %v", err, fs.ErrPermission)
+	}
+	if _, err := fs.ReadFile(files, "docs/b.md"); err != nil {
+		t.Errorf("Open of other file: %v", err)
+	}
+
+	files.SetError("docs/a.md", nil)
+	if data, err := fs.ReadFile(files, "docs/a.md"); err != nil || string(data) != "# A" {
+		t.Errorf("after clearing the error: %q, %v", data, err)
+	}
+}
+
+func TestFSSetErrorFailsReads(t *testing.T) {
+	files := mcptest.NewFS(map[string]string{"a.md": "# A"})
+	c := mcptest.NewClient(t, newServer(t, files))
+
+	files.SetError("a.md", fs.ErrPermission)
+	ctx, cancel := context.WithTimeout(context.Background(), mcptest.DefaultTimeout)
+	defer cancel()
+	result, err := c.CallTool(ctx, "read_file", map[string]interface{}{"root": "docs", "path": "a.md"})
+	if err == nil && !result.IsError {
+		t.Fatalf("read_file of a failing file succeeded: %s", result.Text())
+	}
+
+	files.SetError("a.md", nil)
+	if text := c.MustCallTool("read_file", map[string]interface{}{"root": "docs", "path": "a.md"}).Text(); !strings.Contains(text, "# A") {
+		t.Errorf("read_file after clearing the error = %q", text)
+	}
+}
+
+func TestFSChanges(t *testing.T) {
+	clock := mcptest.NewClock(time.Time{})
+	files := mcptest.NewFS(map[string]string{"docs/a.md": "# A"})
+	files.SetClock(clock)
+
+	later := clock.Advance(time.Hour)
+	files.WriteFile("docs/b.md", "# B")
+	info, err := fs.Stat(files, "docs/b.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(later) {
+		t.Errorf("written file modified at %v, want %v", info.ModTime(), later)
+	}
+	if info, _ := fs.Stat(files, "docs/a.md"); !info.ModTime().Equal(mcptest.Epoch) {
+		t.Errorf("initial file modified at %v, want %v", info.ModTime(), mcptest.Epoch)
+	}
+
+	if !files.Remove("docs") {
+		t.Error("Remove of a directory reported nothing removed")
+	}
+	if _, err := files.Open("docs/a.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open after Remove: error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestClockAdvance(t *testing.T) {
+	if now := mcptest.NewClock(time.Time{}).Now(); !now.Equal(mcptest.Epoch) {
+		t.Errorf("zero start reads %v, want %v", now, mcptest.Epoch)
+	}
+
+	start := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	clock := mcptest.NewClock(start)
+	if now := clock.Now(); !now.Equal(start) {
+		t.Errorf("Now = %v, want %v", now, start)
+	}
+	if now := clock.Now(); !now.Equal(start) {
+		t.Errorf("clock moved on its own to %v", now)
+	}
+
+	if got, want := clock.Advance(90*time.Second), start.Add(90*time.Second); !got.Equal(want) || !clock.Now().Equal(want) {
+		t.Errorf("Advance returned %v and reads %v, want %v", got, clock.Now(), want)
+	}
+	clock.Advance(time.Hour)
+	if got, want := clock.Now(), start.Add(time.Hour+90*time.Second); !got.Equal(want) {
+		t.Errorf("after two advances Now = %v, want %v", got, want)
+	}
+
+	clock.Set(start)
+	if now := clock.Now(); !now.Equal(start) {
+		t.Errorf("after Set Now = %v, want %v", now, start)
+	}
+}
@@ -0,0 +1,364 @@
+// Package mcptest helps test MCP tools, servers and clients without
+// processes, pipes or ports. NewTransport connects a pkg/client Client to a
+// server in memory and NewClient wraps one for tests, recording the
+// notifications the server sends so that tests can assert on them. FS is an
+// in-memory filesystem to search in place of real directories, and Clock a
+// clock that only moves when told to.
+//
+//	clock := mcptest.NewClock(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+//	files := mcptest.NewFS(map[string]string{"docs/a.md": "# Hello"})
+//	srv, err := server.NewServer(
+//		server.WithRootFS("docs", files),
+//		server.WithIndex(filepath.Join(t.TempDir(), "index.json")),
+//		server.WithClock(clock),
+//	)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	c := mcptest.NewClient(t, srv)
+//	result := c.MustCallTool("find_files", map[string]interface{}{"pattern": "*.md"})
+package mcptest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/aawadall/go-mcp-filesearch/pkg/client"
+)
+
+// Server is a server that can serve a client session over a pair of
+// streams, such as a pkg/server Server
+type Server interface {
+	Serve(ctx context.Context, in io.Reader, out io.Writer) error
+}
+
+// Conn is the server end of an in-memory connection. The server reads the
+// client's messages from it and writes its own to it, one per line, as it
+// would over stdin and stdout.
+type Conn struct {
+	mu     sync.Mutex
+	ready  *sync.Cond
+	input  []byte
+	closed bool
+	// output holds the start of a line the server has not finished writing
+	output []byte
+	// queued holds the lines written before the client connected
+	queued  [][]byte
+	receive func([]byte)
+	lost    func(error)
+
+	// writing keeps the server's messages in order while they are passed
+	// to the client
+	writing sync.Mutex
+}
+
+// newConn returns an open connection without a client
+func newConn() *Conn {
+	c := &Conn{}
+	c.ready = sync.NewCond(&c.mu)
+	return c
+}
+
+// Read reads the client's messages. It returns io.EOF once the connection
+// is closed and every message has been read.
+func (c *Conn) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.input) == 0 && !c.closed {
+		c.ready.Wait()
+	}
+	if len(c.input) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, c.input)
+	c.input = c.input[n:]
+	return n, nil
+}
+
+// Write passes each complete line to the client before returning
+func (c *Conn) Write(p []byte) (int, error) {
+	c.writing.Lock()
+	defer c.writing.Unlock()
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, io.ErrClosedPipe
+	}
+	c.output = append(c.output, p...)
+	var lines [][]byte
+	for {
+		i := bytes.IndexByte(c.output, '\n')
+		if i < 0 {
+			break
+		}
+		if line := bytes.TrimSpace(c.output[:i]); len(line) > 0 {
+			lines = append(lines, append([]byte{}, line...))
+		}
+		c.output = c.output[i+1:]
+	}
+	receive := c.receive
+	if receive == nil {
+		c.queued = append(c.queued, lines...)
+		lines = nil
+	}
+	c.mu.Unlock()
+
+	for _, line := range lines {
+		receive(line)
+	}
+	return len(p), nil
+}
+
+// Close disconnects the client, as if the server had gone away. The server
+// reads io.EOF once it has read the messages already sent.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	lost := c.lost
+	wasClosed := c.closed
+	c.mu.Unlock()
+
+	c.shutdown()
+	if lost != nil && !wasClosed {
+		lost(fmt.Errorf("%w: connection closed by the server", client.ErrDisconnected))
+	}
+	return nil
+}
+
+// shutdown closes the connection without telling the client
+func (c *Conn) shutdown() {
+	c.mu.Lock()
+	c.closed = true
+	c.ready.Broadcast()
+	c.mu.Unlock()
+}
+
+// connect attaches the client, passing it the lines written so far
+func (c *Conn) connect(receive func([]byte), lost func(error)) {
+	c.writing.Lock()
+	defer c.writing.Unlock()
+
+	c.mu.Lock()
+	c.receive, c.lost = receive, lost
+	queued := c.queued
+	c.queued = nil
+	c.mu.Unlock()
+
+	for _, line := range queued {
+		receive(line)
+	}
+}
+
+// send queues a message for the server to read
+func (c *Conn) send(message []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return fmt.Errorf("%w: connection closed", client.ErrDisconnected)
+	}
+	c.input = append(append(c.input, bytes.TrimSpace(message)...), '\n')
+	c.ready.Broadcast()
+	return nil
+}
+
+// Transport is a client transport connected to a server in memory. It
+// records the notifications the server sends before passing them on, so
+// that a notification sent while a request is handled is recorded by the
+// time the request returns.
+type Transport struct {
+	serve    Server
+	recorder *Recorder
+
+	mu sync.Mutex
+	// pipe is the server end of a transport made by NewPipe, until the
+	// transport connects
+	pipe   *Conn
+	conn   *Conn
+	cancel context.CancelFunc
+	served chan struct{}
+}
+
+// NewTransport returns a transport serving each connection with a new
+// session of srv
+func NewTransport(srv Server) *Transport {
+	return &Transport{serve: srv, recorder: NewRecorder()}
+}
+
+// NewPipe returns a transport and the server end it connects to, for
+// servers that are not a Server. The transport connects once; it cannot
+// reconnect after either end is closed.
+//
+//	transport, conn := mcptest.NewPipe()
+//	go srv.Serve(ctx, conn, conn)
+//	c := client.New(transport)
+func NewPipe() (*Transport, *Conn) {
+	conn := newConn()
+	return &Transport{pipe: conn, recorder: NewRecorder()}, conn
+}
+
+// Recorder returns the notifications received over the transport
+func (t *Transport) Recorder() *Recorder {
+	return t.recorder
+}
+
+// Connect connects to the server end of a pipe, or starts a new session of
+// the server, ending any earlier one
+func (t *Transport) Connect(ctx context.Context, receive func([]byte), lost func(error)) error {
+	record := func(message []byte) {
+		t.recorder.record(message)
+		receive(message)
+	}
+
+	if t.serve == nil {
+		t.mu.Lock()
+		conn := t.pipe
+		t.pipe, t.conn = nil, conn
+		t.mu.Unlock()
+		if conn == nil {
+			return fmt.Errorf("%w: a pipe cannot reconnect", client.ErrDisconnected)
+		}
+		conn.connect(record, lost)
+		return nil
+	}
+
+	t.stop()
+	conn := newConn()
+	conn.connect(record, lost)
+	serveCtx, cancel := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		t.serve.Serve(serveCtx, conn, conn)
+		conn.Close()
+	}()
+
+	t.mu.Lock()
+	t.conn, t.cancel, t.served = conn, cancel, served
+	t.mu.Unlock()
+	return nil
+}
+
+// Send passes a message to the server
+func (t *Transport) Send(ctx context.Context, message []byte) error {
+	t.mu.Lock()
+	conn := t.conn
+	t.mu.Unlock()
+	if conn == nil {
+		return fmt.Errorf("%w: not connected", client.ErrDisconnected)
+	}
+	return conn.send(message)
+}
+
+// Close ends the connection and waits for the server's session to end
+func (t *Transport) Close() error {
+	t.stop()
+	return nil
+}
+
+// stop ends the current connection, if any
+func (t *Transport) stop() {
+	t.mu.Lock()
+	conn, cancel, served := t.conn, t.cancel, t.served
+	t.conn, t.cancel, t.served = nil, nil, nil
+	if t.pipe != nil {
+		conn, t.pipe = t.pipe, nil
+	}
+	t.mu.Unlock()
+
+	if conn != nil {
+		conn.shutdown()
+	}
+	if cancel != nil {
+		cancel()
+		<-served
+	}
+}
+
+// Recorder collects the notifications a server sends. It is safe for
+// concurrent use.
+type Recorder struct {
+	mu            sync.Mutex
+	notifications []client.Notification
+	// changed is closed and replaced when a notification is recorded
+	changed chan struct{}
+}
+
+// NewRecorder returns an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{changed: make(chan struct{})}
+}
+
+// record keeps the notifications in a message, or batch of messages
+func (r *Recorder) record(message []byte) {
+	if len(message) > 0 && message[0] == '[' {
+		var batch []json.RawMessage
+		if json.Unmarshal(message, &batch) == nil {
+			for _, item := range batch {
+				r.record(item)
+			}
+		}
+		return
+	}
+
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	if json.Unmarshal(message, &msg) != nil || msg.Method == "" || len(msg.ID) > 0 {
+		return
+	}
+	r.mu.Lock()
+	r.notifications = append(r.notifications, client.Notification{Method: msg.Method, Params: msg.Params})
+	close(r.changed)
+	r.changed = make(chan struct{})
+	r.mu.Unlock()
+}
+
+// Notifications returns the recorded notifications with the given method,
+// or all of them if method is empty, oldest first
+func (r *Recorder) Notifications(method string) []client.Notification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []client.Notification
+	for _, n := range r.notifications {
+		if method == "" || n.Method == method {
+			found = append(found, n)
+		}
+	}
+	return found
+}
+
+// Reset forgets the recorded notifications
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.notifications = nil
+	r.mu.Unlock()
+}
+
+// Wait returns the first recorded notification with the given method for
+// which match, if not nil, returns true, waiting for one until ctx is done
+func (r *Recorder) Wait(ctx context.Context, method string, match func(client.Notification) bool) (client.Notification, error) {
+	seen := 0
+	for {
+		r.mu.Lock()
+		pending, changed := r.notifications[min(seen, len(r.notifications)):], r.changed
+		seen = len(r.notifications)
+		r.mu.Unlock()
+
+		for _, n := range pending {
+			if n.Method == method && (match == nil || match(n)) {
+				return n, nil
+			}
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return client.Notification{}, fmt.Errorf("no %s notification: %w", method, ctx.Err())
+		}
+	}
+}
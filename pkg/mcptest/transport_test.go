@@ -0,0 +1,255 @@
+package mcptest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/pkg/client"
+	"github.com/aawadall/go-mcp-filesearch/pkg/mcptest"
+	"github.com/aawadall/go-mcp-filesearch/pkg/server"
+)
+
+const updated = "notifications/resources/updated"
+
+// newServer returns a server searching files as the root "docs"
+func newServer(t *testing.T, files *mcptest.FS, opts ...server.Option) *server.Server {
+	t.Helper()
+	opts = append([]server.Option{
+		server.WithRootFS("docs", files),
+		server.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	}, opts...)
+	srv, err := server.NewServer(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+// updatedURI returns the URI of a notifications/resources/updated message
+func updatedURI(n client.Notification) string {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	json.Unmarshal(n.Params, &params)
+	return params.URI
+}
+
+func TestTransportConnectsClientToServer(t *testing.T) {
+	srv := newServer(t, mcptest.NewFS(map[string]string{"a.md": "# A"}))
+	c := client.New(mcptest.NewTransport(srv))
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), mcptest.DefaultTimeout)
+	defer cancel()
+	info, err := c.Connect(ctx)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if info.ServerInfo.Name == "" {
+		t.Error("initialize result has no server name")
+	}
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if len(tools) == 0 {
+		t.Error("no tools listed")
+	}
+}
+
+func TestTransportStartsNewSessionOnReconnect(t *testing.T) {
+	srv := newServer(t, mcptest.NewFS(nil))
+	transport := mcptest.NewTransport(srv)
+	c := client.New(transport)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), mcptest.DefaultTimeout)
+	defer cancel()
+	if _, err := c.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Subscribe(ctx, "filesearch://docs/"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The client restores its subscription in the new session
+	if _, err := c.Connect(ctx); err != nil {
+		t.Fatalf("reconnecting: %v", err)
+	}
+	srv.NotifyResourceUpdated("filesearch://docs/")
+	if _, err := transport.Recorder().Wait(ctx, updated, nil); err != nil {
+		t.Errorf("subscription lost on reconnect: %v", err)
+	}
+}
+
+func TestPipeConnectsToServerEnd(t *testing.T) {
+	srv := newServer(t, mcptest.NewFS(nil))
+	transport, conn := mcptest.NewPipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ctx, conn, conn) }()
+
+	c := client.New(transport)
+	c.SetReconnect(nil)
+	if _, err := c.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if _, err := c.ListTools(ctx); err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+
+	// A pipe connects once
+	if _, err := c.Connect(ctx); !errors.Is(err, client.ErrDisconnected) {
+		t.Errorf("reconnecting a pipe: error = %v, want %v", err, client.ErrDisconnected)
+	}
+
+	c.Close()
+	conn.Close()
+	select {
+	case <-served:
+	case <-time.After(mcptest.DefaultTimeout):
+		t.Fatal("server did not stop after the pipe closed")
+	}
+}
+
+func TestClientRecordsNotificationsOfRequests(t *testing.T) {
+	files := mcptest.NewFS(map[string]string{"a.md": "# A"})
+	c := mcptest.NewClient(t, newServer(t, files, server.WithIndex(filepath.Join(t.TempDir(), "index.json"))))
+	c.MustCall("resources/subscribe", map[string]interface{}{"uri": "filesearch://docs/"}, nil)
+	c.AssertNotNotified(updated)
+
+	c.MustCallTool("rebuild_index", nil)
+	if uri := updatedURI(c.AssertNotified(updated)); uri != "filesearch://docs/" {
+		t.Errorf("updated %s, want filesearch://docs/", uri)
+	}
+
+	c.ResetNotifications()
+	c.AssertNotNotified(updated)
+}
+
+func TestRecorderWait(t *testing.T) {
+	srv := newServer(t, mcptest.NewFS(nil))
+	c := mcptest.NewClient(t, srv)
+	c.MustCall("resources/subscribe", map[string]interface{}{"uri": "filesearch://docs/a.md"}, nil)
+	c.MustCall("resources/subscribe", map[string]interface{}{"uri": "filesearch://docs/b.md"}, nil)
+	recorder := c.Transport.Recorder()
+
+	t.Run("already recorded", func(t *testing.T) {
+		srv.NotifyResourceUpdated("filesearch://docs/a.md")
+		ctx, cancel := context.WithTimeout(context.Background(), mcptest.DefaultTimeout)
+		defer cancel()
+		n, err := recorder.Wait(ctx, updated, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uri := updatedURI(n); uri != "filesearch://docs/a.md" {
+			t.Errorf("updated %s, want filesearch://docs/a.md", uri)
+		}
+	})
+
+	t.Run("arrives later", func(t *testing.T) {
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			srv.NotifyResourceUpdated("filesearch://docs/b.md")
+		}()
+		isB := func(n client.Notification) bool { return updatedURI(n) == "filesearch://docs/b.md" }
+		ctx, cancel := context.WithTimeout(context.Background(), mcptest.DefaultTimeout)
+		defer cancel()
+		if _, err := recorder.Wait(ctx, updated, isB); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("never arrives", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		never := func(client.Notification) bool { return false }
+		if _, err := recorder.Wait(ctx, updated, never); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Wait error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+// methodLog is a client transport that answers every request with an empty
+// result and logs the methods of the messages sent
+type methodLog struct {
+	mu      sync.Mutex
+	methods []string
+	receive func([]byte)
+}
+
+func (l *methodLog) Connect(ctx context.Context, receive func([]byte), lost func(error)) error {
+	l.mu.Lock()
+	l.receive = receive
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *methodLog) Send(ctx context.Context, message []byte) error {
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.methods = append(l.methods, msg.Method)
+	receive := l.receive
+	l.mu.Unlock()
+	if msg.ID != nil {
+		go receive([]byte(`{"jsonrpc":"2.0","id":` + string(msg.ID) + `,"result":{}}`))
+	}
+	return nil
+}
+
+func (l *methodLog) Close() error { return nil }
+
+// take returns the methods logged so far and clears the log
+func (l *methodLog) take() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	methods := l.methods
+	l.methods = nil
+	return methods
+}
+
+func TestClientSendsInitializedBeforeOtherRequests(t *testing.T) {
+	ctx := context.Background()
+	transport := &methodLog{}
+	c := client.New(transport)
+	defer c.Close()
+	c.SetReconnect(nil)
+
+	if _, err := c.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if err := c.SetLogLevel(ctx, "debug"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Subscribe(ctx, "filesearch://docs/"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"initialize", "notifications/initialized", "logging/setLevel", "resources/subscribe"}
+	if got := transport.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("first connection sent %v, want %v", got, want)
+	}
+
+	// Reconnecting restores the log level and subscriptions only after
+	// the handshake is complete
+	if _, err := c.Connect(ctx); err != nil {
+		t.Fatalf("reconnect: %v", err)
+	}
+	if got := transport.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("reconnection sent %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+	core "github.com/aawadall/go-mcp-filesearch/internal/server"
+)
+
+// Request is a JSON-RPC request seen by method middleware. Params holds
+// the decoded JSON parameters, usually a map[string]interface{}.
+type Request struct {
+	ID     interface{}
+	Method string
+	Params interface{}
+}
+
+// MethodHandler runs a request and returns its result
+type MethodHandler func(ctx context.Context, req *Request) (interface{}, error)
+
+// MethodMiddleware wraps the handling of every request. It runs inside the
+// server's panic recovery and the request's time limit, so a panic in it is
+// reported to the client as an internal error.
+type MethodMiddleware func(next MethodHandler) MethodHandler
+
+// ToolCallHandler runs a tool call and returns the tools/call result
+type ToolCallHandler func(ctx context.Context, name string, args map[string]interface{}) (interface{}, error)
+
+// ToolMiddleware wraps every tool call, built-in or added, once the caller
+// has been authorized to make it
+type ToolMiddleware func(next ToolCallHandler) ToolCallHandler
+
+// WithMethodMiddleware wraps the handling of every request in mw, the first
+// outermost
+func WithMethodMiddleware(mw ...MethodMiddleware) Option {
+	return func(o *options) {
+		o.methodMiddleware = append(o.methodMiddleware, mw...)
+	}
+}
+
+// WithToolMiddleware wraps every tool call in mw, the first outermost
+func WithToolMiddleware(mw ...ToolMiddleware) Option {
+	return func(o *options) {
+		o.toolMiddleware = append(o.toolMiddleware, mw...)
+	}
+}
+
+// WithDefaultTimeout limits how long requests for methods without their
+// own limit may run
+func WithDefaultTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeouts.Default = d
+	}
+}
+
+// WithMethodTimeout limits how long requests for the methods matching the
+// glob pattern may run. The first matching limit applies.
+func WithMethodTimeout(pattern string, d time.Duration) Option {
+	return func(o *options) {
+		o.timeouts.Methods = append(o.timeouts.Methods, core.Timeout{Pattern: pattern, Limit: d})
+	}
+}
+
+// WithToolTimeout limits how long calls to the tools matching the glob
+// pattern may run. The first matching limit applies.
+func WithToolTimeout(pattern string, d time.Duration) Option {
+	return func(o *options) {
+		o.timeouts.Tools = append(o.timeouts.Tools, core.Timeout{Pattern: pattern, Limit: d})
+	}
+}
+
+// methodMiddleware adapts method middleware to the protocol server
+func methodMiddleware(mw MethodMiddleware) core.MethodMiddleware {
+	return func(next core.MethodHandler) core.MethodHandler {
+		handler := mw(func(ctx context.Context, req *Request) (interface{}, error) {
+			return next(ctx, models.JSONRPCRequest{
+				JSONRPC: models.JSONRPCVersion,
+				ID:      req.ID,
+				Method:  req.Method,
+				Params:  req.Params,
+			})
+		})
+		return func(ctx context.Context, req models.JSONRPCRequest) (interface{}, error) {
+			return handler(ctx, &Request{ID: req.ID, Method: req.Method, Params: req.Params})
+		}
+	}
+}
+
+// toolMiddleware adapts tool middleware to the protocol server
+func toolMiddleware(mw ToolMiddleware) core.ToolMiddleware {
+	return func(next core.ToolCallHandler) core.ToolCallHandler {
+		return core.ToolCallHandler(mw(ToolCallHandler(next)))
+	}
+}
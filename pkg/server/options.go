@@ -0,0 +1,142 @@
+package server
+
+import (
+	"io/fs"
+	"log/slog"
+
+	core "github.com/aawadall/go-mcp-filesearch/internal/server"
+)
+
+// Option configures a Server
+type Option func(*options)
+
+// options collects the settings of NewServer
+type options struct {
+	roots        []root
+	search       *SearchOptions
+	indexPath    string
+	tools        []tool
+	resources    []resource
+	enabledTools []string
+	middleware   []Middleware
+	logger       *slog.Logger
+	ui           bool
+	maxBodyBytes int64
+	clock        Clock
+
+	methodMiddleware []MethodMiddleware
+	toolMiddleware   []ToolMiddleware
+	timeouts         core.Timeouts
+}
+
+// root is a search root added with WithRoot or WithRootFS
+type root struct {
+	name, path string
+	fsys       fs.FS
+}
+
+// tool is a tool added with WithTool
+type tool struct {
+	tool   Tool
+	handle ToolHandler
+}
+
+// resource is a resource added with WithResource
+type resource struct {
+	resource Resource
+	read     ResourceHandler
+}
+
+// WithRoot makes the directory at path searchable as the root name. The
+// file search tools and one resource per root are offered once a root is
+// added.
+func WithRoot(name, path string) Option {
+	return func(o *options) {
+		o.roots = append(o.roots, root{name: name, path: path})
+	}
+}
+
+// WithRootFS makes the files of fsys, such as an embed.FS or a
+// testing/fstest.MapFS, searchable as the root name
+func WithRootFS(name string, fsys fs.FS) Option {
+	return func(o *options) {
+		o.roots = append(o.roots, root{name: name, path: name, fsys: fsys})
+	}
+}
+
+// WithSearchOptions replaces the default search options
+func WithSearchOptions(search SearchOptions) Option {
+	return func(o *options) {
+		o.search = &search
+	}
+}
+
+// WithIndex keeps an index of the roots' files in the file at path, loading
+// it if it exists, and offers the rebuild_index tool
+func WithIndex(path string) Option {
+	return func(o *options) {
+		o.indexPath = path
+	}
+}
+
+// WithTool offers a tool whose calls are run by handle
+func WithTool(t Tool, handle ToolHandler) Option {
+	return func(o *options) {
+		o.tools = append(o.tools, tool{tool: t, handle: handle})
+	}
+}
+
+// WithResource offers a resource read by read
+func WithResource(r Resource, read ResourceHandler) Option {
+	return func(o *options) {
+		o.resources = append(o.resources, resource{resource: r, read: read})
+	}
+}
+
+// WithEnabledTools offers only the tools whose names match one of the glob
+// patterns, such as "find_*"
+func WithEnabledTools(patterns ...string) Option {
+	return func(o *options) {
+		o.enabledTools = append(o.enabledTools, patterns...)
+	}
+}
+
+// WithMiddleware wraps the HTTP handler in mw, the first outermost
+func WithMiddleware(mw ...Middleware) Option {
+	return func(o *options) {
+		o.middleware = append(o.middleware, mw...)
+	}
+}
+
+// WithLogger logs to logger instead of slog.Default. Records are also sent
+// to clients that enabled logging.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithUI serves the search UI to browsers at the root of the HTTP handler.
+// It is disabled by default.
+func WithUI(enabled bool) Option {
+	return func(o *options) {
+		o.ui = enabled
+	}
+}
+
+// WithMaxBodyBytes limits the size of HTTP request bodies; zero removes the
+// limit
+func WithMaxBodyBytes(n int64) Option {
+	return func(o *options) {
+		o.maxBodyBytes = n
+	}
+}
+
+// WithClock reads the time from clock instead of the system clock when
+// dating index builds, recording when calls started and pacing progress
+// notifications
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
@@ -0,0 +1,274 @@
+// Package server embeds the file search MCP server in other programs. A
+// Server is built with NewServer from options naming its search roots,
+// extra tools and resources, middleware, time limits and logger. It is
+// served over stdio with ServeStdio, over any stream pair with Serve, or
+// over HTTP with Handler, which can be mounted under a path prefix in an
+// existing mux.
+//
+//	srv, err := server.NewServer(
+//		server.WithRoot("docs", "./docs"),
+//		server.WithTool(server.Tool{Name: "hello"}, hello),
+//	)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	mux.Handle("/search/", srv.Handler("/search"))
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/filesearch"
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+	core "github.com/aawadall/go-mcp-filesearch/internal/server"
+)
+
+// Server is an embeddable MCP server. It is safe for concurrent use, and
+// one Server may serve stdio and HTTP clients at the same time.
+type Server struct {
+	mcp        *core.MCPServer
+	middleware []Middleware
+	ui         bool
+	body       int64
+
+	// httpOnce creates the HTTP transport the first time it is needed
+	httpOnce sync.Once
+	http     *core.HTTPMCPServer
+}
+
+// NewServer creates a server configured by opts. It fails if a root is not
+// an existing directory or the index cannot be loaded.
+func NewServer(opts ...Option) (*Server, error) {
+	o := options{maxBodyBytes: core.DefaultMaxBodyBytes}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	mcp := core.NewEmptyMCPServer()
+	logger := o.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	mcp.SetLogger(slog.New(mcp.LogHandler(logger.Handler())))
+
+	if len(o.roots) > 0 {
+		files, err := newFileSearch(o)
+		if err != nil {
+			return nil, err
+		}
+		mcp.SetFileSearch(files)
+	}
+	if len(o.enabledTools) > 0 {
+		mcp.SetEnabledTools(o.enabledTools)
+	}
+	mcp.SetTimeouts(o.timeouts)
+	if o.clock != nil {
+		mcp.SetClock(o.clock)
+	}
+	for _, mw := range o.methodMiddleware {
+		mcp.UseMethodMiddleware(methodMiddleware(mw))
+	}
+	for _, mw := range o.toolMiddleware {
+		mcp.UseToolMiddleware(toolMiddleware(mw))
+	}
+
+	s := &Server{mcp: mcp, middleware: o.middleware, ui: o.ui, body: o.maxBodyBytes}
+	for _, t := range o.tools {
+		s.AddTool(t.tool, t.handle)
+	}
+	for _, r := range o.resources {
+		s.AddResource(r.resource, r.read)
+	}
+	return s, nil
+}
+
+// newFileSearch builds the file search handler for the configured roots
+func newFileSearch(o options) (*filesearch.Handler, error) {
+	roots := make([]filesearch.Root, len(o.roots))
+	for i, root := range o.roots {
+		roots[i] = filesearch.Root{Name: root.name, Path: root.path, FS: root.fsys}
+	}
+	registry, err := filesearch.NewRegistry(roots)
+	if err != nil {
+		return nil, err
+	}
+
+	var index *filesearch.Index
+	if o.indexPath != "" {
+		index = filesearch.NewIndex(o.indexPath)
+		if err := index.Load(); err != nil {
+			return nil, fmt.Errorf("loading index: %w", err)
+		}
+	}
+
+	search := SearchOptions{Ignore: filesearch.DefaultIgnore}
+	if o.search != nil {
+		search = *o.search
+	}
+	engine := filesearch.NewEngine(registry, index, filesearch.Options{
+		Ignore:      search.Ignore,
+		MaxFileSize: search.MaxFileSize,
+		MaxResults:  search.MaxResults,
+	})
+	if o.clock != nil {
+		engine.SetClock(o.clock)
+	}
+	return filesearch.NewHandler(engine), nil
+}
+
+// AddTool offers a tool whose calls are run by handle, replacing any tool
+// of the same name, and tells connected clients their tool lists changed
+func (s *Server) AddTool(tool Tool, handle ToolHandler) {
+	s.mcp.AddTool(modelTool(tool), func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		result, err := handle(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			result = &ToolResult{}
+		}
+		return toolResult(result), nil
+	})
+}
+
+// RemoveTool withdraws a tool added with WithTool or AddTool, reporting
+// whether it existed
+func (s *Server) RemoveTool(name string) bool {
+	return s.mcp.RemoveTool(name)
+}
+
+// AddResource offers a resource read by read, replacing any resource with
+// the same URI, and tells connected clients their resource lists changed
+func (s *Server) AddResource(resource Resource, read ResourceHandler) {
+	s.mcp.AddResource(models.Resource{
+		URI:         resource.URI,
+		Name:        resource.Name,
+		Description: resource.Description,
+		MimeType:    resource.MimeType,
+	}, func(ctx context.Context, uri string) (interface{}, error) {
+		contents, err := read(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+		return resourceContents(contents), nil
+	})
+}
+
+// RemoveResource withdraws a resource added with WithResource or
+// AddResource, reporting whether it existed
+func (s *Server) RemoveResource(uri string) bool {
+	return s.mcp.RemoveResource(uri)
+}
+
+// NotifyResourceUpdated tells clients subscribed to the resource at uri
+// that it changed
+func (s *Server) NotifyResourceUpdated(uri string) {
+	s.mcp.Notify(models.NotificationResourceUpdated, models.ResourceUpdatedParams{URI: uri})
+}
+
+// AddRoot makes the directory at path searchable as the root name. It
+// fails if the server was created without roots.
+func (s *Server) AddRoot(name, path string) error {
+	_, err := s.mcp.AddRoot(filesearch.Root{Name: name, Path: path})
+	return err
+}
+
+// AddRootFS makes the files of fsys searchable as the root name. It fails
+// if the server was created without roots.
+func (s *Server) AddRootFS(name string, fsys fs.FS) error {
+	_, err := s.mcp.AddRoot(filesearch.Root{Name: name, Path: name, FS: fsys})
+	return err
+}
+
+// RemoveRoot stops searching the named root
+func (s *Server) RemoveRoot(name string) error {
+	return s.mcp.RemoveRoot(name)
+}
+
+// Serve reads requests from in and writes responses and notifications to
+// out, one JSON-RPC message per line, as a single client session. It
+// returns when in is exhausted; ctx is the parent of every request's
+// context.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	return s.mcp.Serve(ctx, in, out)
+}
+
+// ServeStdio serves a client over the process's stdin and stdout until
+// stdin is closed. Nothing else may write to stdout meanwhile.
+func (s *Server) ServeStdio(ctx context.Context) error {
+	return s.Serve(ctx, os.Stdin, os.Stdout)
+}
+
+// transport returns the HTTP transport, creating it on first use
+func (s *Server) transport() *core.HTTPMCPServer {
+	s.httpOnce.Do(func() {
+		s.http = core.NewHTTPMCPServer(s.mcp)
+		s.http.SetUI(s.ui)
+		s.http.SetMaxBodyBytes(s.body)
+	})
+	return s.http
+}
+
+// Handler returns the HTTP transport serving the MCP endpoint, REST API,
+// health checks and, if enabled, the UI under prefix, wrapped in the
+// configured middleware. Mount it in a mux at prefix + "/":
+//
+//	mux.Handle("/search/", srv.Handler("/search"))
+//
+// An empty prefix serves the endpoints at the root.
+func (s *Server) Handler(prefix string) http.Handler {
+	handler := s.transport().Mount(prefix)
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		handler = s.middleware[i](handler)
+	}
+	return handler
+}
+
+// ListenAndServe serves the HTTP transport at the root of addr until ctx is
+// cancelled, then shuts down gracefully, giving in-flight requests 30
+// seconds to finish. If addr is a loopback address, requests must be
+// addressed to localhost, 127.0.0.1 or [::1].
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	if host, _, err := net.SplitHostPort(addr); err == nil && core.IsLoopback(host) {
+		s.transport().SetAllowedHosts(core.LoopbackHosts())
+	}
+	srv := s.transport().NewServer(addr, core.DefaultHTTPTimeouts())
+	srv.Handler = s.Handler("")
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), core.DefaultShutdownTimeout)
+	defer cancel()
+	if err := s.Shutdown(shutdownCtx, srv); err != nil {
+		return err
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown gracefully stops srv, an http.Server serving Handler: it closes
+// the HTTP sessions' event streams, which would otherwise keep srv from
+// finishing, and waits for in-flight requests until ctx expires
+func (s *Server) Shutdown(ctx context.Context, srv *http.Server) error {
+	return s.transport().Shutdown(ctx, srv)
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/aawadall/go-mcp-filesearch/internal/models"
+)
+
+// Tool describes a tool offered to clients
+type Tool struct {
+	Name        string
+	Description string
+	// InputSchema is the JSON schema of the tool's arguments; nil accepts
+	// any object
+	InputSchema map[string]interface{}
+	// ReadOnly declares that the tool does not modify anything, which
+	// policies may rely on
+	ReadOnly bool
+}
+
+// ToolHandler runs a tool with the arguments of a call. Errors are sent to
+// the client as JSON-RPC errors; failures the model should see belong in a
+// result with IsError set.
+type ToolHandler func(ctx context.Context, args map[string]interface{}) (*ToolResult, error)
+
+// ToolResult is the result of a tool call
+type ToolResult struct {
+	Content []Content
+	// StructuredContent, if set, is sent alongside the content as JSON
+	StructuredContent interface{}
+	IsError           bool
+}
+
+// Content is one item of a tool result. Text items set Text; image and
+// audio items set Data and MimeType.
+type Content struct {
+	Type     string
+	Text     string
+	Data     []byte
+	MimeType string
+}
+
+// TextResult returns a result holding a single text item
+func TextResult(text string) *ToolResult {
+	return &ToolResult{Content: []Content{{Type: "text", Text: text}}}
+}
+
+// ErrorResult returns a failed result explaining the failure
+func ErrorResult(text string) *ToolResult {
+	result := TextResult(text)
+	result.IsError = true
+	return result
+}
+
+// Resource describes a resource offered to clients
+type Resource struct {
+	URI         string
+	Name        string
+	Description string
+	MimeType    string
+}
+
+// ResourceHandler reads a resource
+type ResourceHandler func(ctx context.Context, uri string) ([]ResourceContents, error)
+
+// ResourceContents is the content of a resource: Text for text resources
+// and Blob for binary ones
+type ResourceContents struct {
+	URI      string
+	MimeType string
+	Text     string
+	Blob     []byte
+}
+
+// SearchOptions tunes the file search tools
+type SearchOptions struct {
+	// Ignore lists glob patterns of paths never searched
+	Ignore []string
+	// MaxFileSize is the largest file searched or read, in bytes; zero uses
+	// the default
+	MaxFileSize int64
+	// MaxResults caps the results of one search; zero uses the default
+	MaxResults int
+}
+
+// Clock tells the current time. Tests pass a fake clock to WithClock to
+// control the times the server records.
+type Clock interface {
+	Now() time.Time
+}
+
+// Middleware wraps the HTTP handler returned by Handler
+type Middleware func(http.Handler) http.Handler
+
+// modelTool converts a tool to its protocol form
+func modelTool(tool Tool) models.Tool {
+	schema := tool.InputSchema
+	if schema == nil {
+		schema = map[string]interface{}{"type": "object"}
+	}
+	converted := models.Tool{
+		Name:        tool.Name,
+		Description: tool.Description,
+		InputSchema: schema,
+	}
+	if tool.ReadOnly {
+		converted.Annotations = &models.ToolAnnotations{ReadOnlyHint: true}
+	}
+	return converted
+}
+
+// toolResult converts a tool result to its protocol form
+func toolResult(result *ToolResult) map[string]interface{} {
+	content := make([]map[string]interface{}, 0, len(result.Content))
+	for _, c := range result.Content {
+		item := map[string]interface{}{"type": c.Type}
+		if c.Text != "" || c.Type == "text" {
+			item["text"] = c.Text
+		}
+		if c.Data != nil {
+			item["data"] = base64.StdEncoding.EncodeToString(c.Data)
+		}
+		if c.MimeType != "" {
+			item["mimeType"] = c.MimeType
+		}
+		content = append(content, item)
+	}
+
+	converted := map[string]interface{}{"content": content}
+	if result.StructuredContent != nil {
+		converted["structuredContent"] = result.StructuredContent
+	}
+	if result.IsError {
+		converted["isError"] = true
+	}
+	return converted
+}
+
+// resourceContents converts resource contents to the result of
+// resources/read
+func resourceContents(contents []ResourceContents) map[string]interface{} {
+	converted := make([]map[string]interface{}, 0, len(contents))
+	for _, c := range contents {
+		item := map[string]interface{}{"uri": c.URI}
+		if c.MimeType != "" {
+			item["mimeType"] = c.MimeType
+		}
+		if c.Blob != nil {
+			item["blob"] = base64.StdEncoding.EncodeToString(c.Blob)
+		} else {
+			item["text"] = c.Text
+		}
+		converted = append(converted, item)
+	}
+	return map[string]interface{}{"contents": converted}
+}